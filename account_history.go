@@ -15,9 +15,13 @@ type PricingSnapshot struct {
 }
 
 // The AccountHistory contains a record of point in time account snapshots as well as a list of all
-// of the Securities tracked by the account over time.
+// of the Securities tracked by the account over time. If a Benchmark security is designated, its
+// prices are read from the pricing snapshots for relative analysis. If a trading Calendar is set,
+// returns are annualized by its trading days rather than calendar days.
 type AccountHistory struct {
 	Securities []string
+	Benchmark  string
+	Calendar   *Calendar
 	Prices     []*PricingSnapshot
 	Snapshots  []*AccountSnapshot
 }
@@ -10,9 +10,10 @@ import (
 
 // Measures the return for a given period (such as a year, month etc) for analysis.
 type ReturnPeriod struct {
-	Period      TimePeriod  `yaml:"period"`
-	PercentGain big.Decimal `yaml:"percent_gain"`
-	TotalProfit big.Decimal `yaml:"total_profit"`
+	Label       string      `yaml:"label" json:"label"`
+	Period      TimePeriod  `yaml:"period" json:"period"`
+	PercentGain big.Decimal `yaml:"percent_gain" json:"percent_gain"`
+	TotalProfit big.Decimal `yaml:"total_profit" json:"total_profit"`
 }
 
 // Total profit of the account history.
@@ -32,7 +33,8 @@ func (ah *AccountHistory) PercentGain() big.Decimal {
 	return ah.TotalProfit().Div(ah.Snapshots[0].Equity).Mul(big.NewDecimal(100.00))
 }
 
-// Returns broken down by month
+// Returns broken down by month. Percent gains are fractions measured from the first snapshot of each
+// month; PeriodReturns measures calendar returns in percent from the close of the previous period.
 func (ah *AccountHistory) MonthlyPercentGains() []ReturnPeriod {
 	monthlyReturns := []ReturnPeriod{}
 
@@ -98,7 +100,8 @@ func (ah *AccountHistory) MonthlyPercentGains() []ReturnPeriod {
 	return monthlyReturns
 }
 
-// Get the annualized return of the account equity
+// Get the annualized return of the account equity. The length of the history in years is measured
+// in calendar days, or in trading days of the history's Calendar if one is set.
 func (ah *AccountHistory) AnnualizedReturn() big.Decimal {
 	startTimestamp := ah.Snapshots[0].Period.Start
 	endTimestamp := ah.Snapshots[ah.LastIndex()].Period.Start
@@ -106,6 +109,11 @@ func (ah *AccountHistory) AnnualizedReturn() big.Decimal {
 	days := big.NewDecimal(endTimestamp.Sub(startTimestamp).Hours()).Div(big.NewDecimal(24.0))
 	years := days.Div(big.NewDecimal(365.00))
 
+	if ah.Calendar != nil {
+		tradingDays := ah.Calendar.TradingDaysBetween(startTimestamp, endTimestamp)
+		years = big.NewFromInt(tradingDays).Div(big.NewFromInt(ah.Calendar.DaysPerYear))
+	}
+
 	startEquity := ah.Snapshots[0].Equity
 	endEquity := ah.Snapshots[ah.LastIndex()].Equity
 
@@ -119,7 +127,9 @@ func (ah *AccountHistory) AnnualizedReturn() big.Decimal {
 	return big.NewDecimal(math.Pow(base, exponent)).Sub(big.ONE).Mul(big.NewDecimal(100.00))
 }
 
-// Calculate the annualized volatility of the account's equity.
+// Calculate the annualized volatility of the account's equity. The deviation of the daily returns
+// is multiplied by 252 rather than by its square root, and is not a percentage, so the value is not
+// comparable with that of RollingVolatilityIndicator over the same returns.
 func (ah *AccountHistory) AnnualizedVolatility() big.Decimal {
 	if len(ah.Snapshots) <= 1 {
 		return big.ZERO
@@ -154,6 +164,17 @@ func (ah *AccountHistory) AnnualizedVolatility() big.Decimal {
 	return dailyVolatility.Mul(big.NewFromInt(252))
 }
 
+// Calculate the annualized Sharpe ratio of the account's periodic returns. The risk free rate is an
+// annual percentage.
+func (ah *AccountHistory) SharpeRatio(riskFreeRate big.Decimal) big.Decimal {
+	returns := ah.equityReturns()
+	if len(returns) > 0 {
+		returns = returns[1:]
+	}
+
+	return big.NewDecimal(sharpeRatio(returns, riskFreeRate.Float()/100.0/benchmarkPeriodsPerYear))
+}
+
 // Exports the account snapshots in a readable yaml format for viewing and analysis.
 func (ah *AccountHistory) ExportSnapshotsYaml(filepath string) error {
 	file, err := os.Create(filepath)
@@ -192,6 +213,11 @@ func (ah *AccountHistory) ExportAnalysisSummaryYaml(filepath string) error {
 		"total_profit":      ah.TotalProfit(),
 		"percent_gain":      ah.PercentGain(),
 		"annualized_return": ah.AnnualizedReturn(),
+		"maximum_drawdown":  ah.MaximumDrawdown(),
+	}
+
+	if ah.Benchmark != "" {
+		analysis["benchmark"] = ah.benchmarkAnalysis()
 	}
 
 	err = encoder.Encode(analysis)
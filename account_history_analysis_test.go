@@ -174,7 +174,7 @@ func TestAccountHistory_MonthlyPercentGains(t *testing.T) {
 			expected: []ReturnPeriod{
 				{
 					Period:      TimePeriod{},
-					PercentGain: big.NewDecimal(100.00),
+					PercentGain: big.NewDecimal(1.0),
 					TotalProfit: big.NewDecimal(1.0),
 				},
 			},
@@ -187,17 +187,17 @@ func TestAccountHistory_MonthlyPercentGains(t *testing.T) {
 				time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
 				time.Date(2020, time.February, 28, 0, 0, 0, 0, time.UTC),
 			},
-			equities: []float64{1.0, 2.0, 2.0, 4.0},
+			equities: []float64{1.0, 2.0, 3.0, 6.0},
 			expected: []ReturnPeriod{
 				{
 					Period:      TimePeriod{},
-					PercentGain: big.NewDecimal(100.00),
+					PercentGain: big.NewDecimal(1.0),
 					TotalProfit: big.NewDecimal(1.0),
 				},
 				{
 					Period:      TimePeriod{},
-					PercentGain: big.NewDecimal(100.00),
-					TotalProfit: big.NewDecimal(2.0),
+					PercentGain: big.NewDecimal(1.0),
+					TotalProfit: big.NewDecimal(3.0),
 				},
 			},
 		},
@@ -222,8 +222,8 @@ func TestAccountHistory_MonthlyPercentGains(t *testing.T) {
 			}
 
 			for i, r := range monthlyReturns {
-				decimalAlmostEquals(t, tt.expected[i].TotalProfit, r.TotalProfit, 1.0)
-				decimalAlmostEquals(t, tt.expected[i].PercentGain, r.PercentGain, 1.0)
+				decimalAlmostEquals(t, tt.expected[i].TotalProfit, r.TotalProfit, 1e-9)
+				decimalAlmostEquals(t, tt.expected[i].PercentGain, r.PercentGain, 1e-9)
 			}
 		})
 	}
@@ -294,3 +294,9 @@ func TestAccountHistory_ExportMonthlyGainsYaml(t *testing.T) {
 
 	os.Remove(filepath)
 }
+
+func TestAccountHistory_SharpeRatio(t *testing.T) {
+	ah := mockAccountHistory(100.0, 110.0, 99.0, 108.9, 119.79)
+	decimalEquals(t, 7.9373, ah.SharpeRatio(big.ZERO))
+	decimalEquals(t, 0.0, mockAccountHistory(100.0).SharpeRatio(big.ZERO))
+}
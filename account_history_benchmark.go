@@ -0,0 +1,346 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/schmidthole/big"
+	"gopkg.in/yaml.v3"
+)
+
+// Number of trading periods used to annualize the benchmark relative and rolling statistics. Mean
+// returns are scaled by it and deviations by its square root, as the deviation of returns grows
+// with the square root of time. AnnualizedVolatility instead multiplies the daily deviation by 252
+// and returns a fraction, as it always has, so it is not comparable with the tracking error or the
+// rolling volatility.
+const benchmarkPeriodsPerYear = 252.0
+
+// A RelativeEquityPoint is a single point of the account equity curve compared against the
+// benchmark. Both curves are rebased to start at 1.0 so they can be plotted together, and the
+// relative value is the account curve divided by the benchmark curve.
+type RelativeEquityPoint struct {
+	Period    TimePeriod  `yaml:"period"`
+	Equity    big.Decimal `yaml:"equity"`
+	Benchmark big.Decimal `yaml:"benchmark"`
+	Relative  big.Decimal `yaml:"relative"`
+}
+
+// Designates a security in the pricing snapshots to be used as the benchmark for all relative
+// analytics. The security does not need to be traded by the account.
+func (ah *AccountHistory) SetBenchmark(security string) {
+	ah.Benchmark = security
+}
+
+// Copies the close prices of a benchmark TimeSeries into the pricing snapshots with a matching
+// period start and designates the security as the benchmark. Snapshots without a matching candle
+// are left without a benchmark price and are skipped by the relative analytics.
+func (ah *AccountHistory) ApplyBenchmarkTimeSeries(security string, series *TimeSeries) error {
+	if series == nil || len(series.Candles) == 0 {
+		return fmt.Errorf("benchmark timeseries for %v has no candles", security)
+	}
+
+	closes := make(map[int64]big.Decimal, len(series.Candles))
+	for _, candle := range series.Candles {
+		closes[candle.Period.Start.UnixNano()] = candle.ClosePrice
+	}
+
+	for _, pricing := range ah.Prices {
+		price, exists := closes[pricing.Period.Start.UnixNano()]
+		if !exists {
+			continue
+		}
+
+		if pricing.Prices == nil {
+			pricing.Prices = Pricing{}
+		}
+		pricing.Prices[security] = price
+	}
+
+	ah.SetBenchmark(security)
+
+	return nil
+}
+
+// The beta of the account returns against the benchmark returns over the full history.
+func (ah *AccountHistory) Beta() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(beta(account, benchmark))
+}
+
+// Jensen's alpha of the account over the full history as an annualized percentage. The risk free
+// rate is supplied as an annual percentage.
+func (ah *AccountHistory) Alpha(riskFreeRate big.Decimal) big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(jensensAlpha(account, benchmark, riskFreeRate.Float()))
+}
+
+// The correlation of the account returns with the benchmark returns over the full history.
+func (ah *AccountHistory) BenchmarkCorrelation() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(correlation(account, benchmark))
+}
+
+// The annualized tracking error of the account against the benchmark as a percentage.
+func (ah *AccountHistory) TrackingError() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(trackingError(account, benchmark))
+}
+
+// The information ratio of the account against the benchmark, which is the annualized active
+// return divided by the annualized tracking error.
+func (ah *AccountHistory) InformationRatio() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(informationRatio(account, benchmark))
+}
+
+// The up capture ratio is the average account return during periods where the benchmark rose,
+// divided by the average benchmark return for those periods, as a percentage.
+func (ah *AccountHistory) UpCaptureRatio() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(captureRatio(account, benchmark, 1))
+}
+
+// The down capture ratio is the average account return during periods where the benchmark fell,
+// divided by the average benchmark return for those periods, as a percentage.
+func (ah *AccountHistory) DownCaptureRatio() big.Decimal {
+	account, benchmark, _ := ah.benchmarkReturns()
+	return big.NewDecimal(captureRatio(account, benchmark, -1))
+}
+
+// Derive an Indicator of the beta against the benchmark over a rolling window of returns ending
+// at each snapshot.
+func (ah *AccountHistory) RollingBetaIndicator(window int) Indicator {
+	return ah.rollingBenchmarkIndicator(window, beta)
+}
+
+// Derive an Indicator of Jensen's alpha over a rolling window of returns ending at each snapshot.
+func (ah *AccountHistory) RollingAlphaIndicator(window int, riskFreeRate big.Decimal) Indicator {
+	return ah.rollingBenchmarkIndicator(window, func(account, benchmark []float64) float64 {
+		return jensensAlpha(account, benchmark, riskFreeRate.Float())
+	})
+}
+
+// Derive an Indicator of the correlation with the benchmark over a rolling window of returns
+// ending at each snapshot.
+func (ah *AccountHistory) RollingCorrelationIndicator(window int) Indicator {
+	return ah.rollingBenchmarkIndicator(window, correlation)
+}
+
+// Derive an Indicator of the tracking error over a rolling window of returns ending at each
+// snapshot.
+func (ah *AccountHistory) RollingTrackingErrorIndicator(window int) Indicator {
+	return ah.rollingBenchmarkIndicator(window, trackingError)
+}
+
+// Derive an Indicator of the information ratio over a rolling window of returns ending at each
+// snapshot.
+func (ah *AccountHistory) RollingInformationRatioIndicator(window int) Indicator {
+	return ah.rollingBenchmarkIndicator(window, informationRatio)
+}
+
+// Computes the account equity curve relative to the benchmark. The curve starts at the first
+// snapshot with a benchmark price, and snapshots without a benchmark price are skipped.
+func (ah *AccountHistory) RelativeEquity() []RelativeEquityPoint {
+	points := []RelativeEquityPoint{}
+
+	if ah.Benchmark == "" {
+		return points
+	}
+
+	var baseEquity, basePrice big.Decimal
+	started := false
+
+	for i, snapshot := range ah.Snapshots {
+		price, exists := ah.PriceAtIndex(ah.Benchmark, i)
+		if !exists {
+			continue
+		}
+
+		if !started {
+			if snapshot.Equity.IsZero() || price.IsZero() {
+				continue
+			}
+
+			baseEquity = snapshot.Equity
+			basePrice = price
+			started = true
+		}
+
+		equity := snapshot.Equity.Div(baseEquity)
+		benchmark := price.Div(basePrice)
+
+		relative := big.ZERO
+		if !benchmark.IsZero() {
+			relative = equity.Div(benchmark)
+		}
+
+		points = append(points, RelativeEquityPoint{
+			Period:    snapshot.Period,
+			Equity:    equity,
+			Benchmark: benchmark,
+			Relative:  relative,
+		})
+	}
+
+	return points
+}
+
+// Exports the relative equity curve to yaml for viewing and analysis.
+func (ah *AccountHistory) ExportRelativeEquityYaml(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	defer encoder.Close()
+
+	err = encoder.Encode(ah.RelativeEquity())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ah *AccountHistory) benchmarkAnalysis() map[string]interface{} {
+	return map[string]interface{}{
+		"benchmark":          ah.Benchmark,
+		"beta":               ah.Beta(),
+		"alpha":              ah.Alpha(big.ZERO),
+		"correlation":        ah.BenchmarkCorrelation(),
+		"tracking_error":     ah.TrackingError(),
+		"information_ratio":  ah.InformationRatio(),
+		"up_capture_ratio":   ah.UpCaptureRatio(),
+		"down_capture_ratio": ah.DownCaptureRatio(),
+	}
+}
+
+// benchmarkReturns pairs the account and benchmark returns for every snapshot where both are
+// available. The snapshot index that each return ends at is returned alongside.
+func (ah *AccountHistory) benchmarkReturns() ([]float64, []float64, []int) {
+	account := []float64{}
+	benchmark := []float64{}
+	indexes := []int{}
+
+	if ah.Benchmark == "" {
+		return account, benchmark, indexes
+	}
+
+	for i := 1; i <= ah.LastIndex(); i++ {
+		lastPrice, lastExists := ah.PriceAtIndex(ah.Benchmark, i-1)
+		price, exists := ah.PriceAtIndex(ah.Benchmark, i)
+		lastEquity := ah.Snapshots[i-1].Equity
+
+		if !lastExists || !exists || lastPrice.IsZero() || lastEquity.IsZero() {
+			continue
+		}
+
+		account = append(account, ah.Snapshots[i].Equity.Div(lastEquity).Sub(big.ONE).Float())
+		benchmark = append(benchmark, price.Div(lastPrice).Sub(big.ONE).Float())
+		indexes = append(indexes, i)
+	}
+
+	return account, benchmark, indexes
+}
+
+func (ah *AccountHistory) rollingBenchmarkIndicator(window int, stat func(account, benchmark []float64) float64) Indicator {
+	checkRollingWindow(window)
+	account, benchmark, indexes := ah.benchmarkReturns()
+	values := make([]float64, len(ah.Snapshots))
+
+	start := 0
+	end := 0
+	for i := range values {
+		for end < len(indexes) && indexes[end] <= i {
+			end++
+		}
+		for start < end && indexes[start] <= i-window {
+			start++
+		}
+
+		if end-start < window {
+			continue
+		}
+
+		values[i] = stat(account[start:end], benchmark[start:end])
+	}
+
+	return NewFixedIndicator(values...)
+}
+
+func beta(account, benchmark []float64) float64 {
+	variance := covariance(benchmark, benchmark)
+	if variance == 0.0 {
+		return 0.0
+	}
+
+	return covariance(account, benchmark) / variance
+}
+
+func jensensAlpha(account, benchmark []float64, riskFreeRate float64) float64 {
+	if len(account) == 0 {
+		return 0.0
+	}
+
+	riskFree := riskFreeRate / 100.0 / benchmarkPeriodsPerYear
+	alpha := mean(account) - (riskFree + beta(account, benchmark)*(mean(benchmark)-riskFree))
+
+	return alpha * benchmarkPeriodsPerYear * 100.0
+}
+
+func correlation(account, benchmark []float64) float64 {
+	denominator := stdev(account) * stdev(benchmark)
+	if denominator == 0.0 {
+		return 0.0
+	}
+
+	return covariance(account, benchmark) / denominator
+}
+
+func activeReturns(account, benchmark []float64) []float64 {
+	active := make([]float64, len(account))
+	for i := range account {
+		active[i] = account[i] - benchmark[i]
+	}
+
+	return active
+}
+
+func trackingError(account, benchmark []float64) float64 {
+	return stdev(activeReturns(account, benchmark)) * math.Sqrt(benchmarkPeriodsPerYear) * 100.0
+}
+
+func informationRatio(account, benchmark []float64) float64 {
+	active := activeReturns(account, benchmark)
+
+	deviation := stdev(active)
+	if deviation == 0.0 {
+		return 0.0
+	}
+
+	return mean(active) / deviation * math.Sqrt(benchmarkPeriodsPerYear)
+}
+
+// captureRatio compares the account and benchmark returns for the periods where the benchmark
+// moved in the given direction (1 for up, -1 for down).
+func captureRatio(account, benchmark []float64, direction float64) float64 {
+	accountCaptured := []float64{}
+	benchmarkCaptured := []float64{}
+
+	for i := range benchmark {
+		if benchmark[i]*direction > 0.0 {
+			accountCaptured = append(accountCaptured, account[i])
+			benchmarkCaptured = append(benchmarkCaptured, benchmark[i])
+		}
+	}
+
+	benchmarkMean := mean(benchmarkCaptured)
+	if benchmarkMean == 0.0 {
+		return 0.0
+	}
+
+	return mean(accountCaptured) / benchmarkMean * 100.0
+}
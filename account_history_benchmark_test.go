@@ -0,0 +1,119 @@
+package techan
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockBenchmark = "BENCH"
+
+func mockBenchmarkHistory() *AccountHistory {
+	ah := mockAccountHistory(100.0, 120.0, 96.0, 115.2)
+	prices := []float64{100.0, 110.0, 99.0, 108.9}
+
+	for i, p := range prices {
+		ah.Prices[i].Prices[mockBenchmark] = big.NewDecimal(p)
+	}
+	ah.SetBenchmark(mockBenchmark)
+
+	return ah
+}
+
+func TestAccountHistory_ApplyBenchmarkTimeSeries(t *testing.T) {
+	ah := mockAccountHistory(100.0, 120.0, 96.0)
+
+	series := NewTimeSeries()
+	for i, p := range []float64{100.0, 110.0} {
+		candle := NewCandle(ah.Snapshots[i+1].Period)
+		candle.ClosePrice = big.NewDecimal(p)
+		series.AddCandle(candle)
+	}
+
+	err := ah.ApplyBenchmarkTimeSeries(mockBenchmark, series)
+	assert.Nil(t, err)
+	assert.Equal(t, mockBenchmark, ah.Benchmark)
+
+	_, exists := ah.PriceAtIndex(mockBenchmark, 0)
+	assert.False(t, exists)
+
+	price, exists := ah.PriceAtIndex(mockBenchmark, 2)
+	assert.True(t, exists)
+	decimalEquals(t, 110.0, price)
+
+	err = ah.ApplyBenchmarkTimeSeries(mockBenchmark, NewTimeSeries())
+	assert.NotNil(t, err)
+}
+
+func TestAccountHistory_BenchmarkStatistics(t *testing.T) {
+	ah := mockBenchmarkHistory()
+
+	decimalEquals(t, 2.0, ah.Beta())
+	decimalEquals(t, 1.0, ah.BenchmarkCorrelation())
+	assert.InDelta(t, 0.0, ah.Alpha(big.ZERO).Float(), 1e-9)
+	decimalEquals(t, 200.0, ah.UpCaptureRatio())
+	decimalEquals(t, 200.0, ah.DownCaptureRatio())
+	decimalAlmostEquals(t, big.NewDecimal(0.11547*math.Sqrt(252)*100.0), ah.TrackingError(), 0.01)
+	decimalAlmostEquals(t, big.NewDecimal(0.0333/0.11547*math.Sqrt(252)), ah.InformationRatio(), 0.01)
+}
+
+func TestAccountHistory_BenchmarkStatisticsNoBenchmark(t *testing.T) {
+	ah := mockAccountHistory(100.0, 120.0, 96.0)
+
+	decimalEquals(t, 0.0, ah.Beta())
+	decimalEquals(t, 0.0, ah.TrackingError())
+	assert.Empty(t, ah.RelativeEquity())
+}
+
+func TestAccountHistory_RollingBenchmarkIndicators(t *testing.T) {
+	ah := mockBenchmarkHistory()
+
+	indicatorEquals(t, []float64{0.0, 0.0, 2.0, 2.0}, ah.RollingBetaIndicator(2))
+	indicatorEquals(t, []float64{0.0, 0.0, 1.0, 1.0}, ah.RollingCorrelationIndicator(2))
+	assert.InDelta(t, 0.0, ah.RollingAlphaIndicator(3, big.ZERO).Calculate(3).Float(), 1e-9)
+
+	te := ah.RollingTrackingErrorIndicator(2)
+	assert.True(t, te.Calculate(2).GT(big.ZERO))
+
+	ir := ah.RollingInformationRatioIndicator(2)
+	assert.InDelta(t, 0.0, ir.Calculate(2).Float(), 1e-9)
+}
+
+func TestAccountHistory_RelativeEquity(t *testing.T) {
+	ah := mockBenchmarkHistory()
+	delete(ah.Prices[0].Prices, mockBenchmark)
+
+	points := ah.RelativeEquity()
+
+	assert.Equal(t, 3, len(points))
+	assert.Equal(t, ah.Snapshots[1].Period, points[0].Period)
+	decimalEquals(t, 1.0, points[0].Equity)
+	decimalEquals(t, 1.0, points[0].Benchmark)
+	decimalEquals(t, 0.8, points[1].Equity)
+	decimalEquals(t, 0.9, points[1].Benchmark)
+	decimalAlmostEquals(t, big.NewDecimal(0.8/0.9), points[1].Relative, 0.0001)
+}
+
+func TestAccountHistory_ExportRelativeEquityYaml(t *testing.T) {
+	ah := mockBenchmarkHistory()
+
+	filepath := "relative_equity.yaml"
+	err := ah.ExportRelativeEquityYaml(filepath)
+	assert.Nil(t, err)
+
+	os.Remove(filepath)
+
+	filepath = "benchmark_analysis_summary.yaml"
+	err = ah.ExportAnalysisSummaryYaml(filepath)
+	assert.Nil(t, err)
+
+	contents, err := ioutil.ReadFile(filepath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "tracking_error")
+
+	os.Remove(filepath)
+}
@@ -0,0 +1,395 @@
+package techan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schmidthole/big"
+	"gopkg.in/yaml.v3"
+)
+
+// A NamedAccountHistory labels an AccountHistory, such as the result of one backtest variant, for
+// use in a comparison.
+type NamedAccountHistory struct {
+	Name    string
+	History *AccountHistory
+}
+
+// BacktestMetrics holds the analysis metrics of a single account history within a comparison.
+// Benchmark metrics are only set when the history has a benchmark.
+type BacktestMetrics struct {
+	Name                  string        `yaml:"name"`
+	Start                 time.Time     `yaml:"start"`
+	End                   time.Time     `yaml:"end"`
+	StartingEquity        big.Decimal   `yaml:"starting_equity"`
+	EndingEquity          big.Decimal   `yaml:"ending_equity"`
+	TotalProfit           big.Decimal   `yaml:"total_profit"`
+	PercentGain           big.Decimal   `yaml:"percent_gain"`
+	AnnualizedReturn      big.Decimal   `yaml:"annualized_return"`
+	AnnualizedVolatility  big.Decimal   `yaml:"annualized_volatility"`
+	SharpeRatio           big.Decimal   `yaml:"sharpe_ratio"`
+	MaximumDrawdown       big.Decimal   `yaml:"maximum_drawdown"`
+	LongestTimeUnderwater time.Duration `yaml:"longest_time_underwater"`
+	TotalTrades           int           `yaml:"total_trades"`
+	WinRate               big.Decimal   `yaml:"win_rate"`
+	ProfitFactor          big.Decimal   `yaml:"profit_factor"`
+	Expectancy            big.Decimal   `yaml:"expectancy"`
+	Benchmark             string        `yaml:"benchmark,omitempty"`
+	Beta                  *big.Decimal  `yaml:"beta,omitempty"`
+	Alpha                 *big.Decimal  `yaml:"alpha,omitempty"`
+	BenchmarkCorrelation  *big.Decimal  `yaml:"benchmark_correlation,omitempty"`
+	TrackingError         *big.Decimal  `yaml:"tracking_error,omitempty"`
+	InformationRatio      *big.Decimal  `yaml:"information_ratio,omitempty"`
+	UpCaptureRatio        *big.Decimal  `yaml:"up_capture_ratio,omitempty"`
+	DownCaptureRatio      *big.Decimal  `yaml:"down_capture_ratio,omitempty"`
+}
+
+// A BacktestComparison holds several account histories aligned on the periods they have in
+// common, along with the metrics of each and the correlations between their returns. The
+// correlation matrix is ordered the same as the names.
+type BacktestComparison struct {
+	Names        []string          `yaml:"names"`
+	Histories    []*AccountHistory `yaml:"-"`
+	Metrics      []BacktestMetrics `yaml:"metrics"`
+	Correlations [][]big.Decimal   `yaml:"correlations"`
+}
+
+// Compares several account histories. Each history is restricted to the snapshots whose period
+// start is shared by all of the histories, so every metric is computed over the same time frame.
+// The risk free rate is an annual percentage used for the Sharpe ratio and alpha.
+func CompareBacktests(riskFreeRate big.Decimal, histories ...NamedAccountHistory) (*BacktestComparison, error) {
+	if len(histories) == 0 {
+		return nil, fmt.Errorf("no account histories to compare")
+	}
+
+	common := map[int64]int{}
+	for _, h := range histories {
+		seen := map[int64]bool{}
+		for _, snapshot := range h.History.Snapshots {
+			key := snapshot.Period.Start.UnixNano()
+			if !seen[key] {
+				common[key]++
+				seen[key] = true
+			}
+		}
+	}
+
+	comparison := &BacktestComparison{
+		Names:        make([]string, len(histories)),
+		Histories:    make([]*AccountHistory, len(histories)),
+		Metrics:      make([]BacktestMetrics, len(histories)),
+		Correlations: make([][]big.Decimal, len(histories)),
+	}
+
+	for i, h := range histories {
+		aligned := &AccountHistory{
+			Securities: h.History.Securities,
+			Benchmark:  h.History.Benchmark,
+			Calendar:   h.History.Calendar,
+			Prices:     []*PricingSnapshot{},
+			Snapshots:  []*AccountSnapshot{},
+		}
+
+		for j, snapshot := range h.History.Snapshots {
+			if common[snapshot.Period.Start.UnixNano()] == len(histories) {
+				aligned.Snapshots = append(aligned.Snapshots, snapshot)
+				aligned.Prices = append(aligned.Prices, h.History.Prices[j])
+			}
+		}
+
+		if len(aligned.Snapshots) < 2 {
+			return nil, fmt.Errorf("account histories have less than two common periods")
+		}
+
+		metrics, err := aligned.backtestMetrics(h.Name, riskFreeRate)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", h.Name, err)
+		}
+
+		comparison.Names[i] = h.Name
+		comparison.Histories[i] = aligned
+		comparison.Metrics[i] = metrics
+	}
+
+	returns := make([][]float64, len(histories))
+	for i, ah := range comparison.Histories {
+		returns[i] = ah.equityReturns()[1:]
+	}
+
+	for i := range returns {
+		comparison.Correlations[i] = make([]big.Decimal, len(returns))
+		for j := range returns {
+			comparison.Correlations[i][j] = big.NewDecimal(correlation(returns[i], returns[j]))
+		}
+	}
+
+	return comparison, nil
+}
+
+// Exports the comparison metrics and return correlations to yaml for viewing and analysis.
+func (bc *BacktestComparison) ExportYaml(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	defer encoder.Close()
+
+	err = encoder.Encode(bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Writes the comparison table as csv, with one row per metric and one column per history.
+func (bc *BacktestComparison) WriteCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.WriteAll(bc.table())
+	if err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// Exports the comparison table to a csv file.
+func (bc *BacktestComparison) ExportCsv(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return bc.WriteCsv(file)
+}
+
+// Writes a self-contained html report of the comparison with the metrics table, the combined
+// equity chart and the return correlation matrix.
+func (bc *BacktestComparison) WriteHtml(w io.Writer, title string) error {
+	if title == "" {
+		title = "Backtest Comparison"
+	}
+
+	table := bc.table()
+
+	correlations := [][]string{}
+	for i, row := range bc.Correlations {
+		values := []string{bc.Names[i]}
+		for _, c := range row {
+			values = append(values, c.FormattedString(2))
+		}
+		correlations = append(correlations, values)
+	}
+
+	data := map[string]interface{}{
+		"Title":        title,
+		"Header":       table[0],
+		"Rows":         table[1:],
+		"Equity":       template.HTML(bc.EquityChartSvg()),
+		"Names":        bc.Names,
+		"Correlations": correlations,
+	}
+
+	return comparisonTemplate.Execute(w, data)
+}
+
+// Exports a self-contained html report of the comparison to a file.
+func (bc *BacktestComparison) ExportHtml(filepath string, title string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return bc.WriteHtml(file, title)
+}
+
+// Renders the equity curves of all histories as an svg chart. Each curve is normalized to start
+// at 100 so that histories with different starting equity can be compared.
+func (bc *BacktestComparison) EquityChartSvg() string {
+	series := make([]svgSeries, len(bc.Histories))
+
+	for i, ah := range bc.Histories {
+		values := make([]float64, len(ah.Snapshots))
+		start := ah.Snapshots[0].Equity.Float()
+
+		for j, snapshot := range ah.Snapshots {
+			values[j] = math.NaN()
+			if start != 0.0 {
+				values[j] = snapshot.Equity.Float() / start * 100.0
+			}
+		}
+
+		series[i] = svgSeries{Name: bc.Names[i], Color: comparisonColors[i%len(comparisonColors)], Values: values}
+	}
+
+	return bc.Histories[0].timeChartSvg(series, false)
+}
+
+// Exports the combined equity chart to a standalone svg file.
+func (bc *BacktestComparison) ExportEquityChartSvg(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	svg := strings.Replace(bc.EquityChartSvg(), "<svg ", `<svg xmlns="http://www.w3.org/2000/svg" `, 1)
+	_, err = io.WriteString(file, svg+"\n")
+
+	return err
+}
+
+func (ah *AccountHistory) backtestMetrics(name string, riskFreeRate big.Decimal) (BacktestMetrics, error) {
+	analysis, err := AnalyzeTrades(ah.ImpliedOrders(), nil)
+	if err != nil {
+		return BacktestMetrics{}, err
+	}
+
+	metrics := BacktestMetrics{
+		Name:                  name,
+		Start:                 ah.Snapshots[0].Period.Start,
+		End:                   ah.Snapshots[ah.LastIndex()].Period.Start,
+		StartingEquity:        ah.Snapshots[0].Equity,
+		EndingEquity:          ah.Snapshots[ah.LastIndex()].Equity,
+		TotalProfit:           ah.TotalProfit(),
+		PercentGain:           ah.PercentGain(),
+		AnnualizedReturn:      ah.AnnualizedReturn(),
+		AnnualizedVolatility:  big.ZERO,
+		SharpeRatio:           ah.SharpeRatio(riskFreeRate),
+		MaximumDrawdown:       ah.MaximumDrawdown(),
+		LongestTimeUnderwater: ah.LongestTimeUnderwater(),
+		TotalTrades:           analysis.TotalTrades,
+		WinRate:               analysis.WinRate,
+		ProfitFactor:          analysis.ProfitFactor,
+		Expectancy:            analysis.Expectancy,
+	}
+
+	// the sample variance needs at least two returns
+	if len(ah.Snapshots) > 2 {
+		metrics.AnnualizedVolatility = ah.AnnualizedVolatility()
+	}
+
+	if ah.Benchmark != "" {
+		beta := ah.Beta()
+		alpha := ah.Alpha(riskFreeRate)
+		correlation := ah.BenchmarkCorrelation()
+		trackingError := ah.TrackingError()
+		informationRatio := ah.InformationRatio()
+		upCapture := ah.UpCaptureRatio()
+		downCapture := ah.DownCaptureRatio()
+
+		metrics.Benchmark = ah.Benchmark
+		metrics.Beta = &beta
+		metrics.Alpha = &alpha
+		metrics.BenchmarkCorrelation = &correlation
+		metrics.TrackingError = &trackingError
+		metrics.InformationRatio = &informationRatio
+		metrics.UpCaptureRatio = &upCapture
+		metrics.DownCaptureRatio = &downCapture
+	}
+
+	return metrics, nil
+}
+
+// table formats the metrics of the comparison with a header row of the history names followed by
+// one row per metric.
+func (bc *BacktestComparison) table() [][]string {
+	layout := SimpleDateFormatV2
+
+	rows := [][]string{append([]string{"metric"}, bc.Names...)}
+	add := func(name string, value func(m BacktestMetrics) string) {
+		row := []string{name}
+		for _, m := range bc.Metrics {
+			row = append(row, value(m))
+		}
+		rows = append(rows, row)
+	}
+	optional := func(d *big.Decimal, places int) string {
+		if d == nil {
+			return ""
+		}
+		return d.FormattedString(places)
+	}
+
+	add("start", func(m BacktestMetrics) string { return m.Start.Format(layout) })
+	add("end", func(m BacktestMetrics) string { return m.End.Format(layout) })
+	add("starting_equity", func(m BacktestMetrics) string { return m.StartingEquity.FormattedString(2) })
+	add("ending_equity", func(m BacktestMetrics) string { return m.EndingEquity.FormattedString(2) })
+	add("total_profit", func(m BacktestMetrics) string { return m.TotalProfit.FormattedString(2) })
+	add("percent_gain", func(m BacktestMetrics) string { return m.PercentGain.FormattedString(2) })
+	add("annualized_return", func(m BacktestMetrics) string { return m.AnnualizedReturn.FormattedString(2) })
+	add("annualized_volatility", func(m BacktestMetrics) string { return m.AnnualizedVolatility.FormattedString(4) })
+	add("sharpe_ratio", func(m BacktestMetrics) string { return m.SharpeRatio.FormattedString(2) })
+	add("maximum_drawdown", func(m BacktestMetrics) string { return m.MaximumDrawdown.FormattedString(2) })
+	add("longest_time_underwater", func(m BacktestMetrics) string { return m.LongestTimeUnderwater.String() })
+	add("total_trades", func(m BacktestMetrics) string { return fmt.Sprint(m.TotalTrades) })
+	add("win_rate", func(m BacktestMetrics) string { return m.WinRate.FormattedString(2) })
+	add("profit_factor", func(m BacktestMetrics) string { return m.ProfitFactor.FormattedString(2) })
+	add("expectancy", func(m BacktestMetrics) string { return m.Expectancy.FormattedString(2) })
+
+	for _, m := range bc.Metrics {
+		if m.Benchmark == "" {
+			continue
+		}
+
+		add("benchmark", func(m BacktestMetrics) string { return m.Benchmark })
+		add("beta", func(m BacktestMetrics) string { return optional(m.Beta, 2) })
+		add("alpha", func(m BacktestMetrics) string { return optional(m.Alpha, 2) })
+		add("benchmark_correlation", func(m BacktestMetrics) string { return optional(m.BenchmarkCorrelation, 2) })
+		add("tracking_error", func(m BacktestMetrics) string { return optional(m.TrackingError, 2) })
+		add("information_ratio", func(m BacktestMetrics) string { return optional(m.InformationRatio, 2) })
+		add("up_capture_ratio", func(m BacktestMetrics) string { return optional(m.UpCaptureRatio, 2) })
+		add("down_capture_ratio", func(m BacktestMetrics) string { return optional(m.DownCaptureRatio, 2) })
+		break
+	}
+
+	return rows
+}
+
+var comparisonColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+var comparisonTemplate = template.Must(template.New("comparison").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; font-size: 13px; }
+th, td { padding: 4px 10px; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+
+<h2>Metrics</h2>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+
+<h2>Equity (normalized to 100)</h2>
+{{.Equity}}
+
+<h2>Return Correlations</h2>
+<table>
+<tr><th></th>{{range .Names}}<th>{{.}}</th>{{end}}</tr>
+{{range .Correlations}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
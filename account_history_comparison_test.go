@@ -0,0 +1,93 @@
+package techan
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareBacktests(t *testing.T) {
+	first := mockAccountHistory(100.0, 110.0, 99.0, 108.9, 119.79)
+	second := mockAccountHistory(200.0, 220.0, 198.0, 217.8, 239.58, 250.0)
+	third := mockAccountHistory(100.0, 90.0, 99.0, 89.1, 80.19)
+
+	// drop the first period of the third history so only four periods are shared
+	third.Snapshots = third.Snapshots[1:]
+	third.Prices = third.Prices[1:]
+
+	comparison, err := CompareBacktests(big.ZERO,
+		NamedAccountHistory{"first", first},
+		NamedAccountHistory{"second", second},
+		NamedAccountHistory{"third", third},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"first", "second", "third"}, comparison.Names)
+	for _, ah := range comparison.Histories {
+		assert.Equal(t, 4, len(ah.Snapshots))
+		assert.Equal(t, 4, len(ah.Prices))
+	}
+
+	decimalEquals(t, 8.9, comparison.Metrics[0].PercentGain)
+	decimalEquals(t, 8.9, comparison.Metrics[1].PercentGain)
+	decimalEquals(t, -10.9, comparison.Metrics[2].PercentGain)
+	decimalEquals(t, 10.0, comparison.Metrics[0].MaximumDrawdown)
+
+	decimalEquals(t, 1.0, comparison.Correlations[0][1])
+	decimalEquals(t, -1.0, comparison.Correlations[0][2])
+	decimalEquals(t, 1.0, comparison.Correlations[2][2])
+}
+
+func TestCompareBacktests_Errors(t *testing.T) {
+	_, err := CompareBacktests(big.ZERO)
+	assert.EqualError(t, err, "no account histories to compare")
+
+	second := mockAccountHistory(100.0, 110.0)
+	second.Snapshots = second.Snapshots[1:]
+	second.Prices = second.Prices[1:]
+
+	_, err = CompareBacktests(big.ZERO,
+		NamedAccountHistory{"first", mockAccountHistory(100.0, 110.0)},
+		NamedAccountHistory{"second", second},
+	)
+	assert.EqualError(t, err, "account histories have less than two common periods")
+}
+
+func TestBacktestComparison_Export(t *testing.T) {
+	comparison, err := CompareBacktests(big.ZERO,
+		NamedAccountHistory{"fast", mockAccountHistory(100.0, 110.0, 99.0)},
+		NamedAccountHistory{"slow", mockAccountHistory(100.0, 101.0, 102.0)},
+	)
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, comparison.WriteCsv(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "metric,fast,slow\n"))
+	assert.Contains(t, buf.String(), "percent_gain,-1.00,2.00\n")
+
+	buf.Reset()
+	assert.Nil(t, comparison.WriteHtml(&buf, ""))
+	assert.Contains(t, buf.String(), "<title>Backtest Comparison</title>")
+	assert.Equal(t, 1, strings.Count(buf.String(), "<svg"))
+	assert.Equal(t, 2, strings.Count(buf.String(), "<polyline"))
+
+	assert.Nil(t, comparison.ExportYaml("comparison.yaml"))
+	os.Remove("comparison.yaml")
+
+	assert.Nil(t, comparison.ExportCsv("comparison.csv"))
+	os.Remove("comparison.csv")
+
+	assert.Nil(t, comparison.ExportHtml("comparison.html", "Test"))
+	os.Remove("comparison.html")
+
+	assert.Nil(t, comparison.ExportEquityChartSvg("comparison.svg"))
+	contents, err := ioutil.ReadFile("comparison.svg")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(contents), `<svg xmlns="http://www.w3.org/2000/svg"`))
+	os.Remove("comparison.svg")
+}
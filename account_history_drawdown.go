@@ -0,0 +1,259 @@
+package techan
+
+import (
+	"encoding/csv"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/schmidthole/big"
+	"gopkg.in/yaml.v3"
+)
+
+// A DrawdownPeriod describes a single peak-to-trough drawdown episode of the account equity. The
+// episode starts at the last high-water mark, bottoms out at the trough and ends when the equity
+// recovers back to the high-water mark. Unrecovered drawdowns have a zero Recovery time and their
+// lengths are measured up until the last snapshot of the history.
+type DrawdownPeriod struct {
+	Start          time.Time     `yaml:"start"`
+	Trough         time.Time     `yaml:"trough"`
+	Recovery       time.Time     `yaml:"recovery"`
+	Recovered      bool          `yaml:"recovered"`
+	PeakEquity     big.Decimal   `yaml:"peak_equity"`
+	TroughEquity   big.Decimal   `yaml:"trough_equity"`
+	Depth          big.Decimal   `yaml:"depth"`
+	Length         time.Duration `yaml:"length"`
+	TimeToTrough   time.Duration `yaml:"time_to_trough"`
+	TimeToRecovery time.Duration `yaml:"time_to_recovery"`
+	StartIndex     int           `yaml:"start_index"`
+	TroughIndex    int           `yaml:"trough_index"`
+	EndIndex       int           `yaml:"end_index"`
+}
+
+// Calculates the underwater series of the account equity. Each value is the percentage that the
+// equity sits below its running high-water mark at that snapshot, so a value of zero means the
+// account is at a new high.
+func (ah *AccountHistory) Underwater() []big.Decimal {
+	underwater := make([]big.Decimal, len(ah.Snapshots))
+	if len(ah.Snapshots) == 0 {
+		return underwater
+	}
+
+	highWaterMark := ah.Snapshots[0].Equity
+	for i, snapshot := range ah.Snapshots {
+		if snapshot.Equity.GT(highWaterMark) {
+			highWaterMark = snapshot.Equity
+		}
+
+		underwater[i] = percentBelow(highWaterMark, snapshot.Equity)
+	}
+
+	return underwater
+}
+
+// Derive an Indicator from the underwater series of the account equity.
+func (ah *AccountHistory) UnderwaterAsIndicator() Indicator {
+	values := []float64{}
+	for _, u := range ah.Underwater() {
+		values = append(values, u.Float())
+	}
+
+	return NewFixedIndicator(values...)
+}
+
+// Finds every drawdown episode in the account history in chronological order. Drawdowns are
+// measured exactly from the running high-water mark of the equity rather than estimated with a
+// local extrema window.
+func (ah *AccountHistory) DrawdownPeriods() []DrawdownPeriod {
+	drawdowns := []DrawdownPeriod{}
+
+	if len(ah.Snapshots) < 2 {
+		return drawdowns
+	}
+
+	peakIndex := 0
+	troughIndex := 0
+	inDrawdown := false
+
+	for i := 1; i <= ah.LastIndex(); i++ {
+		equity := ah.Snapshots[i].Equity
+
+		if equity.GTE(ah.Snapshots[peakIndex].Equity) {
+			if inDrawdown {
+				drawdowns = append(drawdowns, ah.newDrawdownPeriod(peakIndex, troughIndex, i, true))
+				inDrawdown = false
+			}
+
+			peakIndex = i
+			continue
+		}
+
+		if !inDrawdown {
+			inDrawdown = true
+			troughIndex = i
+		} else if equity.LT(ah.Snapshots[troughIndex].Equity) {
+			troughIndex = i
+		}
+	}
+
+	if inDrawdown {
+		drawdowns = append(drawdowns, ah.newDrawdownPeriod(peakIndex, troughIndex, ah.LastIndex(), false))
+	}
+
+	return drawdowns
+}
+
+// Returns the n deepest drawdown episodes, ordered from deepest to shallowest. If n is less than
+// one or larger than the number of episodes, all episodes are returned.
+func (ah *AccountHistory) TopDrawdowns(n int) []DrawdownPeriod {
+	drawdowns := ah.DrawdownPeriods()
+
+	sort.SliceStable(drawdowns, func(i, j int) bool {
+		return drawdowns[i].Depth.GT(drawdowns[j].Depth)
+	})
+
+	if n > 0 && n < len(drawdowns) {
+		drawdowns = drawdowns[:n]
+	}
+
+	return drawdowns
+}
+
+// The maximum peak-to-trough drawdown of the account equity as a percentage.
+func (ah *AccountHistory) MaximumDrawdown() big.Decimal {
+	maxDrawdown := big.ZERO
+	for _, u := range ah.Underwater() {
+		if u.GT(maxDrawdown) {
+			maxDrawdown = u
+		}
+	}
+
+	return maxDrawdown
+}
+
+// The longest amount of time the account equity spent below its high-water mark.
+func (ah *AccountHistory) LongestTimeUnderwater() time.Duration {
+	longest := time.Duration(0)
+	for _, dd := range ah.DrawdownPeriods() {
+		if dd.Length > longest {
+			longest = dd.Length
+		}
+	}
+
+	return longest
+}
+
+// Exports the n deepest drawdown episodes to yaml for viewing and analysis.
+func (ah *AccountHistory) ExportDrawdownsYaml(filepath string, n int) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	defer encoder.Close()
+
+	err = encoder.Encode(ah.TopDrawdowns(n))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Exports the n deepest drawdown episodes as a csv table for viewing and analysis.
+func (ah *AccountHistory) ExportDrawdownsCsv(filepath string, n int) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	err = writer.Write([]string{
+		"rank",
+		"start",
+		"trough",
+		"recovery",
+		"peak_equity",
+		"trough_equity",
+		"depth",
+		"length",
+		"time_to_trough",
+		"time_to_recovery",
+	})
+	if err != nil {
+		return err
+	}
+
+	layout := SimpleDateFormatV2 + "T" + SimpleTimeFormat
+
+	for i, dd := range ah.TopDrawdowns(n) {
+		recovery := ""
+		timeToRecovery := ""
+		if dd.Recovered {
+			recovery = dd.Recovery.Format(layout)
+			timeToRecovery = dd.TimeToRecovery.String()
+		}
+
+		err = writer.Write([]string{
+			strconv.Itoa(i + 1),
+			dd.Start.Format(layout),
+			dd.Trough.Format(layout),
+			recovery,
+			dd.PeakEquity.String(),
+			dd.TroughEquity.String(),
+			dd.Depth.FormattedString(4),
+			dd.Length.String(),
+			dd.TimeToTrough.String(),
+			timeToRecovery,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (ah *AccountHistory) newDrawdownPeriod(peakIndex, troughIndex, endIndex int, recovered bool) DrawdownPeriod {
+	start := ah.Snapshots[peakIndex].Period.Start
+	trough := ah.Snapshots[troughIndex].Period.Start
+	end := ah.Snapshots[endIndex].Period.Start
+
+	dd := DrawdownPeriod{
+		Start:        start,
+		Trough:       trough,
+		Recovered:    recovered,
+		PeakEquity:   ah.Snapshots[peakIndex].Equity,
+		TroughEquity: ah.Snapshots[troughIndex].Equity,
+		Length:       end.Sub(start),
+		TimeToTrough: trough.Sub(start),
+		StartIndex:   peakIndex,
+		TroughIndex:  troughIndex,
+		EndIndex:     endIndex,
+	}
+
+	dd.Depth = percentBelow(dd.PeakEquity, dd.TroughEquity)
+
+	if recovered {
+		dd.Recovery = end
+		dd.TimeToRecovery = end.Sub(trough)
+	}
+
+	return dd
+}
+
+// percentBelow returns how far a value sits below a peak as a positive percentage of the peak.
+func percentBelow(peak, value big.Decimal) big.Decimal {
+	if peak.LTE(big.ZERO) || value.GTE(peak) {
+		return big.ZERO
+	}
+
+	return peak.Sub(value).Div(peak).Mul(big.NewDecimal(100.00))
+}
@@ -0,0 +1,103 @@
+package techan
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountHistory_Underwater(t *testing.T) {
+	ah := mockAccountHistory(100.0, 110.0, 99.0, 88.0, 110.0, 120.0, 108.0)
+
+	expected := []float64{0.0, 0.0, 10.0, 20.0, 0.0, 0.0, 10.0}
+	underwater := ah.Underwater()
+
+	assert.Equal(t, len(expected), len(underwater))
+	for i, want := range expected {
+		decimalEquals(t, want, underwater[i])
+	}
+
+	indicatorEquals(t, expected, ah.UnderwaterAsIndicator())
+}
+
+func TestAccountHistory_DrawdownPeriods(t *testing.T) {
+	t.Run("no drawdowns", func(t *testing.T) {
+		ah := mockAccountHistory(100.0, 101.0, 102.0)
+		assert.Empty(t, ah.DrawdownPeriods())
+	})
+
+	t.Run("recovered and open drawdowns", func(t *testing.T) {
+		ah := mockAccountHistory(100.0, 110.0, 99.0, 88.0, 110.0, 120.0, 108.0)
+		drawdowns := ah.DrawdownPeriods()
+
+		assert.Equal(t, 2, len(drawdowns))
+
+		first := drawdowns[0]
+		assert.True(t, first.Recovered)
+		assert.Equal(t, 1, first.StartIndex)
+		assert.Equal(t, 3, first.TroughIndex)
+		assert.Equal(t, 4, first.EndIndex)
+		decimalEquals(t, 20.0, first.Depth)
+		decimalEquals(t, 110.0, first.PeakEquity)
+		decimalEquals(t, 88.0, first.TroughEquity)
+		assert.Equal(t, time.Hour*24*3, first.Length)
+		assert.Equal(t, time.Hour*24*2, first.TimeToTrough)
+		assert.Equal(t, time.Hour*24, first.TimeToRecovery)
+		assert.Equal(t, ah.Snapshots[4].Period.Start, first.Recovery)
+
+		second := drawdowns[1]
+		assert.False(t, second.Recovered)
+		assert.True(t, second.Recovery.IsZero())
+		assert.Equal(t, 5, second.StartIndex)
+		assert.Equal(t, 6, second.EndIndex)
+		decimalEquals(t, 10.0, second.Depth)
+		assert.Equal(t, time.Hour*24, second.Length)
+	})
+}
+
+func TestAccountHistory_TopDrawdowns(t *testing.T) {
+	ah := mockAccountHistory(100.0, 95.0, 100.0, 70.0, 100.0, 90.0, 100.0)
+
+	top := ah.TopDrawdowns(2)
+	assert.Equal(t, 2, len(top))
+	decimalEquals(t, 30.0, top[0].Depth)
+	decimalEquals(t, 10.0, top[1].Depth)
+
+	assert.Equal(t, 3, len(ah.TopDrawdowns(0)))
+	assert.Equal(t, 3, len(ah.TopDrawdowns(10)))
+}
+
+func TestAccountHistory_MaximumDrawdown(t *testing.T) {
+	ah := mockAccountHistory(100.0, 95.0, 100.0, 70.0, 100.0, 90.0, 100.0)
+	decimalEquals(t, 30.0, ah.MaximumDrawdown())
+
+	ah = mockAccountHistory(100.0)
+	decimalEquals(t, 0.0, ah.MaximumDrawdown())
+}
+
+func TestAccountHistory_LongestTimeUnderwater(t *testing.T) {
+	ah := mockAccountHistory(100.0, 95.0, 100.0, 99.0, 98.0, 97.0, 100.0, 90.0)
+	assert.Equal(t, time.Hour*24*4, ah.LongestTimeUnderwater())
+}
+
+func TestAccountHistory_ExportDrawdowns(t *testing.T) {
+	ah := mockAccountHistory(100.0, 110.0, 99.0, 88.0, 110.0, 120.0, 108.0)
+
+	yamlPath := "drawdowns.yaml"
+	err := ah.ExportDrawdownsYaml(yamlPath, 5)
+	assert.Nil(t, err)
+	os.Remove(yamlPath)
+
+	csvPath := "drawdowns.csv"
+	err = ah.ExportDrawdownsCsv(csvPath, 5)
+	assert.Nil(t, err)
+
+	contents, err := ioutil.ReadFile(csvPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "rank,start,trough,recovery")
+	assert.Contains(t, string(contents), "20.0000")
+	os.Remove(csvPath)
+}
@@ -0,0 +1,409 @@
+package techan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// The serialized form of the account history. Decimals are stored as strings produced by
+// encodeDecimal so that they are restored exactly, which is not the case for the yaml marshaling
+// of big.Decimal.
+type historyRecord struct {
+	Securities []string                `json:"securities"`
+	Benchmark  string                  `json:"benchmark"`
+	Prices     []pricingSnapshotRecord `json:"prices"`
+	Snapshots  []accountSnapshotRecord `json:"snapshots"`
+}
+
+type pricingSnapshotRecord struct {
+	Period TimePeriod        `json:"period"`
+	Prices map[string]string `json:"prices"`
+}
+
+type accountSnapshotRecord struct {
+	Period    TimePeriod               `json:"period"`
+	Equity    string                   `json:"equity"`
+	Cash      string                   `json:"cash"`
+	Positions []positionSnapshotRecord `json:"positions"`
+}
+
+type positionSnapshotRecord struct {
+	Security       string    `json:"security"`
+	Side           OrderSide `json:"side"`
+	Amount         string    `json:"amount"`
+	Price          string    `json:"price"`
+	UnrealizedGain string    `json:"unrealized_gain"`
+}
+
+// The csv export stores one record per row, identified by the first column. Position and price
+// rows belong to the snapshot or pricing row that precedes them.
+const (
+	csvSecurity  = "security"
+	csvBenchmark = "benchmark"
+	csvSnapshot  = "snapshot"
+	csvPosition  = "position"
+	csvPricing   = "pricing"
+	csvPrice     = "price"
+)
+
+var historyCsvHeader = []string{"record", "start", "end", "security", "side", "amount", "price", "unrealized_gain", "equity", "cash"}
+
+// Writes the full account history as json. All decimals are written as strings which restore the
+// exact same values when read with ReadAccountHistoryJson.
+func (ah *AccountHistory) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(ah.record())
+}
+
+// Exports the full account history to a json file which can be loaded with
+// LoadAccountHistoryJson.
+func (ah *AccountHistory) ExportJson(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ah.WriteJson(file)
+}
+
+// Reads an account history written by WriteJson.
+func ReadAccountHistoryJson(r io.Reader) (*AccountHistory, error) {
+	record := historyRecord{}
+
+	err := json.NewDecoder(r).Decode(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	return record.history()
+}
+
+// Loads an account history from a json file written by ExportJson.
+func LoadAccountHistoryJson(filepath string) (*AccountHistory, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadAccountHistoryJson(file)
+}
+
+// Writes the full account history as csv. Every row is a single record (security, benchmark,
+// snapshot, position, pricing or price) and position and price rows belong to the preceding
+// snapshot or pricing row. All decimals restore the exact same values when read with
+// ReadAccountHistoryCsv.
+func (ah *AccountHistory) WriteCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(historyCsvHeader)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	row := func(values ...string) []string {
+		r := make([]string, len(historyCsvHeader))
+		copy(r, values)
+		return r
+	}
+
+	for _, security := range ah.Securities {
+		rows = append(rows, row(csvSecurity, "", "", security))
+	}
+
+	if ah.Benchmark != "" {
+		rows = append(rows, row(csvBenchmark, "", "", ah.Benchmark))
+	}
+
+	record := ah.record()
+
+	for i, snapshot := range record.Snapshots {
+		start, end := formatCsvTime(snapshot.Period.Start), formatCsvTime(snapshot.Period.End)
+		rows = append(rows, row(csvSnapshot, start, end, "", "", "", "", "", snapshot.Equity, snapshot.Cash))
+
+		for _, p := range snapshot.Positions {
+			rows = append(rows, row(csvPosition, start, end, p.Security, string(p.Side), p.Amount, p.Price, p.UnrealizedGain))
+		}
+
+		if i >= len(record.Prices) {
+			continue
+		}
+
+		pricing := record.Prices[i]
+		start, end = formatCsvTime(pricing.Period.Start), formatCsvTime(pricing.Period.End)
+		rows = append(rows, row(csvPricing, start, end))
+
+		securities := make([]string, 0, len(pricing.Prices))
+		for security := range pricing.Prices {
+			securities = append(securities, security)
+		}
+		sort.Strings(securities)
+
+		for _, security := range securities {
+			rows = append(rows, row(csvPrice, start, end, security, "", "", pricing.Prices[security]))
+		}
+	}
+
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// Exports the full account history to a csv file which can be loaded with LoadAccountHistoryCsv.
+func (ah *AccountHistory) ExportCsv(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ah.WriteCsv(file)
+}
+
+// Reads an account history written by WriteCsv.
+func ReadAccountHistoryCsv(r io.Reader) (*AccountHistory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(historyCsvHeader)
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("missing csv header")
+	}
+
+	record := historyRecord{
+		Securities: []string{},
+		Prices:     []pricingSnapshotRecord{},
+		Snapshots:  []accountSnapshotRecord{},
+	}
+
+	for i, row := range rows[1:] {
+		line := i + 2
+
+		period := TimePeriod{}
+		if row[1] != "" || row[2] != "" {
+			period, err = parseCsvPeriod(row[1], row[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+		}
+
+		switch row[0] {
+		case csvSecurity:
+			record.Securities = append(record.Securities, row[3])
+		case csvBenchmark:
+			record.Benchmark = row[3]
+		case csvSnapshot:
+			record.Snapshots = append(record.Snapshots, accountSnapshotRecord{
+				Period:    period,
+				Equity:    row[8],
+				Cash:      row[9],
+				Positions: []positionSnapshotRecord{},
+			})
+		case csvPosition:
+			if len(record.Snapshots) == 0 {
+				return nil, fmt.Errorf("line %v: position without a snapshot", line)
+			}
+
+			snapshot := &record.Snapshots[len(record.Snapshots)-1]
+			snapshot.Positions = append(snapshot.Positions, positionSnapshotRecord{
+				Security:       row[3],
+				Side:           OrderSide(row[4]),
+				Amount:         row[5],
+				Price:          row[6],
+				UnrealizedGain: row[7],
+			})
+		case csvPricing:
+			record.Prices = append(record.Prices, pricingSnapshotRecord{Period: period, Prices: map[string]string{}})
+		case csvPrice:
+			if len(record.Prices) == 0 {
+				return nil, fmt.Errorf("line %v: price without a pricing snapshot", line)
+			}
+
+			record.Prices[len(record.Prices)-1].Prices[row[3]] = row[6]
+		default:
+			return nil, fmt.Errorf("line %v: unknown record type %q", line, row[0])
+		}
+	}
+
+	return record.history()
+}
+
+// Loads an account history from a csv file written by ExportCsv.
+func LoadAccountHistoryCsv(filepath string) (*AccountHistory, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadAccountHistoryCsv(file)
+}
+
+func (ah *AccountHistory) record() historyRecord {
+	record := historyRecord{
+		Securities: ah.Securities,
+		Benchmark:  ah.Benchmark,
+		Prices:     make([]pricingSnapshotRecord, len(ah.Prices)),
+		Snapshots:  make([]accountSnapshotRecord, len(ah.Snapshots)),
+	}
+
+	if record.Securities == nil {
+		record.Securities = []string{}
+	}
+
+	for i, pricing := range ah.Prices {
+		record.Prices[i] = pricingSnapshotRecord{Period: pricing.Period, Prices: map[string]string{}}
+		for security, price := range pricing.Prices {
+			record.Prices[i].Prices[security] = encodeDecimal(price)
+		}
+	}
+
+	for i, snapshot := range ah.Snapshots {
+		record.Snapshots[i] = accountSnapshotRecord{
+			Period:    snapshot.Period,
+			Equity:    encodeDecimal(snapshot.Equity),
+			Cash:      encodeDecimal(snapshot.Cash),
+			Positions: make([]positionSnapshotRecord, len(snapshot.Positions)),
+		}
+
+		for j, p := range snapshot.Positions {
+			record.Snapshots[i].Positions[j] = positionSnapshotRecord{
+				Security:       p.Security,
+				Side:           p.Side,
+				Amount:         encodeDecimal(p.Amount),
+				Price:          encodeDecimal(p.Price),
+				UnrealizedGain: encodeDecimal(p.UnrealizedGain),
+			}
+		}
+	}
+
+	return record
+}
+
+func (record historyRecord) history() (*AccountHistory, error) {
+	if len(record.Prices) != len(record.Snapshots) {
+		return nil, fmt.Errorf(
+			"number of pricing and account snapshots do not match: %v <-> %v",
+			len(record.Prices),
+			len(record.Snapshots),
+		)
+	}
+
+	ah := NewAccountHistory()
+	ah.Securities = append(ah.Securities, record.Securities...)
+	ah.Benchmark = record.Benchmark
+
+	for i, s := range record.Snapshots {
+		snapshot := AccountSnapshot{Period: s.Period, Positions: []*PositionSnapshot{}}
+
+		var err error
+		if snapshot.Equity, err = decodeDecimal(s.Equity); err != nil {
+			return nil, fmt.Errorf("snapshot %v: %v", i, err)
+		}
+
+		if snapshot.Cash, err = decodeDecimal(s.Cash); err != nil {
+			return nil, fmt.Errorf("snapshot %v: %v", i, err)
+		}
+
+		for _, p := range s.Positions {
+			position := PositionSnapshot{Security: p.Security, Side: p.Side}
+
+			if position.Amount, err = decodeDecimal(p.Amount); err != nil {
+				return nil, fmt.Errorf("snapshot %v: %v", i, err)
+			}
+
+			if position.Price, err = decodeDecimal(p.Price); err != nil {
+				return nil, fmt.Errorf("snapshot %v: %v", i, err)
+			}
+
+			if position.UnrealizedGain, err = decodeDecimal(p.UnrealizedGain); err != nil {
+				return nil, fmt.Errorf("snapshot %v: %v", i, err)
+			}
+
+			snapshot.Positions = append(snapshot.Positions, &position)
+		}
+
+		pricing := PricingSnapshot{Period: record.Prices[i].Period, Prices: Pricing{}}
+		for security, p := range record.Prices[i].Prices {
+			if pricing.Prices[security], err = decodeDecimal(p); err != nil {
+				return nil, fmt.Errorf("pricing snapshot %v: %v", i, err)
+			}
+		}
+
+		err = ah.ApplySnapshot(&snapshot, &pricing)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ah, nil
+}
+
+func formatCsvTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseCsvPeriod(start, end string) (TimePeriod, error) {
+	s, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return TimePeriod{}, err
+	}
+
+	e, err := time.Parse(time.RFC3339Nano, end)
+	if err != nil {
+		return TimePeriod{}, err
+	}
+
+	return TimePeriod{Start: s, End: e}, nil
+}
+
+// encodeDecimal formats a decimal so that decodeDecimal restores the exact same value. Decimals
+// are written in the shortest form that parses back to the same binary value, which for any value
+// in the range of a float64 is its shortest float64 form.
+func encodeDecimal(d big.Decimal) string {
+	if d.NaN() {
+		return "NaN"
+	}
+
+	f := d.Float()
+	if big.NewDecimal(f).EQ(d) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	text, _ := d.MarshalJSON()
+	return strings.Trim(string(text), "\"")
+}
+
+// decodeDecimal parses a decimal written by encodeDecimal.
+func decodeDecimal(s string) (big.Decimal, error) {
+	if s == "NaN" {
+		return big.NaN, nil
+	}
+
+	d := big.NewFromString(s)
+	if d.NaN() {
+		return big.NaN, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	return d, nil
+}
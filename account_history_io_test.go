@@ -0,0 +1,167 @@
+package techan
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSerializedHistory() *AccountHistory {
+	ah := NewAccountHistory()
+	ah.Securities = []string{"AAPL", "MSFT"}
+	ah.Benchmark = "SPY"
+
+	start := time.Date(2020, time.January, 1, 9, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	third := big.ONE.Div(big.NewFromString("3"))
+
+	for i := 0; i < 3; i++ {
+		period := NewTimePeriod(start.AddDate(0, 0, i), time.Hour*24)
+		snap := AccountSnapshot{
+			Period: period,
+			Equity: big.NewFromString("10000.1").Add(third.Mul(big.NewFromInt(i))),
+			Cash:   big.NewDecimal(0.1),
+			Positions: []*PositionSnapshot{
+				{
+					Security:       "AAPL",
+					Side:           BUY,
+					Amount:         big.NewDecimal(10),
+					Price:          big.NewFromString("123.45"),
+					UnrealizedGain: third,
+				},
+			},
+		}
+		pricing := PricingSnapshot{
+			Period: period,
+			Prices: Pricing{
+				"AAPL": big.NewFromString("123.45"),
+				"MSFT": big.NewDecimal(234.56),
+				"SPY":  big.NewFromString("36028797018963970"),
+			},
+		}
+
+		ah.ApplySnapshot(&snap, &pricing)
+	}
+
+	return ah
+}
+
+func assertHistoryEquals(t *testing.T, expected, actual *AccountHistory) {
+	assert.Equal(t, expected.Securities, actual.Securities)
+	assert.Equal(t, expected.Benchmark, actual.Benchmark)
+	assert.Equal(t, len(expected.Snapshots), len(actual.Snapshots))
+	assert.Equal(t, len(expected.Prices), len(actual.Prices))
+
+	for i, e := range expected.Snapshots {
+		a := actual.Snapshots[i]
+
+		assert.True(t, e.Period.Start.Equal(a.Period.Start))
+		assert.True(t, e.Period.End.Equal(a.Period.End))
+		assert.True(t, e.Equity.EQ(a.Equity), "%v <-> %v", encodeDecimal(e.Equity), encodeDecimal(a.Equity))
+		assert.True(t, e.Cash.EQ(a.Cash))
+		assert.Equal(t, len(e.Positions), len(a.Positions))
+
+		for j, p := range e.Positions {
+			assert.Equal(t, p.Security, a.Positions[j].Security)
+			assert.Equal(t, p.Side, a.Positions[j].Side)
+			assert.True(t, p.Amount.EQ(a.Positions[j].Amount))
+			assert.True(t, p.Price.EQ(a.Positions[j].Price))
+			assert.True(t, p.UnrealizedGain.EQ(a.Positions[j].UnrealizedGain))
+		}
+
+		assert.True(t, expected.Prices[i].Period.Start.Equal(actual.Prices[i].Period.Start))
+		assert.Equal(t, len(expected.Prices[i].Prices), len(actual.Prices[i].Prices))
+		for security, price := range expected.Prices[i].Prices {
+			assert.True(t, price.EQ(actual.Prices[i].Prices[security]), security)
+		}
+	}
+}
+
+func TestDecimalEncoding(t *testing.T) {
+	tests := []big.Decimal{
+		big.ZERO,
+		big.NewDecimal(0.1),
+		big.NewFromString("0.1"),
+		big.NewDecimal(-1234.5678),
+		big.NewFromString("-1234.5678"),
+		big.ONE.Div(big.NewFromString("3")),
+		big.NewFromString("36028797018963970"),
+		big.NewDecimal(1e-12),
+		big.NewDecimal(1e300),
+		big.NewDecimal(1e-300).Mul(big.NewDecimal(1e-300)),
+		big.NewDecimal(math.Inf(-1)),
+	}
+
+	for _, d := range tests {
+		encoded := encodeDecimal(d)
+		decoded, err := decodeDecimal(encoded)
+
+		assert.Nil(t, err)
+		assert.True(t, d.EQ(decoded), encoded)
+	}
+
+	assert.Equal(t, "0.1", encodeDecimal(big.NewDecimal(0.1)))
+
+	nan, err := decodeDecimal(encodeDecimal(big.NaN))
+	assert.Nil(t, err)
+	assert.True(t, nan.NaN())
+
+	_, err = decodeDecimal("abc")
+	assert.NotNil(t, err)
+}
+
+func TestAccountHistory_JsonRoundTrip(t *testing.T) {
+	ah := mockSerializedHistory()
+
+	var buf bytes.Buffer
+	assert.Nil(t, ah.WriteJson(&buf))
+
+	loaded, err := ReadAccountHistoryJson(&buf)
+	assert.Nil(t, err)
+	assertHistoryEquals(t, ah, loaded)
+
+	filepath := "history.json"
+	assert.Nil(t, ah.ExportJson(filepath))
+	loaded, err = LoadAccountHistoryJson(filepath)
+	assert.Nil(t, err)
+	assertHistoryEquals(t, ah, loaded)
+	os.Remove(filepath)
+}
+
+func TestAccountHistory_CsvRoundTrip(t *testing.T) {
+	ah := mockSerializedHistory()
+
+	var buf bytes.Buffer
+	assert.Nil(t, ah.WriteCsv(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "record,start,end,security"))
+
+	loaded, err := ReadAccountHistoryCsv(&buf)
+	assert.Nil(t, err)
+	assertHistoryEquals(t, ah, loaded)
+
+	filepath := "history.csv"
+	assert.Nil(t, ah.ExportCsv(filepath))
+	loaded, err = LoadAccountHistoryCsv(filepath)
+	assert.Nil(t, err)
+	assertHistoryEquals(t, ah, loaded)
+	os.Remove(filepath)
+}
+
+func TestReadAccountHistoryCsv_Errors(t *testing.T) {
+	header := strings.Join(historyCsvHeader, ",") + "\n"
+
+	_, err := ReadAccountHistoryCsv(strings.NewReader(header + "position,,,AAPL,BUY,1,1,0,,\n"))
+	assert.EqualError(t, err, "line 2: position without a snapshot")
+
+	_, err = ReadAccountHistoryCsv(strings.NewReader(header + "unknown,,,,,,,,,\n"))
+	assert.EqualError(t, err, "line 2: unknown record type \"unknown\"")
+
+	_, err = ReadAccountHistoryCsv(strings.NewReader(header +
+		"snapshot,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,,,,,,1,1\n"))
+	assert.EqualError(t, err, "number of pricing and account snapshots do not match: 0 <-> 1")
+}
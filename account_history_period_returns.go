@@ -0,0 +1,290 @@
+package techan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/schmidthole/big"
+	"gopkg.in/yaml.v3"
+)
+
+// ReturnFrequency defines the calendar buckets that account returns are grouped into.
+type ReturnFrequency string
+
+// ReturnFrequency enumerations. Weeks follow the ISO 8601 definition and start on a Monday.
+const (
+	DAILY     ReturnFrequency = "daily"
+	WEEKLY    ReturnFrequency = "weekly"
+	MONTHLY   ReturnFrequency = "monthly"
+	QUARTERLY ReturnFrequency = "quarterly"
+	YEARLY    ReturnFrequency = "yearly"
+)
+
+// A MonthlyReturnRow is a single year of the monthly return matrix. Months without any snapshots
+// are nil. The total is the return for the whole calendar year.
+type MonthlyReturnRow struct {
+	Year   int              `yaml:"year" json:"year"`
+	Months [12]*big.Decimal `yaml:"months" json:"months"`
+	Total  big.Decimal      `yaml:"total" json:"total"`
+}
+
+// A PeriodReturnReport summarizes the account returns for a given frequency, along with the
+// year by month return matrix that is commonly displayed as a heatmap.
+type PeriodReturnReport struct {
+	Frequency       ReturnFrequency    `yaml:"frequency" json:"frequency"`
+	Returns         []ReturnPeriod     `yaml:"returns" json:"returns"`
+	Best            *ReturnPeriod      `yaml:"best" json:"best"`
+	Worst           *ReturnPeriod      `yaml:"worst" json:"worst"`
+	PercentPositive big.Decimal        `yaml:"percent_positive" json:"percent_positive"`
+	MonthlyMatrix   []MonthlyReturnRow `yaml:"monthly_matrix" json:"monthly_matrix"`
+}
+
+// Calculates the account returns bucketed by calendar period. Period boundaries are evaluated in
+// the given location, or in the location of each snapshot if the location is nil. Each period's
+// return is measured from the closing equity of the previous period, so that the returns of
+// consecutive periods compound to the total return. The first period is measured from the first
+// snapshot.
+func (ah *AccountHistory) PeriodReturns(frequency ReturnFrequency, location *time.Location) []ReturnPeriod {
+	returns := []ReturnPeriod{}
+
+	if len(ah.Snapshots) < 2 {
+		return returns
+	}
+
+	startIndex := 0
+	startEquity := ah.Snapshots[0].Equity
+
+	for i := 1; i <= ah.LastIndex()+1; i++ {
+		if i <= ah.LastIndex() && periodLabel(ah.Snapshots[i].Period.Start, frequency, location) ==
+			periodLabel(ah.Snapshots[startIndex].Period.Start, frequency, location) {
+			continue
+		}
+
+		end := ah.Snapshots[i-1]
+		returns = append(returns, ah.closeReturnPeriod(startIndex, i-1, startEquity, frequency, location))
+
+		startIndex = i
+		startEquity = end.Equity
+	}
+
+	return returns
+}
+
+// Summarizes the account returns for a given frequency, including the best and worst periods, the
+// percentage of positive periods and the monthly return matrix.
+func (ah *AccountHistory) PeriodReturnReport(frequency ReturnFrequency, location *time.Location) PeriodReturnReport {
+	report := PeriodReturnReport{
+		Frequency:       frequency,
+		Returns:         ah.PeriodReturns(frequency, location),
+		PercentPositive: big.ZERO,
+		MonthlyMatrix:   ah.MonthlyReturnMatrix(location),
+	}
+
+	if len(report.Returns) == 0 {
+		return report
+	}
+
+	positive := 0
+	for i := range report.Returns {
+		r := &report.Returns[i]
+
+		if r.PercentGain.GT(big.ZERO) {
+			positive++
+		}
+
+		if report.Best == nil || r.PercentGain.GT(report.Best.PercentGain) {
+			report.Best = r
+		}
+
+		if report.Worst == nil || r.PercentGain.LT(report.Worst.PercentGain) {
+			report.Worst = r
+		}
+	}
+
+	report.PercentPositive = big.NewFromInt(positive).Div(big.NewFromInt(len(report.Returns))).Mul(big.NewDecimal(100.00))
+
+	return report
+}
+
+// Builds a year by month matrix of the monthly account returns, with a yearly total for each row.
+func (ah *AccountHistory) MonthlyReturnMatrix(location *time.Location) []MonthlyReturnRow {
+	rows := []MonthlyReturnRow{}
+	yearly := map[int]big.Decimal{}
+
+	for _, r := range ah.PeriodReturns(YEARLY, location) {
+		yearly[inLocation(r.Period.Start, location).Year()] = r.PercentGain
+	}
+
+	for _, r := range ah.PeriodReturns(MONTHLY, location) {
+		start := inLocation(r.Period.Start, location)
+
+		if len(rows) == 0 || rows[len(rows)-1].Year != start.Year() {
+			rows = append(rows, MonthlyReturnRow{Year: start.Year(), Total: yearly[start.Year()]})
+		}
+
+		gain := r.PercentGain
+		rows[len(rows)-1].Months[start.Month()-1] = &gain
+	}
+
+	return rows
+}
+
+// Exports the period return report to yaml for viewing and analysis.
+func (ah *AccountHistory) ExportPeriodReturnsYaml(filepath string, frequency ReturnFrequency, location *time.Location) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	defer encoder.Close()
+
+	err = encoder.Encode(ah.PeriodReturnReport(frequency, location))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Exports the period return report to json for viewing and analysis.
+func (ah *AccountHistory) ExportPeriodReturnsJson(filepath string, frequency ReturnFrequency, location *time.Location) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(ah.PeriodReturnReport(frequency, location))
+}
+
+// Exports the period returns as a csv table for viewing and analysis.
+func (ah *AccountHistory) ExportPeriodReturnsCsv(filepath string, frequency ReturnFrequency, location *time.Location) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	err = writer.Write([]string{"label", "start", "end", "percent_gain", "total_profit"})
+	if err != nil {
+		return err
+	}
+
+	layout := SimpleDateFormatV2 + "T" + SimpleTimeFormat
+
+	for _, r := range ah.PeriodReturns(frequency, location) {
+		err = writer.Write([]string{
+			r.Label,
+			r.Period.Start.Format(layout),
+			r.Period.End.Format(layout),
+			r.PercentGain.FormattedString(4),
+			r.TotalProfit.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// Exports the monthly return matrix as a csv table with one row per year for viewing and analysis.
+func (ah *AccountHistory) ExportMonthlyReturnMatrixCsv(filepath string, location *time.Location) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	header := []string{"year"}
+	for m := time.January; m <= time.December; m++ {
+		header = append(header, m.String()[:3])
+	}
+	header = append(header, "total")
+
+	err = writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range ah.MonthlyReturnMatrix(location) {
+		record := []string{strconv.Itoa(row.Year)}
+		for _, gain := range row.Months {
+			if gain == nil {
+				record = append(record, "")
+			} else {
+				record = append(record, gain.FormattedString(4))
+			}
+		}
+		record = append(record, row.Total.FormattedString(4))
+
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (ah *AccountHistory) closeReturnPeriod(startIndex, endIndex int, startEquity big.Decimal, frequency ReturnFrequency, location *time.Location) ReturnPeriod {
+	start := ah.Snapshots[startIndex].Period.Start
+	endEquity := ah.Snapshots[endIndex].Equity
+	profit := endEquity.Sub(startEquity)
+
+	percentGain := big.ZERO
+	if !startEquity.IsZero() {
+		percentGain = profit.Div(startEquity).Mul(big.NewDecimal(100.00))
+	}
+
+	return ReturnPeriod{
+		Label:       periodLabel(start, frequency, location),
+		Period:      TimePeriod{Start: start, End: ah.Snapshots[endIndex].Period.Start},
+		TotalProfit: profit,
+		PercentGain: percentGain,
+	}
+}
+
+// periodLabel returns a label which uniquely identifies the calendar period a time falls in.
+func periodLabel(t time.Time, frequency ReturnFrequency, location *time.Location) string {
+	t = inLocation(t, location)
+
+	switch frequency {
+	case DAILY:
+		return t.Format(SimpleDateFormatV2)
+	case WEEKLY:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case QUARTERLY:
+		return fmt.Sprintf("%04d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case YEARLY:
+		return fmt.Sprintf("%04d", t.Year())
+	default:
+		return fmt.Sprintf("%04d-%02d", t.Year(), int(t.Month()))
+	}
+}
+
+func inLocation(t time.Time, location *time.Location) time.Time {
+	if location == nil {
+		return t
+	}
+
+	return t.In(location)
+}
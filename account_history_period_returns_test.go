@@ -0,0 +1,153 @@
+package techan
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockCalendarHistory() *AccountHistory {
+	dates := []time.Time{
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.February, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.March, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.April, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC),
+	}
+	equities := []float64{100.0, 110.0, 121.0, 108.9, 119.79, 119.79}
+
+	ah := NewAccountHistory()
+	for i, e := range equities {
+		period := NewTimePeriod(dates[i], time.Hour*24)
+		snap := AccountSnapshot{Period: period, Equity: big.NewDecimal(e)}
+		pricing := PricingSnapshot{Period: period}
+
+		ah.ApplySnapshot(&snap, &pricing)
+	}
+
+	return ah
+}
+
+func TestAccountHistory_PeriodReturns(t *testing.T) {
+	tests := []struct {
+		frequency ReturnFrequency
+		labels    []string
+		gains     []float64
+	}{
+		{
+			frequency: DAILY,
+			labels:    []string{"2020-01-01", "2020-01-31", "2020-02-03", "2020-03-31", "2020-04-01", "2021-01-04"},
+			gains:     []float64{0.0, 10.0, 10.0, -10.0, 10.0, 0.0},
+		},
+		{
+			frequency: WEEKLY,
+			labels:    []string{"2020-W01", "2020-W05", "2020-W06", "2020-W14", "2021-W01"},
+			gains:     []float64{0.0, 10.0, 10.0, -1.0, 0.0},
+		},
+		{
+			frequency: MONTHLY,
+			labels:    []string{"2020-01", "2020-02", "2020-03", "2020-04", "2021-01"},
+			gains:     []float64{10.0, 10.0, -10.0, 10.0, 0.0},
+		},
+		{
+			frequency: QUARTERLY,
+			labels:    []string{"2020-Q1", "2020-Q2", "2021-Q1"},
+			gains:     []float64{8.9, 10.0, 0.0},
+		},
+		{
+			frequency: YEARLY,
+			labels:    []string{"2020", "2021"},
+			gains:     []float64{19.79, 0.0},
+		},
+	}
+
+	ah := mockCalendarHistory()
+
+	for _, tt := range tests {
+		t.Run(string(tt.frequency), func(t *testing.T) {
+			returns := ah.PeriodReturns(tt.frequency, time.UTC)
+
+			assert.Equal(t, len(tt.labels), len(returns))
+			for i, r := range returns {
+				assert.Equal(t, tt.labels[i], r.Label)
+				decimalEquals(t, tt.gains[i], r.PercentGain)
+			}
+		})
+	}
+}
+
+func TestAccountHistory_PeriodReturnsLocation(t *testing.T) {
+	ah := NewAccountHistory()
+	for i, date := range []time.Time{
+		time.Date(2020, time.January, 30, 12, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 31, 20, 0, 0, 0, time.UTC),
+	} {
+		period := NewTimePeriod(date, time.Hour)
+		snap := AccountSnapshot{Period: period, Equity: big.NewFromInt(100 + i)}
+		ah.ApplySnapshot(&snap, &PricingSnapshot{Period: period})
+	}
+
+	assert.Equal(t, 1, len(ah.PeriodReturns(MONTHLY, time.UTC)))
+	assert.Equal(t, 2, len(ah.PeriodReturns(MONTHLY, time.FixedZone("UTC+9", 9*60*60))))
+}
+
+func TestAccountHistory_PeriodReturnReport(t *testing.T) {
+	ah := mockCalendarHistory()
+	report := ah.PeriodReturnReport(MONTHLY, time.UTC)
+
+	assert.Equal(t, MONTHLY, report.Frequency)
+	assert.Equal(t, "2020-01", report.Best.Label)
+	assert.Equal(t, "2020-03", report.Worst.Label)
+	decimalEquals(t, 60.0, report.PercentPositive)
+
+	empty := NewAccountHistory().PeriodReturnReport(MONTHLY, time.UTC)
+	assert.Nil(t, empty.Best)
+	decimalEquals(t, 0.0, empty.PercentPositive)
+}
+
+func TestAccountHistory_MonthlyReturnMatrix(t *testing.T) {
+	ah := mockCalendarHistory()
+	matrix := ah.MonthlyReturnMatrix(time.UTC)
+
+	assert.Equal(t, 2, len(matrix))
+	assert.Equal(t, 2020, matrix[0].Year)
+	decimalEquals(t, 10.0, *matrix[0].Months[0])
+	decimalEquals(t, -10.0, *matrix[0].Months[2])
+	assert.Nil(t, matrix[0].Months[4])
+	decimalEquals(t, 19.79, matrix[0].Total)
+	assert.Equal(t, 2021, matrix[1].Year)
+}
+
+func TestAccountHistory_ExportPeriodReturns(t *testing.T) {
+	ah := mockCalendarHistory()
+
+	yamlPath := "period_returns.yaml"
+	assert.Nil(t, ah.ExportPeriodReturnsYaml(yamlPath, QUARTERLY, time.UTC))
+	os.Remove(yamlPath)
+
+	jsonPath := "period_returns.json"
+	assert.Nil(t, ah.ExportPeriodReturnsJson(jsonPath, QUARTERLY, time.UTC))
+	contents, err := ioutil.ReadFile(jsonPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "\"2020-Q2\"")
+	os.Remove(jsonPath)
+
+	csvPath := "period_returns.csv"
+	assert.Nil(t, ah.ExportPeriodReturnsCsv(csvPath, QUARTERLY, time.UTC))
+	contents, err = ioutil.ReadFile(csvPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "2020-Q1,")
+	os.Remove(csvPath)
+
+	matrixPath := "monthly_matrix.csv"
+	assert.Nil(t, ah.ExportMonthlyReturnMatrixCsv(matrixPath, time.UTC))
+	contents, err = ioutil.ReadFile(matrixPath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "year,Jan,Feb")
+	os.Remove(matrixPath)
+}
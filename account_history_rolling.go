@@ -0,0 +1,123 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/schmidthole/big"
+)
+
+// The rolling indicators below are derived from the account history in the same way as
+// AccountEquityAsIndicator. Each index of the indicator matches a snapshot index and is computed
+// over the window of snapshots ending at that index. Indexes without a full window return zero,
+// and a window which is not positive panics.
+// The rolling beta against a benchmark is provided by RollingBetaIndicator.
+
+// Derive an Indicator of the percentage gain of the account equity over a rolling window of
+// snapshots.
+func (ah *AccountHistory) RollingReturnIndicator(window int) Indicator {
+	checkRollingWindow(window)
+	values := make([]float64, len(ah.Snapshots))
+
+	for i := window; i < len(values); i++ {
+		start := ah.Snapshots[i-window].Equity
+		if start.IsZero() {
+			continue
+		}
+
+		values[i] = ah.Snapshots[i].Equity.Sub(start).Div(start).Mul(big.NewDecimal(100.00)).Float()
+	}
+
+	return NewFixedIndicator(values...)
+}
+
+// Derive an Indicator of the annualized volatility of the account returns, as a percentage, over a
+// rolling window of returns.
+func (ah *AccountHistory) RollingVolatilityIndicator(window int) Indicator {
+	return ah.rollingReturnsIndicator(window, func(returns []float64) float64 {
+		return stdev(returns) * math.Sqrt(benchmarkPeriodsPerYear) * 100.0
+	})
+}
+
+// Derive an Indicator of the annualized Sharpe ratio of the account returns over a rolling window
+// of returns. The risk free rate is supplied as an annual percentage.
+func (ah *AccountHistory) RollingSharpeIndicator(window int, riskFreeRate big.Decimal) Indicator {
+	riskFree := riskFreeRate.Float() / 100.0 / benchmarkPeriodsPerYear
+
+	return ah.rollingReturnsIndicator(window, func(returns []float64) float64 {
+		return sharpeRatio(returns, riskFree)
+	})
+}
+
+// Derive an Indicator of the maximum peak-to-trough drawdown, as a percentage, within a rolling
+// window of snapshots.
+func (ah *AccountHistory) RollingDrawdownIndicator(window int) Indicator {
+	checkRollingWindow(window)
+	values := make([]float64, len(ah.Snapshots))
+
+	for i := window; i < len(values); i++ {
+		peak := ah.Snapshots[i-window].Equity
+		maxDrawdown := big.ZERO
+
+		for j := i - window; j <= i; j++ {
+			equity := ah.Snapshots[j].Equity
+			if equity.GT(peak) {
+				peak = equity
+			}
+
+			drawdown := percentBelow(peak, equity)
+			if drawdown.GT(maxDrawdown) {
+				maxDrawdown = drawdown
+			}
+		}
+
+		values[i] = maxDrawdown.Float()
+	}
+
+	return NewFixedIndicator(values...)
+}
+
+// equityReturns returns the fractional return of the account equity between each snapshot and
+// the snapshot before it. The first snapshot has no return and is reported as zero, as are
+// returns from a zero equity.
+func (ah *AccountHistory) equityReturns() []float64 {
+	returns := make([]float64, len(ah.Snapshots))
+
+	for i := 1; i < len(ah.Snapshots); i++ {
+		last := ah.Snapshots[i-1].Equity
+		if last.IsZero() {
+			continue
+		}
+
+		returns[i] = ah.Snapshots[i].Equity.Div(last).Sub(big.ONE).Float()
+	}
+
+	return returns
+}
+
+func (ah *AccountHistory) rollingReturnsIndicator(window int, stat func(returns []float64) float64) Indicator {
+	checkRollingWindow(window)
+	returns := ah.equityReturns()
+	values := make([]float64, len(ah.Snapshots))
+
+	for i := window; i < len(values); i++ {
+		values[i] = stat(returns[i-window+1 : i+1])
+	}
+
+	return NewFixedIndicator(values...)
+}
+
+func sharpeRatio(returns []float64, riskFree float64) float64 {
+	deviation := stdev(returns)
+	if deviation == 0.0 {
+		return 0.0
+	}
+
+	return (mean(returns) - riskFree) / deviation * math.Sqrt(benchmarkPeriodsPerYear)
+}
+
+func checkRollingWindow(window int) {
+	if window <= 0 {
+		panic(fmt.Errorf("error creating rolling indicator: window must be positive"))
+	}
+}
@@ -0,0 +1,57 @@
+package techan
+
+import (
+	"testing"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockRollingEquities = []float64{100.0, 110.0, 99.0, 108.9, 119.79}
+
+func TestAccountHistory_RollingReturnIndicator(t *testing.T) {
+	ah := mockAccountHistory(mockRollingEquities...)
+	indicatorEquals(t, []float64{0.0, 0.0, -1.0, -1.0, 21.0}, ah.RollingReturnIndicator(2))
+}
+
+func TestAccountHistory_RollingVolatilityIndicator(t *testing.T) {
+	ah := mockAccountHistory(mockRollingEquities...)
+	indicatorEquals(t, []float64{0.0, 0.0, 224.4994, 224.4994, 0.0}, ah.RollingVolatilityIndicator(2))
+	indicatorEquals(t, []float64{0.0, 0.0, 0.0, 183.303, 183.303}, ah.RollingVolatilityIndicator(3))
+	indicatorEquals(t, []float64{0.0, 0.0, 0.0, 0.0, 0.0}, ah.RollingVolatilityIndicator(5))
+}
+
+func TestAccountHistory_RollingSharpeIndicator(t *testing.T) {
+	ah := mockAccountHistory(mockRollingEquities...)
+	indicatorEquals(t, []float64{0.0, 0.0, 0.0, 4.5826, 4.5826}, ah.RollingSharpeIndicator(3, big.ZERO))
+}
+
+func TestAccountHistory_RollingDrawdownIndicator(t *testing.T) {
+	ah := mockAccountHistory(mockRollingEquities...)
+	indicatorEquals(t, []float64{0.0, 0.0, 10.0, 10.0, 0.0}, ah.RollingDrawdownIndicator(2))
+}
+
+func TestAccountHistory_RollingIndicatorWindow(t *testing.T) {
+	ah := mockAccountHistory(mockRollingEquities...)
+
+	for _, window := range []int{0, -1} {
+		assert.PanicsWithError(t, "error creating rolling indicator: window must be positive", func() {
+			ah.RollingReturnIndicator(window)
+		})
+		assert.PanicsWithError(t, "error creating rolling indicator: window must be positive", func() {
+			ah.RollingVolatilityIndicator(window)
+		})
+		assert.PanicsWithError(t, "error creating rolling indicator: window must be positive", func() {
+			ah.RollingSharpeIndicator(window, big.ZERO)
+		})
+		assert.PanicsWithError(t, "error creating rolling indicator: window must be positive", func() {
+			ah.RollingDrawdownIndicator(window)
+		})
+		assert.PanicsWithError(t, "error creating rolling indicator: window must be positive", func() {
+			ah.RollingBetaIndicator(window)
+		})
+	}
+
+	indicatorEquals(t, []float64{0.0, 10.0, -10.0, 10.0, 10.0}, ah.RollingReturnIndicator(1))
+	indicatorEquals(t, []float64{0.0, 0.0, 0.0, 0.0, 0.0}, ah.RollingReturnIndicator(len(mockRollingEquities)))
+}
@@ -0,0 +1,571 @@
+package techan
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// TearsheetOptions configures the html tearsheet generated from an AccountHistory. If no trades
+// are provided, they are implied from the changes in the position snapshots.
+type TearsheetOptions struct {
+	Title        string
+	Trades       []*Order
+	Location     *time.Location
+	RiskFreeRate big.Decimal
+}
+
+type tearsheetMetric struct {
+	Name  string
+	Value string
+}
+
+type tearsheetHeatmapCell struct {
+	Value string
+	Color template.CSS
+}
+
+type tearsheetHeatmapRow struct {
+	Year   int
+	Months []tearsheetHeatmapCell
+	Total  tearsheetHeatmapCell
+}
+
+type tearsheetTrade struct {
+	Security  string
+	EntryTime string
+	ExitTime  string
+	Quantity  string
+	Entry     string
+	Exit      string
+	Profit    string
+	Return    string
+	Holding   string
+}
+
+type tearsheetData struct {
+	Title      string
+	Generated  string
+	Metrics    []tearsheetMetric
+	Equity     template.HTML
+	Underwater template.HTML
+	Exposure   template.HTML
+	Heatmap    []tearsheetHeatmapRow
+	Trades     []tearsheetTrade
+}
+
+// Writes a self-contained html tearsheet of the account history. The report contains the key
+// metrics, an equity curve with the benchmark overlaid if one is set, the underwater chart, the
+// monthly return heatmap, the position exposure over time and the list of round trip trades.
+// All charts are rendered as inline svg so the report has no external assets.
+func (ah *AccountHistory) WriteTearsheetHtml(w io.Writer, options TearsheetOptions) error {
+	if len(ah.Snapshots) == 0 {
+		return fmt.Errorf("cannot create a tearsheet from an empty account history")
+	}
+
+	if options.Title == "" {
+		options.Title = "Backtest Tearsheet"
+	}
+
+	if options.RiskFreeRate.NaN() {
+		options.RiskFreeRate = big.ZERO
+	}
+
+	trades := options.Trades
+	if trades == nil {
+		trades = ah.ImpliedOrders()
+	}
+
+	analysis, err := AnalyzeTrades(trades, nil)
+	if err != nil {
+		return err
+	}
+
+	data := tearsheetData{
+		Title:      options.Title,
+		Generated:  time.Now().Format(SimpleDateFormatV2 + " " + SimpleTimeFormat),
+		Metrics:    ah.tearsheetMetrics(analysis, options.RiskFreeRate),
+		Equity:     template.HTML(ah.equityChartSvg()),
+		Underwater: template.HTML(ah.underwaterChartSvg()),
+		Exposure:   template.HTML(ah.exposureChartSvg()),
+		Heatmap:    ah.tearsheetHeatmap(options.Location),
+		Trades:     tearsheetTrades(analysis),
+	}
+
+	return tearsheetTemplate.Execute(w, data)
+}
+
+// Exports a self-contained html tearsheet of the account history to a file.
+func (ah *AccountHistory) ExportTearsheetHtml(filepath string, options TearsheetOptions) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ah.WriteTearsheetHtml(file, options)
+}
+
+// Derives the orders executed by the account from the changes in position amounts between
+// consecutive snapshots. Orders are priced at the position price of the snapshot they occur in,
+// and sells are listed before buys within a snapshot.
+func (ah *AccountHistory) ImpliedOrders() []*Order {
+	orders := []*Order{}
+	lastAmounts := map[string]big.Decimal{}
+
+	for i, snapshot := range ah.Snapshots {
+		amounts := map[string]big.Decimal{}
+		prices := map[string]big.Decimal{}
+
+		for _, pos := range snapshot.Positions {
+			amounts[pos.Security] = pos.Amount
+			prices[pos.Security] = pos.Price
+		}
+
+		securities := []string{}
+		for security := range amounts {
+			securities = append(securities, security)
+		}
+		for security := range lastAmounts {
+			if _, exists := amounts[security]; !exists {
+				securities = append(securities, security)
+			}
+		}
+		sort.Strings(securities)
+
+		sells := []*Order{}
+		buys := []*Order{}
+
+		for _, security := range securities {
+			amount, exists := amounts[security]
+			if !exists {
+				amount = big.ZERO
+			}
+
+			last, exists := lastAmounts[security]
+			if !exists {
+				last = big.ZERO
+			}
+
+			diff := amount.Sub(last)
+			if diff.IsZero() {
+				continue
+			}
+
+			price, exists := prices[security]
+			if !exists {
+				price, _ = ah.PriceAtIndex(security, i)
+			}
+
+			order := &Order{
+				Security:      security,
+				Side:          BUY,
+				Amount:        diff.Abs(),
+				Price:         price,
+				ExecutionTime: snapshot.Period.Start,
+			}
+
+			if diff.LT(big.ZERO) {
+				order.Side = SELL
+				sells = append(sells, order)
+			} else {
+				buys = append(buys, order)
+			}
+		}
+
+		orders = append(orders, sells...)
+		orders = append(orders, buys...)
+		lastAmounts = amounts
+	}
+
+	return orders
+}
+
+// Calculates the fraction of the account equity invested in open positions at each snapshot as a
+// percentage.
+func (ah *AccountHistory) Exposure() []big.Decimal {
+	exposure := make([]big.Decimal, len(ah.Snapshots))
+
+	for i, snapshot := range ah.Snapshots {
+		invested := big.ZERO
+		for _, pos := range snapshot.Positions {
+			invested = invested.Add(pos.Amount.Mul(pos.Price))
+		}
+
+		if snapshot.Equity.IsZero() || snapshot.Equity.NaN() {
+			exposure[i] = big.ZERO
+			continue
+		}
+
+		exposure[i] = invested.Div(snapshot.Equity).Mul(big.NewDecimal(100.00))
+	}
+
+	return exposure
+}
+
+func (ah *AccountHistory) tearsheetMetrics(analysis *TradeAnalysis, riskFreeRate big.Decimal) []tearsheetMetric {
+	layout := SimpleDateFormatV2
+
+	metrics := []tearsheetMetric{
+		{"Start", ah.Snapshots[0].Period.Start.Format(layout)},
+		{"End", ah.Snapshots[ah.LastIndex()].Period.Start.Format(layout)},
+		{"Starting Equity", ah.Snapshots[0].Equity.FormattedString(2)},
+		{"Ending Equity", ah.Snapshots[ah.LastIndex()].Equity.FormattedString(2)},
+		{"Total Profit", ah.TotalProfit().FormattedString(2)},
+		{"Percent Gain", ah.PercentGain().FormattedString(2) + "%"},
+		{"Annualized Return", ah.AnnualizedReturn().FormattedString(2) + "%"},
+		{"Annualized Volatility", ah.AnnualizedVolatility().FormattedString(4)},
+		{"Sharpe Ratio", ah.SharpeRatio(riskFreeRate).FormattedString(2)},
+		{"Maximum Drawdown", ah.MaximumDrawdown().FormattedString(2) + "%"},
+		{"Longest Time Underwater", formatTearsheetDuration(ah.LongestTimeUnderwater())},
+	}
+
+	if ah.Benchmark != "" {
+		metrics = append(metrics,
+			tearsheetMetric{"Benchmark", ah.Benchmark},
+			tearsheetMetric{"Beta", ah.Beta().FormattedString(2)},
+			tearsheetMetric{"Alpha", ah.Alpha(riskFreeRate).FormattedString(2) + "%"},
+			tearsheetMetric{"Correlation", ah.BenchmarkCorrelation().FormattedString(2)},
+			tearsheetMetric{"Tracking Error", ah.TrackingError().FormattedString(2) + "%"},
+			tearsheetMetric{"Information Ratio", ah.InformationRatio().FormattedString(2)},
+			tearsheetMetric{"Up Capture", ah.UpCaptureRatio().FormattedString(2) + "%"},
+			tearsheetMetric{"Down Capture", ah.DownCaptureRatio().FormattedString(2) + "%"},
+		)
+	}
+
+	metrics = append(metrics,
+		tearsheetMetric{"Closed Trades", fmt.Sprint(analysis.TotalTrades)},
+		tearsheetMetric{"Win Rate", analysis.WinRate.FormattedString(2) + "%"},
+		tearsheetMetric{"Profit Factor", analysis.ProfitFactor.FormattedString(2)},
+		tearsheetMetric{"Expectancy", analysis.Expectancy.FormattedString(2)},
+		tearsheetMetric{"Largest Win", analysis.LargestWin.FormattedString(2)},
+		tearsheetMetric{"Largest Loss", analysis.LargestLoss.FormattedString(2)},
+	)
+
+	return metrics
+}
+
+func (ah *AccountHistory) equityChartSvg() string {
+	equity := make([]float64, len(ah.Snapshots))
+	for i, snapshot := range ah.Snapshots {
+		equity[i] = snapshot.Equity.Float()
+	}
+
+	series := []svgSeries{{Name: "Equity", Color: "#1f77b4", Values: equity}}
+
+	if ah.Benchmark != "" {
+		benchmark := make([]float64, len(ah.Snapshots))
+		for i := range benchmark {
+			benchmark[i] = math.NaN()
+		}
+
+		var scale float64
+		for i := range ah.Snapshots {
+			price, exists := ah.PriceAtIndex(ah.Benchmark, i)
+			if !exists || price.IsZero() {
+				continue
+			}
+
+			if scale == 0.0 {
+				scale = equity[i] / price.Float()
+			}
+			benchmark[i] = price.Float() * scale
+		}
+
+		series = append(series, svgSeries{Name: ah.Benchmark, Color: "#ff7f0e", Values: benchmark})
+	}
+
+	return ah.timeChartSvg(series, false)
+}
+
+func (ah *AccountHistory) underwaterChartSvg() string {
+	underwater := ah.Underwater()
+	values := make([]float64, len(underwater))
+	for i, u := range underwater {
+		values[i] = -u.Float()
+	}
+
+	return ah.timeChartSvg([]svgSeries{{Name: "Drawdown %", Color: "#d62728", Values: values}}, true)
+}
+
+func (ah *AccountHistory) exposureChartSvg() string {
+	exposure := ah.Exposure()
+	values := make([]float64, len(exposure))
+	for i, e := range exposure {
+		values[i] = e.Float()
+	}
+
+	return ah.timeChartSvg([]svgSeries{{Name: "Exposure %", Color: "#2ca02c", Values: values}}, true)
+}
+
+func (ah *AccountHistory) timeChartSvg(series []svgSeries, fill bool) string {
+	layout := SimpleDateFormatV2
+	start := ah.Snapshots[0].Period.Start.Format(layout)
+	end := ah.Snapshots[ah.LastIndex()].Period.Start.Format(layout)
+
+	return svgLineChart(series, start, end, fill)
+}
+
+func (ah *AccountHistory) tearsheetHeatmap(location *time.Location) []tearsheetHeatmapRow {
+	matrix := ah.MonthlyReturnMatrix(location)
+
+	maxAbs := 0.0
+	for _, row := range matrix {
+		for _, gain := range row.Months {
+			if gain != nil {
+				maxAbs = math.Max(maxAbs, math.Abs(gain.Float()))
+			}
+		}
+	}
+
+	rows := []tearsheetHeatmapRow{}
+	for _, row := range matrix {
+		heatmapRow := tearsheetHeatmapRow{Year: row.Year, Total: heatmapCell(&row.Total, maxAbs)}
+		for _, gain := range row.Months {
+			heatmapRow.Months = append(heatmapRow.Months, heatmapCell(gain, maxAbs))
+		}
+
+		rows = append(rows, heatmapRow)
+	}
+
+	return rows
+}
+
+func heatmapCell(gain *big.Decimal, maxAbs float64) tearsheetHeatmapCell {
+	if gain == nil {
+		return tearsheetHeatmapCell{Color: "background-color: transparent"}
+	}
+
+	value := gain.Float()
+	intensity := 0.0
+	if maxAbs > 0.0 {
+		intensity = math.Min(math.Abs(value)/maxAbs, 1.0)
+	}
+
+	color := fmt.Sprintf("rgba(44, 160, 44, %.2f)", 0.15+0.85*intensity)
+	if value < 0.0 {
+		color = fmt.Sprintf("rgba(214, 39, 40, %.2f)", 0.15+0.85*intensity)
+	}
+
+	return tearsheetHeatmapCell{Value: gain.FormattedString(2), Color: template.CSS("background-color: " + color)}
+}
+
+func tearsheetTrades(analysis *TradeAnalysis) []tearsheetTrade {
+	layout := SimpleDateFormatV2
+	trades := []tearsheetTrade{}
+
+	for _, trip := range analysis.Trades {
+		trade := tearsheetTrade{
+			Security:  trip.Security,
+			EntryTime: trip.EntryTime.Format(layout),
+			Quantity:  trip.Quantity.FormattedString(2),
+			Entry:     trip.AvgEntryPrice.FormattedString(2),
+			Profit:    trip.Profit.FormattedString(2),
+			Return:    trip.Return.FormattedString(2) + "%",
+		}
+
+		if trip.Closed {
+			trade.ExitTime = trip.ExitTime.Format(layout)
+			trade.Exit = trip.AvgExitPrice.FormattedString(2)
+			trade.Holding = formatTearsheetDuration(trip.HoldingPeriod)
+		} else {
+			trade.ExitTime = "open"
+		}
+
+		trades = append(trades, trade)
+	}
+
+	return trades
+}
+
+func formatTearsheetDuration(d time.Duration) string {
+	days := d.Hours() / 24.0
+	if days >= 1.0 {
+		return fmt.Sprintf("%.0f days", days)
+	}
+
+	return d.String()
+}
+
+// svgSeries is a single named line of an svg chart. NaN values are left as gaps in the line.
+type svgSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+const (
+	svgChartWidth  = 900.0
+	svgChartHeight = 260.0
+	svgChartMargin = 50.0
+)
+
+// svgLineChart renders one or more series as an inline svg line chart, indexed by position. If
+// fill is set, the area between each line and zero is shaded.
+func svgLineChart(series []svgSeries, startLabel, endLabel string, fill bool) string {
+	minValue := math.Inf(1)
+	maxValue := math.Inf(-1)
+	length := 0
+
+	for _, s := range series {
+		length = Max(length, len(s.Values))
+		for _, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+
+			minValue = math.Min(minValue, v)
+			maxValue = math.Max(maxValue, v)
+		}
+	}
+
+	if fill {
+		minValue = math.Min(minValue, 0.0)
+		maxValue = math.Max(maxValue, 0.0)
+	}
+
+	if math.IsInf(minValue, 0) {
+		minValue, maxValue = 0.0, 1.0
+	} else if minValue == maxValue {
+		minValue, maxValue = minValue-1.0, maxValue+1.0
+	}
+
+	plotWidth := svgChartWidth - 2*svgChartMargin
+	plotHeight := svgChartHeight - 2*svgChartMargin
+
+	x := func(i int) float64 {
+		if length < 2 {
+			return svgChartMargin
+		}
+		return svgChartMargin + float64(i)/float64(length-1)*plotWidth
+	}
+	y := func(v float64) float64 {
+		return svgChartMargin + (maxValue-v)/(maxValue-minValue)*plotHeight
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %.0f %.0f" width="100%%" role="img">`, svgChartWidth, svgChartHeight)
+	fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="none" stroke="#ccc"/>`,
+		svgChartMargin, svgChartMargin, plotWidth, plotHeight)
+
+	if minValue < 0.0 && maxValue > 0.0 {
+		fmt.Fprintf(&b, `<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="#999" stroke-dasharray="4"/>`,
+			svgChartMargin, y(0.0), svgChartMargin+plotWidth, y(0.0))
+	}
+
+	for _, s := range series {
+		segments := [][]string{}
+		current := []string{}
+
+		for i, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				if len(current) > 0 {
+					segments = append(segments, current)
+					current = []string{}
+				}
+				continue
+			}
+
+			current = append(current, fmt.Sprintf("%.2f,%.2f", x(i), y(v)))
+		}
+		if len(current) > 0 {
+			segments = append(segments, current)
+		}
+
+		for _, segment := range segments {
+			points := strings.Join(segment, " ")
+
+			if fill {
+				first := strings.Split(segment[0], ",")[0]
+				last := strings.Split(segment[len(segment)-1], ",")[0]
+				fmt.Fprintf(&b, `<polygon points="%s,%.2f %s %s,%.2f" fill="%s" fill-opacity="0.3" stroke="none"/>`,
+					first, y(0.0), points, last, y(0.0), s.Color)
+			}
+
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, points, s.Color)
+		}
+	}
+
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.2f" font-size="11" text-anchor="end">%s</text>`,
+		svgChartMargin-4, y(maxValue)+4, svgFormatValue(maxValue))
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.2f" font-size="11" text-anchor="end">%s</text>`,
+		svgChartMargin-4, y(minValue)+4, svgFormatValue(minValue))
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" font-size="11">%s</text>`,
+		svgChartMargin, svgChartHeight-svgChartMargin+16, template.HTMLEscapeString(startLabel))
+	fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" font-size="11" text-anchor="end">%s</text>`,
+		svgChartMargin+plotWidth, svgChartHeight-svgChartMargin+16, template.HTMLEscapeString(endLabel))
+
+	for i, s := range series {
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="10" height="10" fill="%s"/>`,
+			svgChartMargin+float64(i)*150, svgChartMargin-22, s.Color)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" font-size="12">%s</text>`,
+			svgChartMargin+float64(i)*150+14, svgChartMargin-13, template.HTMLEscapeString(s.Name))
+	}
+
+	b.WriteString(`</svg>`)
+
+	return b.String()
+}
+
+func svgFormatValue(v float64) string {
+	if math.Abs(v) >= 1000.0 {
+		return fmt.Sprintf("%.0f", v)
+	}
+
+	return fmt.Sprintf("%.2f", v)
+}
+
+var tearsheetTemplate = template.Must(template.New("tearsheet").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; font-size: 13px; }
+th, td { padding: 4px 10px; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+.generated { color: #777; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">Generated {{.Generated}}</p>
+
+<h2>Key Metrics</h2>
+<table>
+{{range .Metrics}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Equity</h2>
+{{.Equity}}
+
+<h2>Underwater</h2>
+{{.Underwater}}
+
+<h2>Monthly Returns (%)</h2>
+<table>
+<tr><th>Year</th><th>Jan</th><th>Feb</th><th>Mar</th><th>Apr</th><th>May</th><th>Jun</th><th>Jul</th><th>Aug</th><th>Sep</th><th>Oct</th><th>Nov</th><th>Dec</th><th>Year</th></tr>
+{{range .Heatmap}}<tr><td>{{.Year}}</td>{{range .Months}}<td style="{{.Color}}">{{.Value}}</td>{{end}}<td style="{{.Total.Color}}">{{.Total.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Exposure</h2>
+{{.Exposure}}
+
+<h2>Trades</h2>
+<table>
+<tr><th>Security</th><th>Entry</th><th>Exit</th><th>Quantity</th><th>Entry Price</th><th>Exit Price</th><th>Profit</th><th>Return</th><th>Held</th></tr>
+{{range .Trades}}<tr><td>{{.Security}}</td><td>{{.EntryTime}}</td><td>{{.ExitTime}}</td><td>{{.Quantity}}</td><td>{{.Entry}}</td><td>{{.Exit}}</td><td>{{.Profit}}</td><td>{{.Return}}</td><td>{{.Holding}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,101 @@
+package techan
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPositionHistory() *AccountHistory {
+	ah := NewAccountHistory()
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	amounts := []float64{0, 10, 10, 5, 0}
+	prices := []float64{10.0, 10.0, 12.0, 13.0, 11.0}
+	cash := []float64{100.0, 0.0, 0.0, 65.0, 120.0}
+
+	for i := range amounts {
+		period := NewTimePeriod(start.AddDate(0, 0, i), time.Hour*24)
+		price := big.NewDecimal(prices[i])
+		positions := []*PositionSnapshot{}
+
+		if amounts[i] > 0 {
+			positions = append(positions, &PositionSnapshot{
+				Security: MOCK_SECURITY,
+				Side:     BUY,
+				Amount:   big.NewDecimal(amounts[i]),
+				Price:    price,
+			})
+		}
+
+		equity := big.NewDecimal(cash[i]).Add(big.NewDecimal(amounts[i]).Mul(price))
+		snap := AccountSnapshot{Period: period, Equity: equity, Cash: big.NewDecimal(cash[i]), Positions: positions}
+		pricing := PricingSnapshot{Period: period, Prices: Pricing{MOCK_SECURITY: price, mockBenchmark: price}}
+
+		ah.ApplySnapshot(&snap, &pricing)
+	}
+
+	return ah
+}
+
+func TestAccountHistory_ImpliedOrders(t *testing.T) {
+	ah := mockPositionHistory()
+	orders := ah.ImpliedOrders()
+
+	assert.Equal(t, 3, len(orders))
+	assert.Equal(t, BUY, orders[0].Side)
+	decimalEquals(t, 10.0, orders[0].Amount)
+	decimalEquals(t, 10.0, orders[0].Price)
+	assert.Equal(t, SELL, orders[1].Side)
+	decimalEquals(t, 5.0, orders[1].Amount)
+	decimalEquals(t, 13.0, orders[1].Price)
+	assert.Equal(t, SELL, orders[2].Side)
+	decimalEquals(t, 11.0, orders[2].Price)
+	assert.Equal(t, ah.Snapshots[4].Period.Start, orders[2].ExecutionTime)
+}
+
+func TestAccountHistory_Exposure(t *testing.T) {
+	ah := mockPositionHistory()
+	exposure := ah.Exposure()
+
+	decimalEquals(t, 0.0, exposure[0])
+	decimalEquals(t, 100.0, exposure[1])
+	decimalEquals(t, 50.0, exposure[3])
+}
+
+func TestAccountHistory_WriteTearsheetHtml(t *testing.T) {
+	ah := mockPositionHistory()
+	ah.SetBenchmark(mockBenchmark)
+
+	var buf bytes.Buffer
+	err := ah.WriteTearsheetHtml(&buf, TearsheetOptions{Title: "Test <Strategy>"})
+	assert.Nil(t, err)
+
+	html := buf.String()
+	assert.Contains(t, html, "Test &lt;Strategy&gt;")
+	assert.Equal(t, 3, strings.Count(html, "<svg"))
+	assert.Contains(t, html, "Maximum Drawdown")
+	assert.Contains(t, html, "Tracking Error")
+	assert.Contains(t, html, "<td>2020</td>")
+	assert.Contains(t, html, "<td>"+MOCK_SECURITY+"</td>")
+	assert.NotContains(t, html, "http")
+	assert.NotContains(t, html, "<script")
+
+	err = NewAccountHistory().WriteTearsheetHtml(&buf, TearsheetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestAccountHistory_ExportTearsheetHtml(t *testing.T) {
+	ah := mockPositionHistory()
+
+	filepath := "tearsheet.html"
+	err := ah.ExportTearsheetHtml(filepath, TearsheetOptions{})
+	assert.Nil(t, err)
+
+	os.Remove(filepath)
+}
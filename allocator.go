@@ -46,13 +46,13 @@ func NewNaiveAllocator(maxSinglePositionFraction big.Decimal, maxTotalPositionFr
 }
 
 // Perform a naive allocation which simply gives an equal portion of allocation to all strategies whose
-// rules are satisfied.
+// rules are satisfied. Strategies whose indicators are still warming up are not allocated to.
 func (na *NaiveAllocator) Allocate(index int, strategies []Strategy) Allocations {
 	triggers := make([]string, 0)
 	allocations := make(map[string]big.Decimal, 0)
 
 	for _, s := range strategies {
-		if s.Rule.IsSatisfied(index) {
+		if s.IsValid(index) && s.Rule.IsSatisfied(index) {
 			triggers = append(triggers, s.Security)
 		}
 	}
@@ -87,7 +87,7 @@ func (na *NaiveAllocator) AllocateWithAccount(index int, strategies []Strategy,
 	allocations := make(map[string]big.Decimal, 0)
 
 	for _, s := range strategies {
-		if s.Rule.IsSatisfied(index) {
+		if s.IsValid(index) && s.Rule.IsSatisfied(index) {
 			triggers = append(triggers, s.Security)
 
 			if s.Rule.IsSatisfied(index - 1) {
@@ -56,3 +56,27 @@ func TestAllocator_NaiveAllocatorAllocate(t *testing.T) {
 	alc3 := alc.Allocate(3, strats)
 	assert.Equal(t, 0, len(alc3))
 }
+
+func TestAllocator_NaiveAllocatorAllocate_WarmUp(t *testing.T) {
+	alc := NewNaiveAllocator(big.NewDecimal(0.5), big.NewDecimal(1.0))
+	series := mockTimeSeriesFl(1, 2, 3, 4)
+
+	strats := []Strategy{
+		{Security: "ONE", Timeseries: *series, Rule: truthRule{}},
+		{
+			Security:   "TWO",
+			Timeseries: *series,
+			Indicators: map[string]Indicator{"sma": NewSimpleMovingAverage(NewClosePriceIndicator(series), 3)},
+			Rule:       truthRule{},
+		},
+	}
+
+	// the moving average is still warming up on the first two candles
+	alc1 := alc.Allocate(1, strats)
+	assert.Equal(t, 1, len(alc1))
+	decimalAlmostEquals(t, big.NewDecimal(0.5), alc1["ONE"], 0.01)
+
+	alc2 := alc.Allocate(2, strats)
+	assert.Equal(t, 2, len(alc2))
+	decimalAlmostEquals(t, big.NewDecimal(0.5), alc2["TWO"], 0.01)
+}
@@ -1,29 +1,72 @@
 package techan
 
+import "github.com/schmidthole/big"
+
 // The Backtest is a holder struct to run a simulated trading strategy against an account.
 type Backtest struct {
 	tick       int
 	strategies []Strategy
+	aligned    []Strategy
+	clock      *Clock
 	allocator  Allocator
 	account    *Account
 	history    *AccountHistory
 }
 
-// Create a new backtest with the provided strategies, allocator, and starting account.
-// All strategies are assumed to be normalized and have the same length, indexes, and periods.
+// Create a new backtest with the provided strategies, allocator, and starting account. The
+// strategies do not need to share periods: the backtest runs on the master clock of their series,
+// and bars a security is missing while listed are not traded. See NewBacktestWithPolicy.
 func NewBacktest(strategies []Strategy, allocator Allocator, account *Account) *Backtest {
+	return NewBacktestWithPolicy(strategies, allocator, account, NON_TRADEABLE)
+}
+
+// Create a new backtest which runs on the master clock of the strategies' series, applying the
+// given policy to missing bars. The allocator is passed strategies whose series, indicators and
+// rules are indexed by clock tick, with rules only satisfied while the security is tradeable. A
+// security is sold at the close of its last bar if it delists before the end of the backtest, and
+// positions in a security are held while it is not tradeable.
+func NewBacktestWithPolicy(strategies []Strategy, allocator Allocator, account *Account, policy MissingBarPolicy) *Backtest {
+	series := make([]*TimeSeries, len(strategies))
+	for i := range strategies {
+		series[i] = &strategies[i].Timeseries
+	}
+
+	clock := NewClock(policy, series...)
+
 	backtest := Backtest{
 		tick:       0,
 		strategies: strategies,
+		aligned:    make([]Strategy, len(strategies)),
+		clock:      clock,
 		allocator:  allocator,
 		account:    account,
 		history:    NewAccountHistory(),
 	}
 
+	for i, strat := range strategies {
+		indicators := make(map[string]Indicator, len(strat.Indicators))
+		for name, indicator := range strat.Indicators {
+			indicators[name] = clockIndicator{indicator: indicator, clock: clock, series: i}
+		}
+
+		backtest.aligned[i] = Strategy{
+			Security:   strat.Security,
+			Timeseries: *clock.Align(i),
+			Indicators: indicators,
+			Rule: clockRule{
+				rule:       strat.Rule,
+				indicators: strat.Indicators,
+				clock:      clock,
+				series:     i,
+				lastIndex:  strat.Timeseries.LastIndex(),
+			},
+		}
+	}
+
 	// setup the initial account state to be one period before all data.
 	// this will serve as a starting point before orders are executed.
-	if (len(strategies) > 0) && (len(strategies[0].Timeseries.Candles) > 0) {
-		initialPeriod := strategies[0].Timeseries.Candles[0].Period.Advance(-1)
+	if clock.Len() > 0 {
+		initialPeriod := clock.Periods[0].Advance(-1)
 		backtest.history.ApplySnapshot(
 			backtest.account.ExportSnapshot(initialPeriod),
 			&PricingSnapshot{Period: initialPeriod, Prices: Pricing{}},
@@ -33,8 +76,25 @@ func NewBacktest(strategies []Strategy, allocator Allocator, account *Account) *
 	return &backtest
 }
 
+// UseCalendar sets the trading calendar of the backtest. The initial account state is then placed
+// in the trading period before the first bar, rather than one bar length before it, and the
+// resulting history annualizes returns by the calendar's trading days. Call it before Run.
+func (b *Backtest) UseCalendar(calendar *Calendar) {
+	b.history.Calendar = calendar
+
+	if b.clock.Len() > 0 && len(b.history.Snapshots) > 0 {
+		initialPeriod := calendar.PreviousPeriod(b.clock.Periods[0])
+		b.history.Snapshots[0].Period = initialPeriod
+		b.history.Prices[0].Period = initialPeriod
+	}
+}
+
 // Run the backtest from start to finish.
 func (b *Backtest) Run() (*AccountHistory, error) {
+	if b.clock.Len() == 0 {
+		return b.history, nil
+	}
+
 	for {
 		err := b.executeTick()
 		if err != nil {
@@ -53,23 +113,42 @@ func (b *Backtest) Run() (*AccountHistory, error) {
 
 func (b *Backtest) executeTick() error {
 	prices := Pricing{}
-	for _, strat := range b.strategies {
-		prices[strat.Security] = strat.Timeseries.Candles[b.tick].ClosePrice
+	for i, strat := range b.strategies {
+		if index := b.clock.Index(i, b.tick); index >= 0 {
+			prices[strat.Security] = strat.Timeseries.Candle(index).ClosePrice
+		}
 	}
 
 	b.account.UpdatePrices(prices)
-	allocations := b.allocator.Allocate(b.tick, b.strategies)
+	allocations := b.allocator.Allocate(b.tick, b.aligned)
+
+	// a security can only be allocated to while it is listed and priced
+	for security := range allocations {
+		if _, exists := prices[security]; !exists {
+			delete(allocations, security)
+		}
+	}
 
 	tradePlan, err := CreateTradePlan(allocations, prices, b.account)
 	if err != nil {
 		return err
 	}
 
-	for _, order := range *tradePlan {
+	period := b.clock.Periods[b.tick]
+
+	// each order is copied so the trade record does not share a single loop variable, and is
+	// stamped with the period it was executed in for later trade analysis. orders for securities
+	// which cannot be traded on this tick are dropped, holding any open position.
+	for i := range *tradePlan {
+		order := (*tradePlan)[i]
+		if !b.tradeable(order.Security) {
+			continue
+		}
+
+		order.ExecutionTime = period.Start
 		b.account.ExecuteOrder(&order)
 	}
 
-	period := b.strategies[0].Timeseries.Candles[b.tick].Period
 	b.history.ApplySnapshot(
 		b.account.ExportSnapshot(period),
 		&PricingSnapshot{Period: period, Prices: prices},
@@ -78,6 +157,16 @@ func (b *Backtest) executeTick() error {
 	return nil
 }
 
+func (b *Backtest) tradeable(security string) bool {
+	for i, strat := range b.strategies {
+		if strat.Security == security {
+			return b.clock.Tradeable(i, b.tick)
+		}
+	}
+
+	return false
+}
+
 func (b *Backtest) advanceTick() {
 	if b.tick >= b.lastTick() {
 		return
@@ -87,5 +176,60 @@ func (b *Backtest) advanceTick() {
 }
 
 func (b *Backtest) lastTick() int {
-	return b.strategies[0].Timeseries.LastIndex()
+	return b.clock.Len() - 1
+}
+
+// clockRule evaluates a strategy's rule at the index of its own series for a clock tick. It is not
+// satisfied while the security is not tradeable, while the strategy's indicators are warming up,
+// nor on the last bar of a security which delists before the clock ends, so that the position is
+// closed while it can still be priced.
+type clockRule struct {
+	rule       Rule
+	indicators map[string]Indicator
+	clock      *Clock
+	series     int
+	lastIndex  int
+}
+
+func (cr clockRule) IsSatisfied(tick int) bool {
+	if tick < 0 || tick >= cr.clock.Len() || !cr.clock.Tradeable(cr.series, tick) {
+		return false
+	}
+
+	index := cr.clock.Index(cr.series, tick)
+	if index == cr.lastIndex && tick < cr.clock.Len()-1 {
+		return false
+	}
+
+	for _, indicator := range cr.indicators {
+		if !IsValid(indicator, index) {
+			return false
+		}
+	}
+
+	return cr.rule.IsSatisfied(index)
+}
+
+// clockIndicator calculates a strategy's indicator at the index of its own series for a clock
+// tick, returning zero while the security is not listed.
+type clockIndicator struct {
+	indicator Indicator
+	clock     *Clock
+	series    int
+}
+
+func (ci clockIndicator) Calculate(tick int) big.Decimal {
+	if tick < 0 || tick >= ci.clock.Len() || ci.clock.Index(ci.series, tick) < 0 {
+		return big.ZERO
+	}
+
+	return ci.indicator.Calculate(ci.clock.Index(ci.series, tick))
+}
+
+func (ci clockIndicator) IsValid(tick int) bool {
+	if tick < 0 || tick >= ci.clock.Len() || ci.clock.Index(ci.series, tick) < 0 {
+		return false
+	}
+
+	return IsValid(ci.indicator, ci.clock.Index(ci.series, tick))
 }
@@ -2,6 +2,7 @@ package techan
 
 import (
 	"testing"
+	"time"
 
 	"github.com/schmidthole/big"
 	"github.com/stretchr/testify/assert"
@@ -44,3 +45,91 @@ func Test_BacktestRun(t *testing.T) {
 	assert.Equal(t, 4, bt.tick)
 	assert.Equal(t, 6, len(hist.Snapshots))
 }
+
+func Test_BacktestRun_Unaligned(t *testing.T) {
+	// the second security lists on the second tick, misses the fourth and delists after the fifth
+	listed := mockGappedSeries([]int{0, 1, 2, 3, 4, 5}, 1.0, 2.0, 3.0, 4.0, 5.0, 6.0)
+	late := mockGappedSeries([]int{1, 2, 4}, 10.0, 10.0, 20.0)
+
+	strategies := []Strategy{
+		{Security: "ONE", Timeseries: *listed, Rule: truthRule{}},
+		{Security: "TWO", Timeseries: *late, Rule: truthRule{}},
+	}
+	alloc := NewNaiveAllocator(big.NewDecimal(0.5), big.NewDecimal(1.0))
+	acct := NewAccount()
+	acct.Deposit(big.NewDecimal(1000.0))
+
+	bt := NewBacktest(strategies, alloc, acct)
+	hist, err := bt.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(hist.Snapshots))
+
+	// the late security is bought once it lists
+	assert.Equal(t, "", snapshotAmount(hist.Snapshots[1], "TWO"))
+	assert.Equal(t, "75", snapshotAmount(hist.Snapshots[2], "TWO"))
+
+	// the position is held through the missing bar, priced at the previous close
+	assert.Equal(t, "93", snapshotAmount(hist.Snapshots[3], "TWO"))
+	assert.Equal(t, "93", snapshotAmount(hist.Snapshots[4], "TWO"))
+	assert.Equal(t, "10", hist.Prices[4].Prices["TWO"].String())
+
+	// and sold on its last bar, since it delists before the end of the backtest
+	assert.Equal(t, "", snapshotAmount(hist.Snapshots[5], "TWO"))
+	_, exists := hist.PriceAtIndex("TWO", 6)
+	assert.False(t, exists)
+}
+
+func Test_BacktestRun_WarmUp(t *testing.T) {
+	ts := mockTimeSeriesFl(1.0, 2.0, 3.0, 4.0, 5.0)
+	strat := Strategy{
+		Security:   "ONE",
+		Timeseries: *ts,
+		Rule:       truthRule{},
+		Indicators: map[string]Indicator{"sma": NewSimpleMovingAverage(NewClosePriceIndicator(ts), 3)},
+	}
+	alloc := NewNaiveAllocator(big.NewDecimal(1.0), big.NewDecimal(1.0))
+	acct := NewAccount()
+	acct.Deposit(big.NewDecimal(1000.0))
+
+	bt := NewBacktest([]Strategy{strat}, alloc, acct)
+	hist, err := bt.Run()
+	assert.Nil(t, err)
+
+	// nothing is bought until the moving average has three candles
+	assert.Equal(t, "", snapshotAmount(hist.Snapshots[1], "ONE"))
+	assert.Equal(t, "", snapshotAmount(hist.Snapshots[2], "ONE"))
+	assert.NotEqual(t, "", snapshotAmount(hist.Snapshots[3], "ONE"))
+}
+
+func snapshotAmount(snapshot *AccountSnapshot, security string) string {
+	for _, position := range snapshot.Positions {
+		if position.Security == security {
+			return position.Amount.String()
+		}
+	}
+
+	return ""
+}
+
+func Test_BacktestUseCalendar(t *testing.T) {
+	nyse := NewNYSECalendar()
+	ts := NewTimeSeries()
+	for _, day := range []int{1, 2} {
+		candle := NewCandle(NewTimePeriod(time.Date(2024, time.April, day, 0, 0, 0, 0, nyse.Location), time.Hour*24))
+		candle.ClosePrice = big.NewDecimal(1.0)
+		ts.AddCandle(candle)
+	}
+
+	strat := Strategy{Security: "ONE", Timeseries: *ts, Rule: truthRule{}}
+	bt := NewBacktest([]Strategy{strat}, NewNaiveAllocator(big.ONE, big.ONE), NewAccount())
+	assert.Equal(t, time.Date(2024, time.March, 31, 0, 0, 0, 0, nyse.Location), bt.history.Snapshots[0].Period.Start)
+
+	// the initial state moves back over the good friday weekend
+	bt.UseCalendar(nyse)
+	assert.Equal(t, time.Date(2024, time.March, 28, 0, 0, 0, 0, nyse.Location), bt.history.Snapshots[0].Period.Start)
+	assert.Equal(t, bt.history.Snapshots[0].Period, bt.history.Prices[0].Period)
+
+	hist, err := bt.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, nyse, hist.Calendar)
+}
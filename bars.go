@@ -0,0 +1,359 @@
+package techan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// A BarBuilder aggregates trades, or candles of a finer series, into bars. Completed bars are added
+// to the builder's Series, while the bar which is still forming is available as the partial bar.
+// Resampler builds time bars; the builders in this file build bars driven by activity or price.
+type BarBuilder interface {
+	AddTrade(timestamp time.Time, amount, price big.Decimal) error
+	AddCandle(candle *Candle) error
+	Partial() *Candle
+	Flush()
+}
+
+// BuildBars feeds every candle of a series to a builder. The partial bar left at the end is only
+// completed if includePartial is set.
+func BuildBars(builder BarBuilder, series *TimeSeries, includePartial bool) error {
+	for _, candle := range series.Candles {
+		if err := builder.AddCandle(candle); err != nil {
+			return err
+		}
+	}
+
+	if includePartial {
+		builder.Flush()
+	}
+
+	return nil
+}
+
+// BuildBarsFromTrades feeds trades in chronological order to a builder. The partial bar left at
+// the end is only completed if includePartial is set.
+func BuildBarsFromTrades(builder BarBuilder, trades []Trade, includePartial bool) error {
+	for _, trade := range trades {
+		if err := builder.AddTrade(trade.Time, trade.Amount, trade.Price); err != nil {
+			return err
+		}
+	}
+
+	if includePartial {
+		builder.Flush()
+	}
+
+	return nil
+}
+
+// barSeries holds the output and the forming bar shared by the activity and price driven builders.
+// Their bars do not have a fixed length: a bar's period runs from its first input to the end of the
+// input that completed it.
+type barSeries struct {
+	Series  *TimeSeries
+	current *Candle
+	last    time.Time
+}
+
+func newBarSeries() barSeries {
+	return barSeries{Series: NewTimeSeries()}
+}
+
+// Partial returns the bar which is still forming, or nil if there is none.
+func (b *barSeries) Partial() *Candle {
+	return b.current
+}
+
+// Completes the partial bar regardless of its size, e.g. at the end of a data set.
+func (b *barSeries) Flush() {
+	if b.current != nil {
+		b.complete()
+	}
+}
+
+func (b *barSeries) checkOrder(period TimePeriod) error {
+	if period.Start.Before(b.last) {
+		return fmt.Errorf("%v is before the previous input ending %v", period.Start, b.last)
+	}
+
+	b.last = period.End
+
+	return nil
+}
+
+// merge adds an input to the forming bar, starting a new one if there is none
+func (b *barSeries) merge(input *Candle) {
+	if b.current == nil {
+		b.current = &Candle{
+			Period:     input.Period,
+			OpenPrice:  input.OpenPrice,
+			MaxPrice:   input.MaxPrice,
+			MinPrice:   input.MinPrice,
+			ClosePrice: input.ClosePrice,
+			Volume:     input.Volume,
+			TradeCount: input.TradeCount,
+		}
+
+		return
+	}
+
+	b.current.Period.End = input.Period.End
+	b.current.MaxPrice = big.MaxSlice(b.current.MaxPrice, input.MaxPrice)
+	b.current.MinPrice = big.MinSlice(b.current.MinPrice, input.MinPrice)
+	b.current.ClosePrice = input.ClosePrice
+	b.current.Volume = b.current.Volume.Add(input.Volume)
+	b.current.TradeCount += input.TradeCount
+}
+
+func (b *barSeries) complete() {
+	b.Series.AddCandle(b.current)
+	b.current = nil
+}
+
+// tradeCandle returns a single trade as a candle with an instantaneous period
+func tradeCandle(timestamp time.Time, amount, price big.Decimal) *Candle {
+	return &Candle{
+		Period:     TimePeriod{Start: timestamp, End: timestamp},
+		OpenPrice:  price,
+		MaxPrice:   price,
+		MinPrice:   price,
+		ClosePrice: price,
+		Volume:     amount,
+		TradeCount: 1,
+	}
+}
+
+// A ThresholdBarBuilder completes a bar once a measure of the activity in it, such as volume,
+// reaches a threshold. Inputs are not split, so a bar ends with the input that crosses the
+// threshold and may overshoot it.
+type ThresholdBarBuilder struct {
+	barSeries
+	threshold big.Decimal
+	measure   func(input *Candle) big.Decimal
+	total     big.Decimal
+}
+
+// NewVolumeBarBuilder returns a builder which completes a bar every time the given volume has traded.
+func NewVolumeBarBuilder(volume big.Decimal) *ThresholdBarBuilder {
+	return newThresholdBarBuilder(volume, func(input *Candle) big.Decimal {
+		return input.Volume
+	})
+}
+
+// NewTickBarBuilder returns a builder which completes a bar every given number of trades. Candle
+// inputs count their TradeCount, so candles without trade counts never complete a bar.
+func NewTickBarBuilder(trades uint) *ThresholdBarBuilder {
+	return newThresholdBarBuilder(big.NewFromInt(int(trades)), func(input *Candle) big.Decimal {
+		return big.NewFromInt(int(input.TradeCount))
+	})
+}
+
+// NewDollarBarBuilder returns a builder which completes a bar every time the given value has
+// traded. The value of a trade is its price times its amount, while the value of a candle input is
+// approximated by its close price times its volume.
+func NewDollarBarBuilder(value big.Decimal) *ThresholdBarBuilder {
+	return newThresholdBarBuilder(value, func(input *Candle) big.Decimal {
+		return input.ClosePrice.Mul(input.Volume)
+	})
+}
+
+func newThresholdBarBuilder(threshold big.Decimal, measure func(input *Candle) big.Decimal) *ThresholdBarBuilder {
+	if !threshold.GT(big.ZERO) {
+		panic(fmt.Errorf("error creating bar builder: threshold must be positive"))
+	}
+
+	return &ThresholdBarBuilder{
+		barSeries: newBarSeries(),
+		threshold: threshold,
+		measure:   measure,
+		total:     big.ZERO,
+	}
+}
+
+// Adds a trade to the forming bar. Trades must be added in chronological order.
+func (b *ThresholdBarBuilder) AddTrade(timestamp time.Time, amount, price big.Decimal) error {
+	return b.AddCandle(tradeCandle(timestamp, amount, price))
+}
+
+// Adds a candle of a finer series to the forming bar. Candles must be added in chronological order.
+func (b *ThresholdBarBuilder) AddCandle(candle *Candle) error {
+	if err := b.checkOrder(candle.Period); err != nil {
+		return err
+	}
+
+	b.merge(candle)
+	b.total = b.total.Add(b.measure(candle))
+
+	if b.total.GTE(b.threshold) {
+		b.complete()
+		b.total = big.ZERO
+	}
+
+	return nil
+}
+
+// A RangeBarBuilder completes a bar once the difference between its high and low reaches a fixed
+// size. Candle inputs are taken whole, since the order of their high and low is unknown.
+type RangeBarBuilder struct {
+	barSeries
+	size big.Decimal
+}
+
+// NewRangeBarBuilder returns a builder which completes a bar once its range reaches the given size.
+func NewRangeBarBuilder(size big.Decimal) *RangeBarBuilder {
+	if !size.GT(big.ZERO) {
+		panic(fmt.Errorf("error creating bar builder: range size must be positive"))
+	}
+
+	return &RangeBarBuilder{
+		barSeries: newBarSeries(),
+		size:      size,
+	}
+}
+
+// Adds a trade to the forming bar. Trades must be added in chronological order.
+func (b *RangeBarBuilder) AddTrade(timestamp time.Time, amount, price big.Decimal) error {
+	return b.AddCandle(tradeCandle(timestamp, amount, price))
+}
+
+// Adds a candle of a finer series to the forming bar. Candles must be added in chronological order.
+func (b *RangeBarBuilder) AddCandle(candle *Candle) error {
+	if err := b.checkOrder(candle.Period); err != nil {
+		return err
+	}
+
+	b.merge(candle)
+
+	if b.current.MaxPrice.Sub(b.current.MinPrice).GTE(b.size) {
+		b.complete()
+	}
+
+	return nil
+}
+
+// A RenkoBarBuilder builds Renko bricks from close prices. A brick of the brick size is added each
+// time the price moves a brick size beyond the top or bottom of the last brick, so reversals need
+// a move of two brick sizes from the close of the last brick. The open and close of a brick are its
+// bounds, and the volume and trades since the previous brick are assigned to the first brick that
+// an input completes.
+type RenkoBarBuilder struct {
+	barSeries
+	size      big.Decimal
+	top       big.Decimal
+	bottom    big.Decimal
+	atrWindow int
+	inputs    *TimeSeries
+}
+
+// NewRenkoBarBuilder returns a builder of Renko bricks of a fixed size.
+func NewRenkoBarBuilder(size big.Decimal) *RenkoBarBuilder {
+	if !size.GT(big.ZERO) {
+		panic(fmt.Errorf("error creating bar builder: brick size must be positive"))
+	}
+
+	return &RenkoBarBuilder{
+		barSeries: newBarSeries(),
+		size:      size,
+	}
+}
+
+// NewAtrRenkoBarBuilder returns a builder of Renko bricks sized by the average true range of the
+// input candles over the given window. The size is set once window candles have been added and is
+// recalculated each time a brick completes, so that it never depends on later inputs. Since trades
+// have no true range, this builder only accepts candles.
+func NewAtrRenkoBarBuilder(window int) *RenkoBarBuilder {
+	if window <= 0 {
+		panic(fmt.Errorf("error creating bar builder: atr window must be positive"))
+	}
+
+	return &RenkoBarBuilder{
+		barSeries: newBarSeries(),
+		size:      big.ZERO,
+		atrWindow: window,
+		inputs:    NewBoundedTimeSeries(window + 1),
+	}
+}
+
+// Size returns the current brick size, which is zero until an ATR based builder has warmed up.
+func (b *RenkoBarBuilder) Size() big.Decimal {
+	return b.size
+}
+
+// Adds a trade at the given price. Trades must be added in chronological order.
+func (b *RenkoBarBuilder) AddTrade(timestamp time.Time, amount, price big.Decimal) error {
+	if b.atrWindow > 0 {
+		return fmt.Errorf("atr sized renko bricks can only be built from candles")
+	}
+
+	return b.AddCandle(tradeCandle(timestamp, amount, price))
+}
+
+// Adds a candle of a finer series at its close price. Candles must be added in chronological order.
+func (b *RenkoBarBuilder) AddCandle(candle *Candle) error {
+	if err := b.checkOrder(candle.Period); err != nil {
+		return err
+	}
+
+	if b.atrWindow > 0 {
+		b.inputs.AddCandle(candle)
+
+		if b.size.IsZero() && b.inputs.LastIndex() >= b.atrWindow {
+			b.updateSize()
+		}
+	}
+
+	price := candle.ClosePrice
+
+	if b.top.NaN() {
+		b.top = price
+		b.bottom = price
+	}
+
+	// the partial brick tracks the activity since the last brick, its open and close being the
+	// bounds of the last brick until the price moves far enough
+	b.merge(candle)
+	b.current.OpenPrice = b.top
+	b.current.ClosePrice = price
+
+	first := true
+	for !b.size.IsZero() {
+		var open, close big.Decimal
+
+		if price.GTE(b.top.Add(b.size)) {
+			open, close = b.top, b.top.Add(b.size)
+		} else if price.LTE(b.bottom.Sub(b.size)) {
+			open, close = b.bottom, b.bottom.Sub(b.size)
+		} else {
+			break
+		}
+
+		brick := b.current
+		if !first {
+			brick = NewCandle(TimePeriod{Start: candle.Period.End, End: candle.Period.End})
+		}
+
+		brick.OpenPrice = open
+		brick.ClosePrice = close
+		brick.MaxPrice = big.MaxSlice(open, close)
+		brick.MinPrice = big.MinSlice(open, close)
+
+		b.Series.AddCandle(brick)
+		b.current = nil
+		b.top = brick.MaxPrice
+		b.bottom = brick.MinPrice
+		first = false
+
+		if b.atrWindow > 0 {
+			b.updateSize()
+		}
+	}
+
+	return nil
+}
+
+func (b *RenkoBarBuilder) updateSize() {
+	b.size = NewAverageTrueRangeIndicator(b.inputs, b.atrWindow).Calculate(b.inputs.LastIndex())
+}
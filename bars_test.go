@@ -0,0 +1,135 @@
+package techan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockTrades(start time.Time, prices ...float64) []Trade {
+	trades := make([]Trade, len(prices))
+	for i, price := range prices {
+		trades[i] = Trade{start.Add(time.Second * time.Duration(i)), big.NewDecimal(float64(i + 1)), big.NewDecimal(price)}
+	}
+
+	return trades
+}
+
+func TestThresholdBarBuilder(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	trades := mockTrades(start, 10, 11, 9, 12, 13)
+
+	t.Run("volume", func(t *testing.T) {
+		builder := NewVolumeBarBuilder(big.NewDecimal(3))
+		assert.Nil(t, BuildBarsFromTrades(builder, trades, false))
+		assert.Equal(t, 4, len(builder.Series.Candles))
+
+		candle := builder.Series.Candles[0]
+		assert.Equal(t, TimePeriod{Start: start, End: start.Add(time.Second)}, candle.Period)
+		decimalEquals(t, 10, candle.OpenPrice)
+		decimalEquals(t, 11, candle.ClosePrice)
+		decimalEquals(t, 3, candle.Volume)
+		assert.EqualValues(t, 2, candle.TradeCount)
+
+		// each remaining trade overshoots the threshold by itself
+		decimalEquals(t, 4, builder.Series.Candles[2].Volume)
+		assert.Nil(t, builder.Partial())
+	})
+
+	t.Run("tick", func(t *testing.T) {
+		builder := NewTickBarBuilder(2)
+		assert.Nil(t, BuildBarsFromTrades(builder, trades, true))
+		assert.Equal(t, 3, len(builder.Series.Candles))
+		decimalEquals(t, 9, builder.Series.Candles[1].MinPrice)
+		decimalEquals(t, 12, builder.Series.Candles[1].MaxPrice)
+		assert.EqualValues(t, 1, builder.Series.Candles[2].TradeCount)
+	})
+
+	t.Run("dollar", func(t *testing.T) {
+		builder := NewDollarBarBuilder(big.NewDecimal(50))
+		assert.Nil(t, BuildBarsFromTrades(builder, trades, false))
+		assert.Equal(t, 2, len(builder.Series.Candles))
+		decimalEquals(t, 6, builder.Series.Candles[0].Volume)
+	})
+
+	t.Run("candles", func(t *testing.T) {
+		builder := NewVolumeBarBuilder(big.NewDecimal(25))
+		assert.Nil(t, BuildBars(builder, minuteSeries(start, 10, 12, 8, 11, 9), false))
+		assert.Equal(t, 1, len(builder.Series.Candles))
+		assert.Equal(t, TimePeriod{Start: start, End: start.Add(time.Minute * 3)}, builder.Series.Candles[0].Period)
+		assert.EqualValues(t, 6, builder.Series.Candles[0].TradeCount)
+		decimalEquals(t, 20, builder.Partial().Volume)
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		builder := NewTickBarBuilder(10)
+		assert.Nil(t, builder.AddTrade(start, big.ONE, big.ONE))
+		assert.EqualError(t, builder.AddTrade(start.Add(-time.Second), big.ONE, big.ONE),
+			"2020-01-02 09:29:59 +0000 UTC is before the previous input ending 2020-01-02 09:30:00 +0000 UTC")
+	})
+}
+
+func TestRangeBarBuilder(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	builder := NewRangeBarBuilder(big.NewDecimal(2))
+
+	assert.Nil(t, BuildBarsFromTrades(builder, mockTrades(start, 10, 11, 9, 12, 12.5, 10.5), false))
+	assert.Equal(t, 2, len(builder.Series.Candles))
+	decimalEquals(t, 9, builder.Series.Candles[0].ClosePrice)
+	decimalEquals(t, 12, builder.Series.Candles[1].OpenPrice)
+	decimalEquals(t, 10.5, builder.Series.Candles[1].MinPrice)
+}
+
+func TestRenkoBarBuilder(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	builder := NewRenkoBarBuilder(big.NewDecimal(1))
+
+	// up 2.5 bricks, a fall of less than two bricks, then a reversal of two bricks
+	assert.Nil(t, BuildBarsFromTrades(builder, mockTrades(start, 10, 12.5, 11, 10.5, 9.9), false))
+	assert.Equal(t, 3, len(builder.Series.Candles))
+
+	bricks := builder.Series.Candles
+	decimalEquals(t, 10, bricks[0].OpenPrice)
+	decimalEquals(t, 11, bricks[0].ClosePrice)
+	decimalEquals(t, 3, bricks[0].Volume)
+	decimalEquals(t, 11, bricks[1].OpenPrice)
+	decimalEquals(t, 12, bricks[1].ClosePrice)
+	decimalEquals(t, 0, bricks[1].Volume)
+	assert.Equal(t, bricks[0].Period.End, bricks[1].Period.Start)
+	decimalEquals(t, 11, bricks[2].OpenPrice)
+	decimalEquals(t, 10, bricks[2].ClosePrice)
+	decimalEquals(t, 10, bricks[2].MinPrice)
+	assert.Nil(t, builder.Partial())
+}
+
+func TestRenkoBarBuilder_Atr(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	builder := NewAtrRenkoBarBuilder(2)
+
+	assert.NotNil(t, builder.AddTrade(start, big.ONE, big.ONE))
+
+	// the size is 3 once the flat candles have warmed up the atr, giving a brick from 10 to 13.
+	// the brick then resizes to include the true range of 5 of the last candle.
+	assert.Nil(t, BuildBars(builder, minuteSeries(start, 10, 10, 10, 14), false))
+	assert.Equal(t, 1, len(builder.Series.Candles))
+	decimalEquals(t, 13, builder.Series.Candles[0].ClosePrice)
+	decimalEquals(t, 4, builder.Size())
+}
+
+func TestBars_Indicators(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	builder := NewTickBarBuilder(1)
+	assert.Nil(t, BuildBarsFromTrades(builder, mockTrades(start, 10, 11, 12, 13), false))
+
+	sma := NewSimpleMovingAverage(NewClosePriceIndicator(builder.Series), 2)
+	decimalEquals(t, 12.5, sma.Calculate(3))
+
+	strategies := []Strategy{{Security: "TEST", Timeseries: *builder.Series, Rule: truthRule{}}}
+	alloc := NewNaiveAllocator(big.NewDecimal(1.0), big.NewDecimal(1.0))
+
+	history, err := NewBacktest(strategies, alloc, NewAccount()).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(history.Snapshots))
+}
@@ -1,40 +1,148 @@
 package techan
 
-import "github.com/schmidthole/big"
+import (
+	"fmt"
+	"sync"
 
-type resultCache []*big.Decimal
+	"github.com/schmidthole/big"
+)
 
-type cachedIndicator interface {
+// resultCache holds calculated values by absolute index. Values before the offset have been
+// evicted along with the candles of a bounded TimeSeries. The revisions and last indexes record the
+// state of each series the values are calculated on when the cache was last validated against them.
+type resultCache struct {
+	offset    int
+	values    []*big.Decimal
+	revisions []int
+	lasts     []int
+}
+
+func newResultCache(size int) resultCache {
+	return resultCache{values: make([]*big.Decimal, size)}
+}
+
+type resultCacher interface {
 	Indicator
 	cache() resultCache
 	setCache(cache resultCache)
+}
+
+// cachedIndicator is a recursive indicator which caches its own values. Its locker guards the cache,
+// and is held by returnIfCached and around cacheResult.
+type cachedIndicator interface {
+	resultCacher
 	windowSize() int
+	locker() sync.Locker
+	sourceSeries() []*TimeSeries
 }
 
-func cacheResult(indicator cachedIndicator, index int, val big.Decimal) {
-	if index < len(indicator.cache()) {
-		indicator.cache()[index] = &val
-	} else if index == len(indicator.cache()) {
-		indicator.setCache(append(indicator.cache(), &val))
+func cacheResult(indicator resultCacher, index int, val big.Decimal) {
+	cache := indicator.cache()
+	position := index - cache.offset
+
+	if position < 0 {
+		return
+	} else if position < len(cache.values) {
+		cache.values[position] = &val
+	} else if position == len(cache.values) {
+		cache.values = append(cache.values, &val)
+		indicator.setCache(cache)
 	} else {
 		expandResultCache(indicator, index+1)
 		cacheResult(indicator, index, val)
 	}
 }
 
-func expandResultCache(indicator cachedIndicator, newSize int) {
-	sizeDiff := newSize - len(indicator.cache())
+func expandResultCache(indicator resultCacher, newSize int) {
+	cache := indicator.cache()
+	sizeDiff := newSize - cache.offset - len(cache.values)
 
-	expansion := make([]*big.Decimal, sizeDiff)
-	indicator.setCache(append(indicator.cache(), expansion...))
+	if sizeDiff > 0 {
+		cache.values = append(cache.values, make([]*big.Decimal, sizeDiff)...)
+		indicator.setCache(cache)
+	}
+}
+
+// evictResultCache drops the cached values before the first index of the indicator's source, so
+// that the cache of an indicator on a bounded TimeSeries stays in step with the series.
+func evictResultCache(indicator resultCacher) {
+	cache := indicator.cache()
+	first := firstIndex(indicator)
+
+	if first <= cache.offset {
+		return
+	}
+
+	evicted := Min(first-cache.offset, len(cache.values))
+	for i := 0; i < evicted; i++ {
+		cache.values[i] = nil
+	}
+
+	cache.values = cache.values[evicted:]
+	cache.offset = first
+	indicator.setCache(cache)
+}
+
+// invalidateResultCache drops the cached values which may be stale after candles were added to any
+// of the series, or their last candle updated, since the cache was last used. Only values from what
+// was then the last candle of a changed series on are dropped, as that candle may have still been
+// forming. It returns true if the cache was not yet validated against the series, or any of them
+// changed.
+func invalidateResultCache(indicator resultCacher, series []*TimeSeries) bool {
+	cache := indicator.cache()
+
+	validated := cache.revisions != nil && len(cache.revisions) == len(series)
+	if !validated {
+		cache.revisions = make([]int, len(series))
+		cache.lasts = make([]int, len(series))
+	}
+
+	changed := false
+	from := 0
+	for i, s := range series {
+		if cache.revisions[i] == s.Revision() {
+			continue
+		}
+
+		if !changed || cache.lasts[i] < from {
+			from = cache.lasts[i]
+		}
+
+		changed = true
+		cache.revisions[i] = s.Revision()
+		cache.lasts[i] = s.LastIndex()
+	}
+
+	if changed {
+		for i := Max(from-cache.offset, 0); i < len(cache.values); i++ {
+			cache.values[i] = nil
+		}
+	}
+
+	if changed || !validated {
+		indicator.setCache(cache)
+	}
+
+	return changed || !validated
 }
 
 func returnIfCached(indicator cachedIndicator, index int, firstValueFallback func(int) big.Decimal) *big.Decimal {
-	if index >= len(indicator.cache()) {
+	indicator.locker().Lock()
+	defer indicator.locker().Unlock()
+
+	if invalidateResultCache(indicator, indicator.sourceSeries()) {
+		evictResultCache(indicator)
+	}
+
+	cache := indicator.cache()
+
+	if index-cache.offset >= len(cache.values) {
 		expandResultCache(indicator, index+1)
 	} else if index < indicator.windowSize()-1 {
 		return &big.ZERO
-	} else if val := indicator.cache()[index]; val != nil {
+	} else if index < cache.offset {
+		return nil
+	} else if val := cache.values[index-cache.offset]; val != nil {
 		return val
 	} else if index == indicator.windowSize()-1 {
 		value := firstValueFallback(index)
@@ -44,3 +152,158 @@ func returnIfCached(indicator cachedIndicator, index int, firstValueFallback fun
 
 	return nil
 }
+
+type memoizedIndicator struct {
+	indicator   Indicator
+	series      []*TimeSeries
+	lookback    int
+	validator   interface{ IsValid(index int) bool }
+	resultCache resultCache
+	mutex       sync.Mutex
+}
+
+// NewCachedIndicator returns an indicator which memoizes the values of an indicator calculated on
+// the given series, so that each value is only calculated once. Values are recalculated when they
+// may have changed: the value for the last candle after a candle is added to any of the series or
+// its last candle is updated with UpdateLastCandle, and values for evicted candles of a bounded
+// series are dropped. Values after the last candle of any series are never cached. An indicator
+// which combines several securities must be given the series of each of them.
+//
+// The built-in indicators are already cached. An indicator must only depend on the candles up to
+// the index it is calculated for to be cached, so indicators which look ahead, or whose values are
+// changed by other means than the series, should not be wrapped. A cached indicator may be
+// calculated from several goroutines if the indicator it wraps can be, as long as the series is
+// not changed meanwhile.
+func NewCachedIndicator(indicator Indicator, series ...*TimeSeries) Indicator {
+	distinct := make([]*TimeSeries, 0, len(series))
+	for _, s := range series {
+		if s == nil {
+			panic(fmt.Errorf("error creating cached indicator: series cannot be nil"))
+		}
+
+		if !containsSeries(distinct, s) {
+			distinct = append(distinct, s)
+		}
+	}
+
+	if len(distinct) == 0 {
+		panic(fmt.Errorf("error creating cached indicator: series cannot be empty"))
+	}
+
+	if cached, ok := indicator.(*memoizedIndicator); ok && sameSeries(cached.series, distinct) {
+		return cached
+	}
+
+	// the lookback and validity of the indicator do not change, and are looked up once rather than
+	// for every index a rule checks
+	validator, _ := indicator.(interface{ IsValid(index int) bool })
+
+	return &memoizedIndicator{
+		indicator: indicator,
+		series:    distinct,
+		lookback:  Lookback(indicator),
+		validator: validator,
+	}
+}
+
+func sameSeries(a, b []*TimeSeries) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, series := range a {
+		if !containsSeries(b, series) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheIndicator memoizes a built-in indicator when the series it is calculated on can be found
+// from its sources. Indicators of values which are not from a series are returned as they are.
+func cacheIndicator(indicator Indicator, sources ...Indicator) Indicator {
+	if series := seriesOf(sources...); len(series) > 0 {
+		return NewCachedIndicator(indicator, series...)
+	}
+
+	return indicator
+}
+
+func (mi *memoizedIndicator) Calculate(index int) big.Decimal {
+	if value, ok := mi.cached(index); ok {
+		return value
+	}
+
+	// the lock is not held while calculating, as the wrapped indicator may calculate other cached
+	// indicators which depend on this one
+	value := mi.indicator.Calculate(index)
+
+	if index <= mi.lastIndex() {
+		mi.mutex.Lock()
+		cacheResult(mi, index, value)
+		mi.mutex.Unlock()
+	}
+
+	return value
+}
+
+// cached returns the cached value at index, after dropping the values which are evicted or may be
+// stale
+func (mi *memoizedIndicator) cached(index int) (big.Decimal, bool) {
+	mi.mutex.Lock()
+	defer mi.mutex.Unlock()
+
+	if invalidateResultCache(mi, mi.series) {
+		evictResultCache(mi)
+	}
+
+	if position := index - mi.resultCache.offset; position >= 0 && position < len(mi.resultCache.values) {
+		if value := mi.resultCache.values[position]; value != nil {
+			return *value, true
+		}
+	}
+
+	return big.ZERO, false
+}
+
+func (mi *memoizedIndicator) cache() resultCache { return mi.resultCache }
+
+func (mi *memoizedIndicator) setCache(cache resultCache) { mi.resultCache = cache }
+
+func (mi *memoizedIndicator) sourceSeries() []*TimeSeries { return mi.series }
+
+func (mi *memoizedIndicator) FirstIndex() int {
+	first := firstIndex(mi.indicator)
+	for _, series := range mi.series {
+		first = Max(first, series.FirstIndex())
+	}
+
+	return first
+}
+
+// lastIndex returns the last index all of the series have a candle for
+func (mi *memoizedIndicator) lastIndex() int {
+	last := mi.series[0].LastIndex()
+	for _, series := range mi.series[1:] {
+		last = Min(last, series.LastIndex())
+	}
+
+	return last
+}
+
+func (mi *memoizedIndicator) Lookback() int {
+	return mi.lookback
+}
+
+func (mi *memoizedIndicator) IsValid(index int) bool {
+	if index < mi.FirstIndex() || index > mi.lastIndex() {
+		return false
+	}
+
+	if mi.validator != nil {
+		return mi.validator.IsValid(index)
+	}
+
+	return index >= mi.lookback
+}
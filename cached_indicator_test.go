@@ -0,0 +1,277 @@
+package techan
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingIndicator counts the calculations of the close price of a series
+type countingIndicator struct {
+	Indicator
+	calculations map[int]int
+}
+
+func (ci *countingIndicator) Calculate(index int) big.Decimal {
+	ci.calculations[index]++
+	return ci.Indicator.Calculate(index)
+}
+
+func TestCachedIndicator(t *testing.T) {
+	series := mockTimeSeriesFl(1, 2, 3, 4, 5)
+	counting := &countingIndicator{NewClosePriceIndicator(series), map[int]int{}}
+	cached := NewCachedIndicator(counting, series)
+
+	values := func() []float64 {
+		values := make([]float64, series.LastIndex()+1)
+		for i := range values {
+			values[i] = cached.Calculate(i).Float()
+		}
+
+		return values
+	}
+
+	t.Run("calculates each value once", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.EqualValues(t, []float64{1, 2, 3, 4, 5}, values())
+		}
+
+		assert.EqualValues(t, map[int]int{0: 1, 1: 1, 2: 1, 3: 1, 4: 1}, counting.calculations)
+		assert.Equal(t, cached, NewCachedIndicator(cached, series))
+	})
+
+	t.Run("recalculates the last candle after an append", func(t *testing.T) {
+		counting.calculations = map[int]int{}
+
+		candle := NewCandle(NewTimePeriod(series.LastCandle().Period.End, time.Second))
+		candle.ClosePrice = big.NewFromInt(6)
+		series.AddCandle(candle)
+
+		assert.EqualValues(t, []float64{1, 2, 3, 4, 5, 6}, values())
+		assert.EqualValues(t, map[int]int{4: 1, 5: 1}, counting.calculations)
+	})
+
+	t.Run("recalculates an updated last candle", func(t *testing.T) {
+		counting.calculations = map[int]int{}
+
+		candle := NewCandle(series.LastCandle().Period)
+		candle.ClosePrice = big.NewFromInt(7)
+		assert.True(t, series.UpdateLastCandle(candle))
+
+		assert.EqualValues(t, []float64{1, 2, 3, 4, 5, 7}, values())
+		assert.EqualValues(t, map[int]int{5: 1}, counting.calculations)
+	})
+
+	t.Run("panics without a series", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewCachedIndicator(counting, nil)
+		})
+
+		assert.Panics(t, func() {
+			NewCachedIndicator(counting)
+		})
+	})
+}
+
+func TestCachedIndicator_SeveralSeries(t *testing.T) {
+	first := mockTimeSeriesFl(1, 2, 3)
+	second := mockTimeSeriesFl(1, 2, 1)
+
+	spread := NewDifferenceIndicator(NewClosePriceIndicator(first), NewClosePriceIndicator(second))
+	average := NewSimpleMovingAverage(spread, 2)
+	decimalEquals(t, 2, spread.Calculate(2))
+	decimalEquals(t, 1, average.Calculate(2))
+
+	t.Run("recalculates when either series is updated", func(t *testing.T) {
+		candle := NewCandle(second.LastCandle().Period)
+		candle.ClosePrice = big.NewFromInt(10)
+		assert.True(t, second.UpdateLastCandle(candle))
+
+		decimalEquals(t, -7, spread.Calculate(2))
+		decimalEquals(t, -3.5, average.Calculate(2))
+
+		candle = NewCandle(first.LastCandle().Period)
+		candle.ClosePrice = big.NewFromInt(11)
+		assert.True(t, first.UpdateLastCandle(candle))
+
+		decimalEquals(t, 1, spread.Calculate(2))
+		decimalEquals(t, 0.5, average.Calculate(2))
+	})
+
+	t.Run("only caches candles of every series", func(t *testing.T) {
+		candle := NewCandle(NewTimePeriod(first.LastCandle().Period.End, time.Second))
+		candle.ClosePrice = big.NewFromInt(12)
+		first.AddCandle(candle)
+
+		assert.False(t, IsValid(spread, 3))
+		assert.Equal(t, 1, len(seriesOf(NewClosePriceIndicator(first))))
+		assert.Equal(t, 2, len(seriesOf(average)))
+	})
+}
+
+func TestCachedIndicator_BuiltIn(t *testing.T) {
+	full := randomTimeSeries(60)
+	series := NewTimeSeries()
+
+	constructors := map[string]func(*TimeSeries) Indicator{
+		"sma": func(s *TimeSeries) Indicator { return NewSimpleMovingAverage(NewClosePriceIndicator(s), 5) },
+		"rsi": func(s *TimeSeries) Indicator { return NewRelativeStrengthIndexIndicator(NewClosePriceIndicator(s), 5) },
+		"atr": func(s *TimeSeries) Indicator { return NewAverageTrueRangeIndicator(s, 5) },
+		"stochastic": func(s *TimeSeries) Indicator {
+			return NewSlowStochasticIndicator(NewFastStochasticIndicator(s, 5), 3)
+		},
+		"bollinger": func(s *TimeSeries) Indicator {
+			return NewBollingerUpperBandIndicator(NewClosePriceIndicator(s), 5, 2)
+		},
+	}
+
+	live := map[string]Indicator{}
+	for name, constructor := range constructors {
+		live[name] = constructor(series)
+		_, ok := live[name].(*memoizedIndicator)
+		assert.True(t, ok, name)
+	}
+
+	// values calculated while the series grows match those of an uncached series
+	for i, candle := range full.Candles {
+		forming := NewCandle(candle.Period)
+		forming.OpenPrice, forming.ClosePrice = candle.OpenPrice, candle.OpenPrice
+		forming.MaxPrice, forming.MinPrice = candle.OpenPrice, candle.OpenPrice
+		series.AddCandle(forming)
+
+		for _, indicator := range live {
+			indicator.Calculate(i)
+		}
+
+		series.UpdateLastCandle(candle)
+
+		for name, indicator := range live {
+			expected := constructors[name](full)
+			assert.EqualValues(t, expected.Calculate(i).String(), indicator.Calculate(i).String(), "%v at %v", name, i)
+		}
+	}
+}
+
+func TestCachedIndicator_Bounded(t *testing.T) {
+	full := randomTimeSeries(50)
+	bounded := NewBoundedTimeSeries(10)
+
+	expected := NewSimpleMovingAverage(NewClosePriceIndicator(full), 4)
+	sma := NewSimpleMovingAverage(NewClosePriceIndicator(bounded), 4)
+
+	for i, candle := range full.Candles {
+		bounded.AddCandle(candle)
+		assert.EqualValues(t, expected.Calculate(i).String(), sma.Calculate(i).String())
+	}
+
+	cached := sma.(*memoizedIndicator)
+	assert.Equal(t, 40, cached.FirstIndex())
+	assert.Equal(t, 40, cached.cache().offset)
+	assert.Len(t, cached.cache().values, 10)
+}
+
+func TestCachedIndicator_WithoutSeries(t *testing.T) {
+	sma := NewSimpleMovingAverage(NewFixedIndicator(1, 2, 3), 2)
+
+	_, ok := sma.(*memoizedIndicator)
+	assert.False(t, ok)
+	decimalEquals(t, 2.5, sma.Calculate(2))
+}
+
+// benchmarkIndicatorPass calculates every value of a new indicator over a series of 2000 candles
+func benchmarkIndicatorPass(b *testing.B, constructor func(*TimeSeries) Indicator) {
+	series := randomTimeSeries(2000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indicator := constructor(series)
+		for index := 0; index <= series.LastIndex(); index++ {
+			indicator.Calculate(index)
+		}
+	}
+}
+
+func BenchmarkCachedIndicator_SMA(b *testing.B) {
+	benchmarkIndicatorPass(b, func(s *TimeSeries) Indicator {
+		return NewSimpleMovingAverage(NewClosePriceIndicator(s), 20)
+	})
+}
+
+func BenchmarkCachedIndicator_ATR(b *testing.B) {
+	benchmarkIndicatorPass(b, func(s *TimeSeries) Indicator { return NewAverageTrueRangeIndicator(s, 14) })
+}
+
+func BenchmarkCachedIndicator_RSI(b *testing.B) {
+	benchmarkIndicatorPass(b, func(s *TimeSeries) Indicator {
+		return NewRelativeStrengthIndexIndicator(NewClosePriceIndicator(s), 14)
+	})
+}
+
+func BenchmarkCachedIndicator_CrossUpRSI(b *testing.B) {
+	series := randomTimeSeries(2000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsi := NewRelativeStrengthIndexIndicator(NewClosePriceIndicator(series), 14)
+		rule := NewCrossUpIndicatorRule(NewConstantIndicator(30), rsi)
+		for index := 0; index <= series.LastIndex(); index++ {
+			rule.IsSatisfied(index)
+		}
+	}
+}
+
+func TestCachedIndicator_Concurrent(t *testing.T) {
+	series := randomTimeSeries(200)
+	closePrice := NewClosePriceIndicator(series)
+
+	indicators := []Indicator{
+		NewSimpleMovingAverage(closePrice, 10),
+		NewRelativeStrengthIndexIndicator(closePrice, 14),
+		NewAverageTrueRangeIndicator(series, 14),
+		NewEMAIndicator(closePrice, 10),
+		NewAroonUpIndicator(NewHighPriceIndicator(series), 10),
+	}
+
+	expected := make([][]string, len(indicators))
+	for i, constructor := range []func() Indicator{
+		func() Indicator { return NewSimpleMovingAverage(closePrice, 10) },
+		func() Indicator { return NewRelativeStrengthIndexIndicator(closePrice, 14) },
+		func() Indicator { return NewAverageTrueRangeIndicator(series, 14) },
+		func() Indicator { return NewEMAIndicator(closePrice, 10) },
+		func() Indicator { return NewAroonUpIndicator(NewHighPriceIndicator(series), 10) },
+	} {
+		indicator := constructor()
+		for index := 0; index <= series.LastIndex(); index++ {
+			expected[i] = append(expected[i], indicator.Calculate(index).String())
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			for i, indicator := range indicators {
+				for index := 0; index <= series.LastIndex(); index++ {
+					// the goroutines walk the series in different directions
+					if g%2 == 1 {
+						index = series.LastIndex() - index
+					}
+
+					assert.Equal(t, expected[i][index], indicator.Calculate(index).String())
+					IsValid(indicator, index)
+
+					if g%2 == 1 {
+						index = series.LastIndex() - index
+					}
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+}
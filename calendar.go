@@ -0,0 +1,473 @@
+package techan
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A Session is the regular trading hours of a trading day, as offsets from local midnight. A
+// negative Open is a session which opens on the evening before, as futures sessions do.
+type Session struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// A Holiday is a day on which an exchange is closed, or closes early if Close is set, as an offset
+// from local midnight.
+type Holiday struct {
+	Date  time.Time
+	Name  string
+	Close time.Duration
+}
+
+// EarlyClose returns true if the exchange trades on the holiday, but closes early
+func (h Holiday) EarlyClose() bool {
+	return h.Close != 0
+}
+
+// A Calendar is the trading schedule of an exchange: its weekday session hours in its location and
+// its holidays, which are generated by rule for any year. Trading days are the weekdays which are
+// not full holidays. DaysPerYear is the number of trading days used to annualize returns.
+type Calendar struct {
+	Name        string
+	Location    *time.Location
+	Session     Session
+	DaysPerYear int
+	rules       func(year int) []Holiday
+	extra       []Holiday
+}
+
+// NewCalendar returns a calendar with the given session hours and holiday rules. The rules are
+// called with a year and return the holidays of that year, at midnight in any location.
+func NewCalendar(name string, location *time.Location, session Session, rules func(year int) []Holiday) *Calendar {
+	return &Calendar{
+		Name:        name,
+		Location:    location,
+		Session:     session,
+		DaysPerYear: 252,
+		rules:       rules,
+		extra:       make([]Holiday, 0),
+	}
+}
+
+// NewNYSECalendar returns the calendar of the New York Stock Exchange, trading from 9:30 to 16:00
+// New York time and closing at 13:00 on the day before Independence Day, the day after
+// Thanksgiving and Christmas Eve.
+//
+// The built-in calendars load their location from the time zone database, and panic if it is not
+// available. Applications which run where no zoneinfo is installed, such as scratch containers or
+// Windows hosts without Go, should embed it by importing time/tzdata or building with -tags
+// timetzdata.
+func NewNYSECalendar() *Calendar {
+	return NewCalendar("NYSE", mustLoadLocation("America/New_York"), Session{hours(9.5), hours(16)}, nyseHolidays)
+}
+
+// NewLSECalendar returns the calendar of the London Stock Exchange, trading from 8:00 to 16:30
+// London time and closing at 12:30 on Christmas Eve and New Year's Eve.
+func NewLSECalendar() *Calendar {
+	return NewCalendar("LSE", mustLoadLocation("Europe/London"), Session{hours(8), hours(16.5)}, lseHolidays)
+}
+
+// NewXETRACalendar returns the calendar of the Xetra trading venue, trading from 9:00 to 17:30
+// Frankfurt time.
+func NewXETRACalendar() *Calendar {
+	return NewCalendar("XETRA", mustLoadLocation("Europe/Berlin"), Session{hours(9), hours(17.5)}, xetraHolidays)
+}
+
+// NewCMECalendar returns the calendar of CME Globex equity and interest rate futures, trading from
+// 17:00 Chicago time on the evening before to 16:00. The exchange is closed on New Year's Day, Good
+// Friday and Christmas, closes at 12:00 on the other US federal holidays, and at 12:15 on the day
+// after Thanksgiving and Christmas Eve.
+func NewCMECalendar() *Calendar {
+	return NewCalendar("CME", mustLoadLocation("America/Chicago"), Session{-hours(7), hours(16)}, cmeHolidays)
+}
+
+// AddHoliday adds a holiday which does not follow the calendar's rules, such as a closure for a
+// national day of mourning.
+func (c *Calendar) AddHoliday(holiday Holiday) {
+	c.extra = append(c.extra, holiday)
+}
+
+// Holidays returns the holidays and early closes of a year, in date order
+func (c *Calendar) Holidays(year int) []Holiday {
+	holidays := make([]Holiday, 0)
+
+	// observed dates can move a holiday into the neighbouring year
+	candidates := append(append(c.rules(year-1), c.rules(year)...), c.rules(year+1)...)
+
+	for _, holiday := range append(candidates, c.extra...) {
+		y, m, d := holiday.Date.Date()
+		if y == year {
+			holiday.Date = time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+			holidays = append(holidays, holiday)
+		}
+	}
+
+	sort.SliceStable(holidays, func(i, j int) bool {
+		return holidays[i].Date.Before(holidays[j].Date)
+	})
+
+	return holidays
+}
+
+// IsTradingDay returns true if the exchange trades on the local date of the given time
+func (c *Calendar) IsTradingDay(date time.Time) bool {
+	_, ok := c.SessionOn(date)
+	return ok
+}
+
+// SessionOn returns the trading session of the local date of the given time, taking early closes
+// into account. False is returned if the date is not a trading day.
+func (c *Calendar) SessionOn(date time.Time) (TimePeriod, bool) {
+	midnight := c.midnight(date)
+	if weekday := midnight.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return TimePeriod{}, false
+	}
+
+	session := c.Session
+	if holiday, ok := c.holidayOn(midnight); ok {
+		if !holiday.EarlyClose() {
+			return TimePeriod{}, false
+		}
+
+		session.Close = holiday.Close
+	}
+
+	return TimePeriod{Start: c.at(midnight, session.Open), End: c.at(midnight, session.Close)}, true
+}
+
+// IsOpen returns true if the exchange is in its regular session at the given time
+func (c *Calendar) IsOpen(t time.Time) bool {
+	// a session opening on the evening before belongs to the next day
+	for _, date := range []time.Time{t, t.In(c.Location).AddDate(0, 0, 1)} {
+		if session, ok := c.SessionOn(date); ok && !t.Before(session.Start) && t.Before(session.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextTradingDay returns local midnight of the first trading day after the date of the given time
+func (c *Calendar) NextTradingDay(date time.Time) time.Time {
+	return c.stepTradingDay(date, 1)
+}
+
+// PreviousTradingDay returns local midnight of the last trading day before the date of the given time
+func (c *Calendar) PreviousTradingDay(date time.Time) time.Time {
+	return c.stepTradingDay(date, -1)
+}
+
+// TradingDaysBetween returns the number of trading days from the date of start up to, but not
+// including, the date of end. It is negative if end is before start.
+func (c *Calendar) TradingDaysBetween(start, end time.Time) int {
+	from, to := c.midnight(start), c.midnight(end)
+	if to.Before(from) {
+		return -c.TradingDaysBetween(end, start)
+	}
+
+	days := 0
+	for date := from; date.Before(to); date = c.addDays(date, 1) {
+		if c.IsTradingDay(date) {
+			days++
+		}
+	}
+
+	return days
+}
+
+// NextPeriod returns the trading period following a bar. Daily bars move to the next trading day at
+// the same local time. Intraday bars advance by their length within the session and move to the
+// open of the next session once the session has ended. Longer bars advance by their length.
+func (c *Calendar) NextPeriod(period TimePeriod) TimePeriod {
+	length := period.Length()
+
+	if isDaily(length) {
+		next := c.NextTradingDay(period.Start)
+		return NewTimePeriod(c.at(next, c.timeOfDay(period.Start)), length)
+	} else if length > time.Hour*24 {
+		return period.Advance(1)
+	}
+
+	candidate := period.Advance(1)
+	if c.withinSession(candidate) {
+		return candidate
+	}
+
+	for date := c.midnight(period.Start); ; date = c.addDays(date, 1) {
+		if session, ok := c.SessionOn(date); ok && !session.Start.Before(period.End) {
+			return NewTimePeriod(session.Start, length)
+		}
+	}
+}
+
+// PreviousPeriod returns the trading period preceding a bar. Daily bars move to the previous trading
+// day at the same local time. Intraday bars move back by their length within the session and to the
+// last full bar of the previous session once at the open. Longer bars move back by their length.
+func (c *Calendar) PreviousPeriod(period TimePeriod) TimePeriod {
+	length := period.Length()
+
+	if isDaily(length) {
+		previous := c.PreviousTradingDay(period.Start)
+		return NewTimePeriod(c.at(previous, c.timeOfDay(period.Start)), length)
+	} else if length > time.Hour*24 {
+		return period.Advance(-1)
+	}
+
+	candidate := period.Advance(-1)
+	if c.withinSession(candidate) {
+		return candidate
+	}
+
+	// a session opening the evening before belongs to the next date
+	for date := c.addDays(c.midnight(period.Start), 1); ; date = c.addDays(date, -1) {
+		if session, ok := c.SessionOn(date); ok && !session.End.After(period.Start) {
+			bars := Max(int(session.Length()/length), 1)
+			return NewTimePeriod(session.Start.Add(length*time.Duration(bars-1)), length)
+		}
+	}
+}
+
+func (c *Calendar) withinSession(period TimePeriod) bool {
+	for _, date := range []time.Time{period.Start, period.Start.In(c.Location).AddDate(0, 0, 1)} {
+		if session, ok := c.SessionOn(date); ok && !period.Start.Before(session.Start) && !period.End.After(session.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Calendar) stepTradingDay(date time.Time, step int) time.Time {
+	day := c.midnight(date)
+
+	for {
+		day = c.addDays(day, step)
+		if c.IsTradingDay(day) {
+			return day
+		}
+	}
+}
+
+func (c *Calendar) holidayOn(midnight time.Time) (Holiday, bool) {
+	for _, holiday := range c.Holidays(midnight.Year()) {
+		if holiday.Date.Equal(midnight) {
+			return holiday, true
+		}
+	}
+
+	return Holiday{}, false
+}
+
+func (c *Calendar) midnight(t time.Time) time.Time {
+	year, month, day := t.In(c.Location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, c.Location)
+}
+
+func (c *Calendar) addDays(midnight time.Time, days int) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day()+days, 0, 0, 0, 0, c.Location)
+}
+
+// at returns the wall clock time at an offset from local midnight, which stays correct across
+// daylight saving changes
+func (c *Calendar) at(midnight time.Time, offset time.Duration) time.Time {
+	day := 0
+	for offset < 0 {
+		offset += time.Hour * 24
+		day--
+	}
+
+	clock := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Add(offset)
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day()+day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), c.Location)
+}
+
+func (c *Calendar) timeOfDay(t time.Time) time.Duration {
+	local := t.In(c.Location)
+	return time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+}
+
+func nyseHolidays(year int) []Holiday {
+	holidays := []Holiday{
+		{Date: usNewYear(year), Name: "New Year's Day"},
+		{Date: nthWeekday(year, time.February, time.Monday, 3), Name: "Washington's Birthday"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: nthWeekday(year, time.May, time.Monday, -1), Name: "Memorial Day"},
+		{Date: usObserved(date(year, time.July, 4)), Name: "Independence Day"},
+		{Date: nthWeekday(year, time.September, time.Monday, 1), Name: "Labor Day"},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4), Name: "Thanksgiving Day"},
+		{Date: usObserved(date(year, time.December, 25)), Name: "Christmas Day"},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1), Name: "Day after Thanksgiving", Close: hours(13)},
+	}
+
+	if year >= 1998 {
+		holidays = append(holidays, Holiday{Date: nthWeekday(year, time.January, time.Monday, 3), Name: "Martin Luther King Jr. Day"})
+	}
+
+	if year >= 2022 {
+		holidays = append(holidays, Holiday{Date: usObserved(date(year, time.June, 19)), Name: "Juneteenth"})
+	}
+
+	// the day before independence day and christmas eve close early if the exchange is open
+	for _, early := range []Holiday{
+		{Date: date(year, time.July, 3), Name: "Day before Independence Day", Close: hours(13)},
+		{Date: date(year, time.December, 24), Name: "Christmas Eve", Close: hours(13)},
+	} {
+		if weekday := early.Date.Weekday(); weekday >= time.Monday && weekday <= time.Thursday {
+			holidays = append(holidays, early)
+		}
+	}
+
+	return holidays
+}
+
+func lseHolidays(year int) []Holiday {
+	goodFriday := easter(year).AddDate(0, 0, -2)
+	holidays := []Holiday{
+		{Date: ukSubstitute(date(year, time.January, 1), nil), Name: "New Year's Day"},
+		{Date: goodFriday, Name: "Good Friday"},
+		{Date: goodFriday.AddDate(0, 0, 3), Name: "Easter Monday"},
+		{Date: nthWeekday(year, time.May, time.Monday, 1), Name: "Early May Bank Holiday"},
+		{Date: nthWeekday(year, time.May, time.Monday, -1), Name: "Spring Bank Holiday"},
+		{Date: nthWeekday(year, time.August, time.Monday, -1), Name: "Summer Bank Holiday"},
+	}
+
+	christmas := ukSubstitute(date(year, time.December, 25), nil)
+	boxingDay := ukSubstitute(date(year, time.December, 26), &christmas)
+	holidays = append(holidays,
+		Holiday{Date: christmas, Name: "Christmas Day"},
+		Holiday{Date: boxingDay, Name: "Boxing Day"},
+	)
+
+	for _, early := range []Holiday{
+		{Date: date(year, time.December, 24), Name: "Christmas Eve", Close: hours(12.5)},
+		{Date: date(year, time.December, 31), Name: "New Year's Eve", Close: hours(12.5)},
+	} {
+		if weekday := early.Date.Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+			holidays = append(holidays, early)
+		}
+	}
+
+	return holidays
+}
+
+func xetraHolidays(year int) []Holiday {
+	goodFriday := easter(year).AddDate(0, 0, -2)
+
+	return []Holiday{
+		{Date: date(year, time.January, 1), Name: "New Year's Day"},
+		{Date: goodFriday, Name: "Good Friday"},
+		{Date: goodFriday.AddDate(0, 0, 3), Name: "Easter Monday"},
+		{Date: date(year, time.May, 1), Name: "Labour Day"},
+		{Date: date(year, time.December, 24), Name: "Christmas Eve"},
+		{Date: date(year, time.December, 25), Name: "Christmas Day"},
+		{Date: date(year, time.December, 26), Name: "Boxing Day"},
+		{Date: date(year, time.December, 31), Name: "New Year's Eve"},
+	}
+}
+
+func cmeHolidays(year int) []Holiday {
+	noon := hours(12)
+	holidays := []Holiday{
+		{Date: usNewYear(year), Name: "New Year's Day"},
+		{Date: easter(year).AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: usObserved(date(year, time.December, 25)), Name: "Christmas Day"},
+		{Date: nthWeekday(year, time.January, time.Monday, 3), Name: "Martin Luther King Jr. Day", Close: noon},
+		{Date: nthWeekday(year, time.February, time.Monday, 3), Name: "Washington's Birthday", Close: noon},
+		{Date: nthWeekday(year, time.May, time.Monday, -1), Name: "Memorial Day", Close: noon},
+		{Date: usObserved(date(year, time.July, 4)), Name: "Independence Day", Close: noon},
+		{Date: nthWeekday(year, time.September, time.Monday, 1), Name: "Labor Day", Close: noon},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4), Name: "Thanksgiving Day", Close: noon},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1), Name: "Day after Thanksgiving", Close: hours(12.25)},
+	}
+
+	if year >= 2022 {
+		holidays = append(holidays, Holiday{Date: usObserved(date(year, time.June, 19)), Name: "Juneteenth", Close: noon})
+	}
+
+	if christmasEve := date(year, time.December, 24); christmasEve.Weekday() >= time.Monday && christmasEve.Weekday() <= time.Thursday {
+		holidays = append(holidays, Holiday{Date: christmasEve, Name: "Christmas Eve", Close: hours(12.25)})
+	}
+
+	return holidays
+}
+
+// isDaily returns true for the length of a daily bar, which is an hour shorter or longer on days
+// with a daylight saving change
+func isDaily(length time.Duration) bool {
+	return length >= time.Hour*23 && length <= time.Hour*25
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func hours(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour))
+}
+
+// nthWeekday returns the nth given weekday of a month, or the last one if n is negative
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		last := date(year, month+1, 0)
+		return last.AddDate(0, 0, -((int(last.Weekday()) - int(weekday) + 7) % 7))
+	}
+
+	first := date(year, month, 1)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(n-1))
+}
+
+// easter returns easter sunday of a year by the anonymous gregorian algorithm
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := (19*a + b - b/4 - (b-(b+8)/25+1)/3 + 15) % 30
+	e := (32 + 2*(b%4) + 2*(c/4) - d - c%4) % 7
+	f := d + e - 7*((a+11*d+22*e)/451) + 114
+
+	return date(year, time.Month(f/31), f%31+1)
+}
+
+// usObserved moves a holiday on a saturday to the friday before and on a sunday to the monday after
+func usObserved(holiday time.Time) time.Time {
+	switch holiday.Weekday() {
+	case time.Saturday:
+		return holiday.AddDate(0, 0, -1)
+	case time.Sunday:
+		return holiday.AddDate(0, 0, 1)
+	}
+
+	return holiday
+}
+
+// usNewYear returns new year's day as observed by US exchanges, which move it to the monday when it
+// falls on a sunday, but do not close on the last day of the year when it falls on a saturday
+func usNewYear(year int) time.Time {
+	if newYear := date(year, time.January, 1); newYear.Weekday() == time.Sunday {
+		return newYear.AddDate(0, 0, 1)
+	}
+
+	return date(year, time.January, 1)
+}
+
+// ukSubstitute moves a holiday on a weekend to the next weekday which is not already taken
+func ukSubstitute(holiday time.Time, taken *time.Time) time.Time {
+	for holiday.Weekday() == time.Saturday || holiday.Weekday() == time.Sunday || (taken != nil && holiday.Equal(*taken)) {
+		holiday = holiday.AddDate(0, 0, 1)
+	}
+
+	return holiday
+}
+
+// mustLoadLocation loads a location of a built-in calendar. The time zone database is not embedded,
+// so that binaries which never use a calendar do not carry it; see NewNYSECalendar.
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Errorf("error loading location %v: %v", name, err))
+	}
+
+	return location
+}
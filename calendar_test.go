@@ -0,0 +1,152 @@
+package techan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func holidayDates(calendar *Calendar, year int, early bool) []string {
+	dates := make([]string, 0)
+	for _, holiday := range calendar.Holidays(year) {
+		if holiday.EarlyClose() == early {
+			dates = append(dates, holiday.Date.Format(SimpleDateFormatV2))
+		}
+	}
+
+	return dates
+}
+
+func TestCalendar_Holidays(t *testing.T) {
+	nyse := NewNYSECalendar()
+	assert.Equal(t, []string{
+		"2024-01-01", "2024-01-15", "2024-02-19", "2024-03-29", "2024-05-27",
+		"2024-06-19", "2024-07-04", "2024-09-02", "2024-11-28", "2024-12-25",
+	}, holidayDates(nyse, 2024, false))
+	assert.Equal(t, []string{"2024-07-03", "2024-11-29", "2024-12-24"}, holidayDates(nyse, 2024, true))
+
+	// new year's day on a saturday is not observed, juneteenth and christmas on a sunday are
+	assert.Equal(t, []string{
+		"2022-01-01", "2022-01-17", "2022-02-21", "2022-04-15", "2022-05-30",
+		"2022-06-20", "2022-07-04", "2022-09-05", "2022-11-24", "2022-12-26",
+	}, holidayDates(nyse, 2022, false))
+
+	lse := NewLSECalendar()
+	assert.Equal(t, []string{
+		"2021-01-01", "2021-04-02", "2021-04-05", "2021-05-03", "2021-05-31",
+		"2021-08-30", "2021-12-27", "2021-12-28",
+	}, holidayDates(lse, 2021, false))
+	assert.Equal(t, []string{"2021-12-24", "2021-12-31"}, holidayDates(lse, 2021, true))
+
+	xetra := NewXETRACalendar()
+	assert.False(t, xetra.IsTradingDay(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, xetra.IsTradingDay(time.Date(2024, time.December, 24, 12, 0, 0, 0, time.UTC)))
+
+	cme := NewCMECalendar()
+	assert.Equal(t, []string{"2021-01-01", "2021-04-02", "2021-12-24"}, holidayDates(cme, 2021, false))
+
+	nyse.AddHoliday(Holiday{Date: date(2025, time.January, 9), Name: "National Day of Mourning"})
+	assert.False(t, nyse.IsTradingDay(time.Date(2025, time.January, 9, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestCalendar_Sessions(t *testing.T) {
+	nyse := NewNYSECalendar()
+	newYork := nyse.Location
+
+	session, ok := nyse.SessionOn(time.Date(2024, time.November, 29, 0, 0, 0, 0, newYork))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, time.November, 29, 9, 30, 0, 0, newYork), session.Start)
+	assert.Equal(t, time.Date(2024, time.November, 29, 13, 0, 0, 0, newYork), session.End)
+
+	_, ok = nyse.SessionOn(time.Date(2024, time.November, 30, 0, 0, 0, 0, newYork))
+	assert.False(t, ok)
+
+	assert.True(t, nyse.IsOpen(time.Date(2024, time.March, 11, 14, 0, 0, 0, time.UTC)))
+	assert.False(t, nyse.IsOpen(time.Date(2024, time.March, 11, 13, 0, 0, 0, time.UTC)))
+
+	// the futures session for monday opens on sunday evening
+	cme := NewCMECalendar()
+	chicago := cme.Location
+	assert.True(t, cme.IsOpen(time.Date(2024, time.March, 10, 18, 0, 0, 0, chicago)))
+	assert.False(t, cme.IsOpen(time.Date(2024, time.March, 10, 16, 0, 0, 0, chicago)))
+	assert.False(t, cme.IsOpen(time.Date(2024, time.March, 11, 16, 30, 0, 0, chicago)))
+}
+
+func TestCalendar_TradingDays(t *testing.T) {
+	nyse := NewNYSECalendar()
+	newYork := nyse.Location
+
+	newYear2023 := time.Date(2023, time.January, 1, 0, 0, 0, 0, newYork)
+	newYear2024 := time.Date(2024, time.January, 1, 0, 0, 0, 0, newYork)
+	newYear2025 := time.Date(2025, time.January, 1, 0, 0, 0, 0, newYork)
+
+	assert.Equal(t, 252, nyse.TradingDaysBetween(newYear2024, newYear2025))
+	assert.Equal(t, 250, nyse.TradingDaysBetween(newYear2023, newYear2024))
+	assert.Equal(t, -252, nyse.TradingDaysBetween(newYear2025, newYear2024))
+
+	// dates are taken in the calendar's location, where midnight utc is still the previous day
+	assert.Equal(t, 251, nyse.TradingDaysBetween(date(2024, time.January, 1), date(2025, time.January, 1)))
+
+	assert.Equal(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, newYork), nyse.NextTradingDay(time.Date(2024, time.March, 28, 12, 0, 0, 0, newYork)))
+	assert.Equal(t, time.Date(2024, time.March, 28, 0, 0, 0, 0, newYork), nyse.PreviousTradingDay(time.Date(2024, time.April, 1, 12, 0, 0, 0, newYork)))
+}
+
+func TestCalendar_Periods(t *testing.T) {
+	nyse := NewNYSECalendar()
+	newYork := nyse.Location
+
+	tests := []struct {
+		name     string
+		period   TimePeriod
+		next     TimePeriod
+		previous TimePeriod
+	}{
+		{
+			name:     "daily over good friday",
+			period:   NewTimePeriod(time.Date(2024, time.March, 28, 0, 0, 0, 0, newYork), time.Hour*24),
+			next:     NewTimePeriod(time.Date(2024, time.April, 1, 0, 0, 0, 0, newYork), time.Hour*24),
+			previous: NewTimePeriod(time.Date(2024, time.March, 27, 0, 0, 0, 0, newYork), time.Hour*24),
+		},
+		{
+			name:     "intraday within the session",
+			period:   NewTimePeriod(time.Date(2024, time.March, 27, 10, 0, 0, 0, newYork), time.Minute*30),
+			next:     NewTimePeriod(time.Date(2024, time.March, 27, 10, 30, 0, 0, newYork), time.Minute*30),
+			previous: NewTimePeriod(time.Date(2024, time.March, 27, 9, 30, 0, 0, newYork), time.Minute*30),
+		},
+		{
+			name:     "intraday over the close",
+			period:   NewTimePeriod(time.Date(2024, time.March, 28, 15, 30, 0, 0, newYork), time.Minute*30),
+			next:     NewTimePeriod(time.Date(2024, time.April, 1, 9, 30, 0, 0, newYork), time.Minute*30),
+			previous: NewTimePeriod(time.Date(2024, time.March, 28, 15, 0, 0, 0, newYork), time.Minute*30),
+		},
+		{
+			name:     "intraday over an early close",
+			period:   NewTimePeriod(time.Date(2024, time.December, 2, 9, 30, 0, 0, newYork), time.Hour),
+			next:     NewTimePeriod(time.Date(2024, time.December, 2, 10, 30, 0, 0, newYork), time.Hour),
+			previous: NewTimePeriod(time.Date(2024, time.November, 29, 11, 30, 0, 0, newYork), time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.next, nyse.NextPeriod(tt.period))
+			assert.Equal(t, tt.previous, nyse.PreviousPeriod(tt.period))
+		})
+	}
+}
+
+func TestCalendar_AnnualizedReturn(t *testing.T) {
+	ah := NewAccountHistory()
+	for i, equity := range []float64{100, 200} {
+		period := NewTimePeriod(date(2024+i, time.January, 2), time.Hour*24)
+		ah.ApplySnapshot(&AccountSnapshot{Period: period, Equity: big.NewDecimal(equity)}, &PricingSnapshot{Period: period})
+	}
+
+	// a leap year of calendar days, but exactly a year of trading days
+	decimalEquals(t, 99.6216, ah.AnnualizedReturn())
+
+	ah.Calendar = NewNYSECalendar()
+	decimalEquals(t, 100, ah.AnnualizedReturn())
+}
@@ -0,0 +1,260 @@
+package chart
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strings"
+)
+
+// canvas is the drawing surface a chart is rendered onto. Coordinates are in pixels with the
+// origin in the top left corner.
+type canvas interface {
+	line(x1, y1, x2, y2 float64, c color.RGBA)
+	polyline(xs, ys []float64, c color.RGBA)
+	rect(x, y, w, h float64, c color.RGBA)
+	triangle(x1, y1, x2, y2, x3, y3 float64, c color.RGBA)
+	text(x, y float64, s string, anchor string, c color.RGBA)
+}
+
+type svgCanvas struct {
+	b strings.Builder
+}
+
+func newSvgCanvas(width, height int, background color.RGBA) *svgCanvas {
+	sc := new(svgCanvas)
+	fmt.Fprintf(&sc.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	sc.rect(0, 0, float64(width), float64(height), background)
+
+	return sc
+}
+
+func (sc *svgCanvas) line(x1, y1, x2, y2 float64, c color.RGBA) {
+	fmt.Fprintf(&sc.b, `<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="%s"/>`, x1, y1, x2, y2, hex(c))
+}
+
+func (sc *svgCanvas) polyline(xs, ys []float64, c color.RGBA) {
+	points := make([]string, len(xs))
+	for i := range xs {
+		points[i] = fmt.Sprintf("%.2f,%.2f", xs[i], ys[i])
+	}
+
+	fmt.Fprintf(&sc.b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.2"/>`,
+		strings.Join(points, " "), hex(c))
+}
+
+func (sc *svgCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	fmt.Fprintf(&sc.b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`, x, y, w, h, hex(c))
+}
+
+func (sc *svgCanvas) triangle(x1, y1, x2, y2, x3, y3 float64, c color.RGBA) {
+	fmt.Fprintf(&sc.b, `<polygon points="%.2f,%.2f %.2f,%.2f %.2f,%.2f" fill="%s"/>`, x1, y1, x2, y2, x3, y3, hex(c))
+}
+
+func (sc *svgCanvas) text(x, y float64, s string, anchor string, c color.RGBA) {
+	fmt.Fprintf(&sc.b, `<text x="%.2f" y="%.2f" font-family="sans-serif" font-size="11" text-anchor="%s" fill="%s">%s</text>`,
+		x, y, anchor, hex(c), html.EscapeString(s))
+}
+
+func (sc *svgCanvas) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, sc.b.String()+"</svg>\n")
+	return err
+}
+
+// rasterCanvas draws onto an in-memory image. Text is not rendered since no font is available
+// without external dependencies. Shapes with coordinates which are not finite are skipped, and
+// shapes are clipped to the image before they are drawn, so that coordinates far outside of it do
+// not cost a pixel each.
+type rasterCanvas struct {
+	img *image.RGBA
+}
+
+func newRasterCanvas(width, height int, background color.RGBA) *rasterCanvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	return &rasterCanvas{img: img}
+}
+
+func (rc *rasterCanvas) line(x1, y1, x2, y2 float64, c color.RGBA) {
+	x1, y1, x2, y2, visible := rc.clip(x1, y1, x2, y2)
+	if !visible {
+		return
+	}
+
+	ix1, iy1 := int(math.Round(x1)), int(math.Round(y1))
+	ix2, iy2 := int(math.Round(x2)), int(math.Round(y2))
+
+	dx := abs(ix2 - ix1)
+	dy := -abs(iy2 - iy1)
+	sx, sy := 1, 1
+	if ix1 > ix2 {
+		sx = -1
+	}
+	if iy1 > iy2 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		rc.img.SetRGBA(ix1, iy1, c)
+		if ix1 == ix2 && iy1 == iy2 {
+			return
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			ix1 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			iy1 += sy
+		}
+	}
+}
+
+// clip clips a segment to the image with the Liang-Barsky algorithm. It returns false if the
+// segment has a coordinate which is not finite, or lies outside of the image.
+func (rc *rasterCanvas) clip(x1, y1, x2, y2 float64) (float64, float64, float64, float64, bool) {
+	if !finite(x1, y1, x2, y2) {
+		return 0, 0, 0, 0, false
+	}
+
+	bounds := rc.img.Bounds()
+	dx, dy := x2-x1, y2-y1
+	edges := [][2]float64{
+		{-dx, x1 - float64(bounds.Min.X)},
+		{dx, float64(bounds.Max.X-1) - x1},
+		{-dy, y1 - float64(bounds.Min.Y)},
+		{dy, float64(bounds.Max.Y-1) - y1},
+	}
+
+	t0, t1 := 0.0, 1.0
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+
+		if r := q / p; p < 0 {
+			t0 = math.Max(t0, r)
+		} else {
+			t1 = math.Min(t1, r)
+		}
+	}
+
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+
+	x1, y1, x2, y2 = x1+t0*dx, y1+t0*dy, x1+t1*dx, y1+t1*dy
+
+	// the length of a segment between coordinates of a huge magnitude may overflow, leaving its
+	// clipped ends outside of the image
+	inside := func(x, y float64) bool {
+		return finite(x, y) && image.Pt(int(math.Round(x)), int(math.Round(y))).In(bounds)
+	}
+
+	return x1, y1, x2, y2, inside(x1, y1) && inside(x2, y2)
+}
+
+func (rc *rasterCanvas) polyline(xs, ys []float64, c color.RGBA) {
+	for i := 1; i < len(xs); i++ {
+		rc.line(xs[i-1], ys[i-1], xs[i], ys[i], c)
+	}
+}
+
+func (rc *rasterCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	if !finite(x, y, w, h) {
+		return
+	}
+
+	bounds := rc.img.Bounds()
+	// a rectangle outside of the image is clamped to just outside of it, so that it stays empty when
+	// widened to a pixel below
+	left, right := rc.clamp(x, bounds.Min.X-1, bounds.Max.X), rc.clamp(x+w, bounds.Min.X-1, bounds.Max.X)
+	top, bottom := rc.clamp(y, bounds.Min.Y-1, bounds.Max.Y), rc.clamp(y+h, bounds.Min.Y-1, bounds.Max.Y)
+
+	r := image.Rect(left, top, right, bottom)
+	if r.Dx() == 0 {
+		r.Max.X++
+	}
+	if r.Dy() == 0 {
+		r.Max.Y++
+	}
+
+	draw.Draw(rc.img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+func (rc *rasterCanvas) triangle(x1, y1, x2, y2, x3, y3 float64, c color.RGBA) {
+	if !finite(x1, y1, x2, y2, x3, y3) {
+		return
+	}
+
+	bounds := rc.img.Bounds()
+	minX := rc.clamp(math.Floor(math.Min(x1, math.Min(x2, x3))), bounds.Min.X, bounds.Max.X)
+	maxX := rc.clamp(math.Ceil(math.Max(x1, math.Max(x2, x3))), bounds.Min.X-1, bounds.Max.X-1)
+	minY := rc.clamp(math.Floor(math.Min(y1, math.Min(y2, y3))), bounds.Min.Y, bounds.Max.Y)
+	maxY := rc.clamp(math.Ceil(math.Max(y1, math.Max(y2, y3))), bounds.Min.Y-1, bounds.Max.Y-1)
+
+	edge := func(ax, ay, bx, by, px, py float64) float64 {
+		return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+	}
+
+	area := edge(x1, y1, x2, y2, x3, y3)
+	if area == 0 {
+		return
+	}
+
+	for py := minY; py <= maxY; py++ {
+		for px := minX; px <= maxX; px++ {
+			fx, fy := float64(px)+0.5, float64(py)+0.5
+			w1 := edge(x2, y2, x3, y3, fx, fy) / area
+			w2 := edge(x3, y3, x1, y1, fx, fy) / area
+			w3 := edge(x1, y1, x2, y2, fx, fy) / area
+
+			if w1 >= 0 && w2 >= 0 && w3 >= 0 {
+				rc.img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func (rc *rasterCanvas) text(x, y float64, s string, anchor string, c color.RGBA) {}
+
+// clamp rounds a coordinate to a pixel within the given range, which keeps it from overflowing an
+// int
+func (rc *rasterCanvas) clamp(v float64, min, max int) int {
+	return int(math.Round(math.Max(float64(min), math.Min(float64(max), v))))
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
@@ -0,0 +1,493 @@
+// Package chart renders a techan TimeSeries as a candlestick or OHLC chart with indicator
+// overlays, oscillator sub-panes and trade markers. Charts are written as SVG or PNG using only
+// the standard library.
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+
+	"github.com/schmidthole/techan"
+)
+
+// BarStyle defines how the price bars of a chart are drawn.
+type BarStyle int
+
+// BarStyle enumerations
+const (
+	CANDLESTICK BarStyle = iota
+	OHLC
+)
+
+// Default colors used by the chart.
+var (
+	Background = color.RGBA{255, 255, 255, 255}
+	GridColor  = color.RGBA{230, 230, 230, 255}
+	TextColor  = color.RGBA{60, 60, 60, 255}
+	UpColor    = color.RGBA{38, 166, 91, 255}
+	DownColor  = color.RGBA{214, 48, 49, 255}
+	Palette    = []color.RGBA{
+		{31, 119, 180, 255},
+		{255, 127, 14, 255},
+		{148, 103, 189, 255},
+		{23, 190, 207, 255},
+		{188, 189, 34, 255},
+		{140, 86, 75, 255},
+	}
+)
+
+// A Line plots the values of an Indicator. Values before the From index, as well as values which
+// are not finite, are not drawn so that indicator warm-up periods do not distort the scale. If no
+// color is set, one is picked from the Palette. Like all indexes of a chart, From is an index of
+// the series, which only differs from the position on the chart for a bounded series.
+type Line struct {
+	Name      string
+	Indicator techan.Indicator
+	Color     color.RGBA
+	From      int
+}
+
+// A Pane is a sub-chart drawn below the price pane, typically used for oscillators. Guides are
+// horizontal reference lines such as the 30 and 70 levels of an RSI.
+type Pane struct {
+	Name   string
+	Lines  []Line
+	Guides []float64
+}
+
+// A Marker highlights a bar of the chart. Buys are drawn as an upward triangle below the bar and
+// sells as a downward triangle above it.
+type Marker struct {
+	Index int
+	Side  techan.OrderSide
+}
+
+// Chart describes the content and layout of a chart for a TimeSeries.
+type Chart struct {
+	Series     *techan.TimeSeries
+	Style      BarStyle
+	Title      string
+	Width      int
+	Height     int
+	ShowVolume bool
+	Overlays   []Line
+	Panes      []Pane
+	Markers    []Marker
+}
+
+// New returns a candlestick Chart of the series with a volume pane and a default size.
+func New(series *techan.TimeSeries) *Chart {
+	return &Chart{
+		Series:     series,
+		Style:      CANDLESTICK,
+		Width:      1200,
+		Height:     800,
+		ShowVolume: true,
+		Overlays:   []Line{},
+		Panes:      []Pane{},
+		Markers:    []Marker{},
+	}
+}
+
+// AddOverlay draws an Indicator on the price pane.
+func (c *Chart) AddOverlay(line Line) *Chart {
+	c.Overlays = append(c.Overlays, line)
+	return c
+}
+
+// AddPane adds a sub-pane below the price pane.
+func (c *Chart) AddPane(pane Pane) *Chart {
+	c.Panes = append(c.Panes, pane)
+	return c
+}
+
+// AddRuleMarkers adds a marker on every bar where the rule becomes satisfied after not being
+// satisfied on the previous bar.
+func (c *Chart) AddRuleMarkers(rule techan.Rule, side techan.OrderSide) *Chart {
+	last := false
+	for i := c.Series.FirstIndex(); i <= c.Series.LastIndex(); i++ {
+		satisfied := safeIsSatisfied(rule, i)
+		if satisfied && !last {
+			c.Markers = append(c.Markers, Marker{Index: i, Side: side})
+		}
+
+		last = satisfied
+	}
+
+	return c
+}
+
+// AddTradeMarkers adds a marker for every order whose execution time falls within the period of a
+// candle, such as the orders in Account.TradeRecord.
+func (c *Chart) AddTradeMarkers(orders []*techan.Order) *Chart {
+	for _, order := range orders {
+		for i, candle := range c.Series.Candles {
+			start := candle.Period.Start
+			if !order.ExecutionTime.Before(start) && order.ExecutionTime.Before(candle.Period.End) ||
+				order.ExecutionTime.Equal(start) {
+				c.Markers = append(c.Markers, Marker{Index: c.Series.FirstIndex() + i, Side: order.Side})
+				break
+			}
+		}
+	}
+
+	return c
+}
+
+// WriteSVG renders the chart as an SVG document.
+func (c *Chart) WriteSVG(w io.Writer) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	sc := newSvgCanvas(c.Width, c.Height, Background)
+	c.render(sc)
+
+	return sc.writeTo(w)
+}
+
+// WritePNG renders the chart as a PNG image. The image has no text, since no font is available
+// without external dependencies: the title, axis labels, pane names and legends are only included
+// in SVG output.
+func (c *Chart) WritePNG(w io.Writer) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	rc := newRasterCanvas(c.Width, c.Height, Background)
+	c.render(rc)
+
+	return png.Encode(w, rc.img)
+}
+
+// SaveSVG renders the chart as an SVG document to a file.
+func (c *Chart) SaveSVG(filepath string) error {
+	return c.save(filepath, c.WriteSVG)
+}
+
+// SavePNG renders the chart as a PNG image to a file.
+func (c *Chart) SavePNG(filepath string) error {
+	return c.save(filepath, c.WritePNG)
+}
+
+func (c *Chart) save(filepath string, write func(io.Writer) error) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return write(file)
+}
+
+func (c *Chart) validate() error {
+	if c.Series == nil || len(c.Series.Candles) == 0 {
+		return fmt.Errorf("cannot chart an empty timeseries")
+	}
+
+	if c.Width <= 0 || c.Height <= 0 {
+		return fmt.Errorf("invalid chart size %vx%v", c.Width, c.Height)
+	}
+
+	return nil
+}
+
+const (
+	marginLeft   = 10.0
+	marginRight  = 70.0
+	marginTop    = 30.0
+	marginBottom = 30.0
+	paneGap      = 12.0
+)
+
+// pane is the pixel area a section of the chart is drawn in, along with its value range.
+type pane struct {
+	top    float64
+	height float64
+	min    float64
+	max    float64
+}
+
+func (p pane) y(value float64) float64 {
+	if p.max == p.min {
+		return p.top + p.height/2
+	}
+
+	return p.top + (p.max-value)/(p.max-p.min)*p.height
+}
+
+func (c *Chart) render(cv canvas) {
+	count := len(c.Series.Candles)
+	plotWidth := float64(c.Width) - marginLeft - marginRight
+	barWidth := plotWidth / float64(count)
+
+	x := func(i int) float64 {
+		return marginLeft + (float64(i)+0.5)*barWidth
+	}
+
+	weights := []float64{3.0}
+	if c.ShowVolume {
+		weights = append(weights, 0.75)
+	}
+	for range c.Panes {
+		weights = append(weights, 1.0)
+	}
+
+	totalWeight := 0.0
+	for _, w := range weights {
+		totalWeight += w
+	}
+
+	available := float64(c.Height) - marginTop - marginBottom - paneGap*float64(len(weights)-1)
+	panes := make([]pane, len(weights))
+	top := marginTop
+	for i, w := range weights {
+		panes[i] = pane{top: top, height: available * w / totalWeight}
+		top += panes[i].height + paneGap
+	}
+
+	if c.Title != "" {
+		cv.text(marginLeft, marginTop-10, c.Title, "start", TextColor)
+	}
+
+	price := &panes[0]
+	price.min, price.max = math.Inf(1), math.Inf(-1)
+	for _, candle := range c.Series.Candles {
+		price.min = math.Min(price.min, candle.MinPrice.Float())
+		price.max = math.Max(price.max, candle.MaxPrice.Float())
+	}
+	overlays := c.lineValues(c.Overlays)
+	for _, values := range overlays {
+		extendRange(price, values)
+	}
+	padRange(price)
+
+	c.drawGrid(cv, *price, plotWidth)
+	c.drawBars(cv, *price, x, barWidth)
+	c.drawLines(cv, *price, x, c.Overlays, overlays, 0)
+	c.drawMarkers(cv, *price, x, barWidth)
+
+	next := 1
+	if c.ShowVolume {
+		volume := &panes[next]
+		volume.min, volume.max = 0.0, 0.0
+		for _, candle := range c.Series.Candles {
+			volume.max = math.Max(volume.max, candle.Volume.Float())
+		}
+
+		c.drawGrid(cv, *volume, plotWidth)
+		for i, candle := range c.Series.Candles {
+			barColor := UpColor
+			if candle.ClosePrice.LT(candle.OpenPrice) {
+				barColor = DownColor
+			}
+
+			y := volume.y(candle.Volume.Float())
+			cv.rect(x(i)-barWidth*0.35, y, barWidth*0.7, volume.top+volume.height-y, barColor)
+		}
+		next++
+	}
+
+	paletteOffset := len(c.Overlays)
+	for i, p := range c.Panes {
+		sub := &panes[next+i]
+		sub.min, sub.max = math.Inf(1), math.Inf(-1)
+
+		values := c.lineValues(p.Lines)
+		for _, v := range values {
+			extendRange(sub, v)
+		}
+		for _, g := range p.Guides {
+			sub.min = math.Min(sub.min, g)
+			sub.max = math.Max(sub.max, g)
+		}
+		padRange(sub)
+
+		c.drawGrid(cv, *sub, plotWidth)
+		for _, g := range p.Guides {
+			cv.line(marginLeft, sub.y(g), marginLeft+plotWidth, sub.y(g), TextColor)
+		}
+		c.drawLines(cv, *sub, x, p.Lines, values, paletteOffset)
+		paletteOffset += len(p.Lines)
+
+		if p.Name != "" {
+			cv.text(marginLeft+4, sub.top+12, p.Name, "start", TextColor)
+		}
+	}
+
+	layout := techan.SimpleDateFormatV2
+	cv.text(marginLeft, float64(c.Height)-marginBottom+16, c.Series.Candles[0].Period.Start.Format(layout), "start", TextColor)
+	cv.text(marginLeft+plotWidth, float64(c.Height)-marginBottom+16,
+		c.Series.LastCandle().Period.Start.Format(layout), "end", TextColor)
+}
+
+func (c *Chart) drawGrid(cv canvas, p pane, plotWidth float64) {
+	ticks := 4
+	for t := 0; t <= ticks; t++ {
+		value := p.min + (p.max-p.min)*float64(t)/float64(ticks)
+		y := p.y(value)
+
+		cv.line(marginLeft, y, marginLeft+plotWidth, y, GridColor)
+		cv.text(marginLeft+plotWidth+6, y+4, formatValue(value), "start", TextColor)
+	}
+}
+
+func (c *Chart) drawBars(cv canvas, p pane, x func(int) float64, barWidth float64) {
+	for i, candle := range c.Series.Candles {
+		open := candle.OpenPrice.Float()
+		closePrice := candle.ClosePrice.Float()
+
+		barColor := UpColor
+		if closePrice < open {
+			barColor = DownColor
+		}
+
+		cx := x(i)
+		cv.line(cx, p.y(candle.MaxPrice.Float()), cx, p.y(candle.MinPrice.Float()), barColor)
+
+		if c.Style == OHLC {
+			tick := barWidth * 0.35
+			cv.line(cx-tick, p.y(open), cx, p.y(open), barColor)
+			cv.line(cx, p.y(closePrice), cx+tick, p.y(closePrice), barColor)
+			continue
+		}
+
+		top := p.y(math.Max(open, closePrice))
+		height := p.y(math.Min(open, closePrice)) - top
+		cv.rect(cx-barWidth*0.35, top, barWidth*0.7, math.Max(height, 1.0), barColor)
+	}
+}
+
+func (c *Chart) drawLines(cv canvas, p pane, x func(int) float64, lines []Line, values [][]float64, paletteOffset int) {
+	for l, line := range lines {
+		lineColor := line.Color
+		if lineColor.A == 0 {
+			lineColor = Palette[(paletteOffset+l)%len(Palette)]
+		}
+
+		xs := []float64{}
+		ys := []float64{}
+		flush := func() {
+			if len(xs) > 1 {
+				cv.polyline(xs, ys, lineColor)
+			}
+			xs, ys = []float64{}, []float64{}
+		}
+
+		for i, v := range values[l] {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				flush()
+				continue
+			}
+
+			xs = append(xs, x(i))
+			ys = append(ys, p.y(v))
+		}
+		flush()
+
+		if line.Name != "" {
+			cv.text(marginLeft+4+float64(l)*120, p.top+p.height-6, line.Name, "start", lineColor)
+		}
+	}
+}
+
+func (c *Chart) drawMarkers(cv canvas, p pane, x func(int) float64, barWidth float64) {
+	size := math.Max(math.Min(barWidth, 12.0), 5.0)
+
+	for _, m := range c.Markers {
+		if !c.Series.HasIndex(m.Index) {
+			continue
+		}
+
+		candle := c.Series.Candle(m.Index)
+		cx := x(m.Index - c.Series.FirstIndex())
+
+		if m.Side == techan.SELL {
+			y := p.y(candle.MaxPrice.Float()) - 4
+			cv.triangle(cx-size/2, y-size, cx+size/2, y-size, cx, y, DownColor)
+		} else {
+			y := p.y(candle.MinPrice.Float()) + 4
+			cv.triangle(cx-size/2, y+size, cx+size/2, y+size, cx, y, UpColor)
+		}
+	}
+}
+
+// lineValues computes the values of every line, replacing the indexes that should not be drawn
+// with NaN. Indicators which panic, such as when their data is past the end or evicted from a
+// bounded series, are treated the same way.
+func (c *Chart) lineValues(lines []Line) [][]float64 {
+	values := make([][]float64, len(lines))
+
+	for l, line := range lines {
+		values[l] = make([]float64, len(c.Series.Candles))
+		for i := range values[l] {
+			index := c.Series.FirstIndex() + i
+			if index < line.From {
+				values[l][i] = math.NaN()
+				continue
+			}
+
+			values[l][i] = safeCalculate(line.Indicator, index)
+		}
+	}
+
+	return values
+}
+
+func safeIsSatisfied(rule techan.Rule, index int) (satisfied bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			satisfied = false
+		}
+	}()
+
+	return rule.IsSatisfied(index)
+}
+
+func safeCalculate(indicator techan.Indicator, index int) (value float64) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = math.NaN()
+		}
+	}()
+
+	return indicator.Calculate(index).Float()
+}
+
+func extendRange(p *pane, values []float64) {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		p.min = math.Min(p.min, v)
+		p.max = math.Max(p.max, v)
+	}
+}
+
+func padRange(p *pane) {
+	if math.IsInf(p.min, 0) || math.IsInf(p.max, 0) {
+		p.min, p.max = 0.0, 1.0
+		return
+	}
+
+	padding := (p.max - p.min) * 0.05
+	if padding == 0 {
+		padding = math.Max(math.Abs(p.max)*0.05, 1.0)
+	}
+
+	p.min -= padding
+	p.max += padding
+}
+
+func formatValue(v float64) string {
+	if math.Abs(v) >= 1000.0 {
+		return fmt.Sprintf("%.0f", v)
+	}
+
+	return fmt.Sprintf("%.2f", v)
+}
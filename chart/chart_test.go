@@ -0,0 +1,146 @@
+package chart
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/schmidthole/techan"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSeries(closes ...float64) *techan.TimeSeries {
+	ts := techan.NewTimeSeries()
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, c := range closes {
+		candle := techan.NewCandle(techan.NewTimePeriod(start.AddDate(0, 0, i), time.Hour*24))
+		open := c - 1.0
+		if i%2 == 0 {
+			open = c + 1.0
+		}
+
+		candle.OpenPrice = big.NewDecimal(open)
+		candle.ClosePrice = big.NewDecimal(c)
+		candle.MaxPrice = big.NewDecimal(c + 2.0)
+		candle.MinPrice = big.NewDecimal(c - 2.0)
+		candle.Volume = big.NewDecimal(float64(100 + i))
+
+		ts.AddCandle(candle)
+	}
+
+	return ts
+}
+
+func mockChart() *Chart {
+	series := mockSeries(10, 11, 12, 11, 13, 14, 13, 15, 16, 15, 14, 13)
+	closePrice := techan.NewClosePriceIndicator(series)
+
+	c := New(series)
+	c.Title = "TEST"
+	c.AddOverlay(Line{Name: "SMA 3", Indicator: techan.NewSimpleMovingAverage(closePrice, 3), From: 2})
+	c.AddPane(Pane{
+		Name:   "RSI",
+		Lines:  []Line{{Name: "RSI 3", Indicator: techan.NewRelativeStrengthIndexIndicator(closePrice, 3), From: 3}},
+		Guides: []float64{30, 70},
+	})
+
+	return c
+}
+
+func TestChart_WriteSVG(t *testing.T) {
+	c := mockChart()
+	c.AddRuleMarkers(techan.OverIndicatorRule{
+		First:  techan.NewClosePriceIndicator(c.Series),
+		Second: techan.NewConstantIndicator(12.5),
+	}, techan.BUY)
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.WriteSVG(&buf))
+
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+	assert.Contains(t, svg, "TEST")
+	assert.Contains(t, svg, "RSI 3")
+	assert.Equal(t, 2, strings.Count(svg, "<polyline"))
+	assert.Equal(t, 1, strings.Count(svg, "<polygon"))
+}
+
+func TestChart_WriteSVGOHLC(t *testing.T) {
+	c := mockChart()
+	c.Style = OHLC
+	c.ShowVolume = false
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.WriteSVG(&buf))
+
+	// background only, since ohlc bars are drawn with lines
+	assert.Equal(t, 1, strings.Count(buf.String(), "<rect"))
+}
+
+func TestChart_WritePNG(t *testing.T) {
+	c := mockChart()
+	c.Width = 400
+	c.Height = 300
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.WritePNG(&buf))
+
+	img, err := png.Decode(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+}
+
+func TestRasterCanvas_Clipping(t *testing.T) {
+	rc := newRasterCanvas(20, 10, Background)
+	red := color.RGBA{R: 255, A: 255}
+
+	rc.line(math.NaN(), 1, 5, 1, red)
+	rc.line(0, 1, math.Inf(1), 1, red)
+	rc.rect(math.NaN(), 0, 5, 5, red)
+	rc.triangle(0, 0, math.Inf(-1), 5, 5, 5, red)
+	assert.Equal(t, Background, rc.img.RGBAAt(0, 1))
+
+	// segments and shapes far outside of the image are clipped rather than drawn pixel by pixel
+	rc.line(-1e18, 5, 1e18, 5, red)
+	rc.line(-1e300, -1e300, 1e300, 1e300, red)
+	rc.rect(-1e18, 8, 2e18, 1, red)
+	rc.triangle(-1e18, -1e18, 1e18, -1e18, 0, 1e18, red)
+	rc.line(30, 0, 40, 9, red)
+
+	assert.Equal(t, red, rc.img.RGBAAt(0, 5))
+	assert.Equal(t, red, rc.img.RGBAAt(19, 5))
+	assert.Equal(t, red, rc.img.RGBAAt(19, 8))
+	assert.Equal(t, red, rc.img.RGBAAt(10, 2))
+}
+
+func TestChart_AddTradeMarkers(t *testing.T) {
+	c := mockChart()
+	c.AddTradeMarkers([]*techan.Order{
+		{Side: techan.BUY, ExecutionTime: c.Series.Candles[2].Period.Start},
+		{Side: techan.SELL, ExecutionTime: c.Series.Candles[5].Period.Start.Add(time.Hour)},
+		{Side: techan.SELL, ExecutionTime: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	})
+
+	assert.Equal(t, []Marker{{Index: 2, Side: techan.BUY}, {Index: 5, Side: techan.SELL}}, c.Markers)
+}
+
+func TestChart_Save(t *testing.T) {
+	c := mockChart()
+
+	assert.Nil(t, c.SaveSVG("chart.svg"))
+	os.Remove("chart.svg")
+
+	assert.Nil(t, c.SavePNG("chart.png"))
+	os.Remove("chart.png")
+
+	assert.NotNil(t, New(techan.NewTimeSeries()).WriteSVG(&bytes.Buffer{}))
+}
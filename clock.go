@@ -0,0 +1,156 @@
+package techan
+
+import "github.com/schmidthole/big"
+
+// MissingBarPolicy defines how a Clock treats a security which has no bar at a time another
+// security has one, while the security is listed, i.e. between its first and last bar.
+type MissingBarPolicy int
+
+// MissingBarPolicy enumerations. SKIP drops every time at which a listed security is missing a bar.
+// FORWARD_FILL carries the previous bar of the security forward and keeps it tradeable at the
+// previous close. NON_TRADEABLE also carries the previous bar forward for pricing, but marks the
+// security as not tradeable until its next bar.
+const (
+	SKIP MissingBarPolicy = iota
+	FORWARD_FILL
+	NON_TRADEABLE
+)
+
+// A Clock is a master timeline for several TimeSeries. Its ticks are the distinct candle start times
+// across all series, in order, and it maps every tick to the index of the bar each series has at that
+// time. Before its first bar and after its last bar a series is not listed, so securities which list
+// or delist part way through share the clock with the others.
+type Clock struct {
+	Periods []TimePeriod
+	policy  MissingBarPolicy
+	series  []*TimeSeries
+	indexes [][]int
+	own     [][]bool
+}
+
+// NewClock builds the master clock of the given series, applying the policy to missing bars.
+func NewClock(policy MissingBarPolicy, series ...*TimeSeries) *Clock {
+	clock := &Clock{
+		Periods: make([]TimePeriod, 0),
+		policy:  policy,
+		series:  series,
+		indexes: make([][]int, len(series)),
+		own:     make([][]bool, len(series)),
+	}
+
+	positions := make([]int, len(series))
+	indexes := make([]int, len(series))
+	own := make([]bool, len(series))
+
+	for {
+		var next *TimePeriod
+		for s, ts := range series {
+			if positions[s] < len(ts.Candles) {
+				period := ts.Candles[positions[s]].Period
+				if next == nil || period.Start.Before(next.Start) {
+					next = &period
+				}
+			}
+		}
+
+		if next == nil {
+			break
+		}
+
+		missing := false
+		for s, ts := range series {
+			position := positions[s]
+			listed := position > 0 && position < len(ts.Candles)
+
+			if position < len(ts.Candles) && ts.Candles[position].Period.Start.Equal(next.Start) {
+				indexes[s] = ts.FirstIndex() + position
+				own[s] = true
+				positions[s]++
+			} else if listed {
+				indexes[s] = ts.FirstIndex() + position - 1
+				own[s] = false
+				missing = true
+			} else {
+				indexes[s] = -1
+				own[s] = false
+			}
+		}
+
+		if missing && policy == SKIP {
+			continue
+		}
+
+		clock.Periods = append(clock.Periods, *next)
+		for s := range series {
+			clock.indexes[s] = append(clock.indexes[s], indexes[s])
+			clock.own[s] = append(clock.own[s], own[s])
+		}
+	}
+
+	return clock
+}
+
+// Len returns the number of ticks of the clock
+func (c *Clock) Len() int {
+	return len(c.Periods)
+}
+
+// Index returns the index of the bar of a series, given by its position in NewClock, at a tick. If
+// the series is missing a bar at the tick, this is the index of its previous bar. If the series is
+// not listed at the tick, -1 is returned.
+func (c *Clock) Index(series, tick int) int {
+	return c.indexes[series][tick]
+}
+
+// Tradeable returns true if a series, given by its position in NewClock, can be traded at a tick.
+// A series is tradeable if it has a bar at the tick, or if a missing bar is forward filled.
+func (c *Clock) Tradeable(series, tick int) bool {
+	if c.indexes[series][tick] < 0 {
+		return false
+	}
+
+	return c.own[series][tick] || c.policy == FORWARD_FILL
+}
+
+// Align returns a series, given by its position in NewClock, with a candle at every tick of the
+// clock, so that all aligned series share the same indexes and periods. Missing bars are filled
+// with a flat candle at the previous close and no volume. Ticks before the series lists are filled
+// at its first open, and ticks after it delists at its last close.
+func (c *Clock) Align(series int) *TimeSeries {
+	ts := c.series[series]
+	aligned := NewTimeSeries()
+
+	for tick, period := range c.Periods {
+		index := c.indexes[series][tick]
+
+		if c.own[series][tick] {
+			aligned.AddCandle(ts.Candle(index))
+			continue
+		}
+
+		price := big.ZERO
+		if index >= 0 {
+			price = ts.Candle(index).ClosePrice
+		} else if len(ts.Candles) > 0 {
+			if first := ts.Candles[0]; period.Start.Before(first.Period.Start) {
+				price = first.OpenPrice
+			} else {
+				price = ts.LastCandle().ClosePrice
+			}
+		}
+
+		aligned.AddCandle(flatCandle(period, price))
+	}
+
+	return aligned
+}
+
+func flatCandle(period TimePeriod, price big.Decimal) *Candle {
+	candle := NewCandle(period)
+	candle.OpenPrice = price
+	candle.ClosePrice = price
+	candle.MaxPrice = price
+	candle.MinPrice = price
+
+	return candle
+}
@@ -0,0 +1,72 @@
+package techan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockGappedSeries returns a series with a candle at each of the given seconds since the epoch
+func mockGappedSeries(seconds []int, closes ...float64) *TimeSeries {
+	series := mockTimeSeriesFl(closes...)
+	for i, candle := range series.Candles {
+		candle.Period = NewTimePeriod(time.Unix(int64(seconds[i]), 0), time.Second)
+	}
+
+	return series
+}
+
+func TestNewClock(t *testing.T) {
+	// the first series lists throughout, the second lists late and misses a bar, and the third
+	// delists early
+	first := mockGappedSeries([]int{0, 1, 2, 3, 4}, 1, 2, 3, 4, 5)
+	second := mockGappedSeries([]int{1, 3, 4}, 10, 30, 40)
+	third := mockGappedSeries([]int{0, 1}, 100, 200)
+
+	t.Run("forward fill", func(t *testing.T) {
+		clock := NewClock(FORWARD_FILL, first, second, third)
+		assert.Equal(t, 5, clock.Len())
+		assert.Equal(t, first.Candles[2].Period, clock.Periods[2])
+
+		assert.Equal(t, []int{-1, 0, 0, 1, 2}, []int{
+			clock.Index(1, 0), clock.Index(1, 1), clock.Index(1, 2), clock.Index(1, 3), clock.Index(1, 4),
+		})
+		assert.False(t, clock.Tradeable(1, 0))
+		assert.True(t, clock.Tradeable(1, 2))
+		assert.Equal(t, -1, clock.Index(2, 2))
+		assert.False(t, clock.Tradeable(2, 2))
+	})
+
+	t.Run("non tradeable", func(t *testing.T) {
+		clock := NewClock(NON_TRADEABLE, first, second, third)
+		assert.Equal(t, 5, clock.Len())
+		assert.Equal(t, 0, clock.Index(1, 2))
+		assert.False(t, clock.Tradeable(1, 2))
+		assert.True(t, clock.Tradeable(1, 3))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		clock := NewClock(SKIP, first, second, third)
+		assert.Equal(t, 4, clock.Len())
+		assert.Equal(t, first.Candles[3].Period, clock.Periods[2])
+		assert.Equal(t, 1, clock.Index(1, 2))
+	})
+
+	t.Run("align", func(t *testing.T) {
+		clock := NewClock(FORWARD_FILL, first, second, third)
+
+		aligned := clock.Align(1)
+		assert.Equal(t, 5, len(aligned.Candles))
+		assert.Equal(t, clock.Periods, []TimePeriod{
+			aligned.Candles[0].Period, aligned.Candles[1].Period, aligned.Candles[2].Period,
+			aligned.Candles[3].Period, aligned.Candles[4].Period,
+		})
+		decimalEquals(t, 10, aligned.Candles[0].ClosePrice)
+		decimalEquals(t, 10, aligned.Candles[2].ClosePrice)
+		decimalEquals(t, 0, aligned.Candles[2].Volume)
+		decimalEquals(t, 30, aligned.Candles[3].ClosePrice)
+
+		decimalEquals(t, 200, clock.Align(2).Candles[4].ClosePrice)
+	})
+}
@@ -0,0 +1,425 @@
+package techan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// A DataProvider supplies the candles of a security. TimeSeries returns the bars of the given size
+// which start within the period, i.e. at or after its start and before its end, in chronological
+// order. Backtests and live trading can share a single data path by depending on a DataProvider
+// rather than on where the candles come from.
+type DataProvider interface {
+	TimeSeries(security string, period TimePeriod, timeframe Timeframe) (*TimeSeries, error)
+}
+
+// CsvDirectoryProvider is a DataProvider which reads candles from a directory of csv files, for use
+// offline. The candles of a security are read from "<security>_<timeframe>.csv", e.g.
+// "SPY_1d.csv", or from a .tsv file of the same name. If there is no such file, the candles in
+// "<security>.csv" are resampled to the requested timeframe, so a directory of minute bars can serve
+// any larger bar size.
+type CsvDirectoryProvider struct {
+	Dir      string
+	Options  CsvOptions
+	Resample ResampleOptions
+}
+
+// NewCsvDirectoryProvider returns a CsvDirectoryProvider reading files from dir with the given csv
+// options.
+func NewCsvDirectoryProvider(dir string, options CsvOptions) *CsvDirectoryProvider {
+	return &CsvDirectoryProvider{Dir: dir, Options: options}
+}
+
+// TimeSeries reads the candles of a security which start within the period.
+func (p *CsvDirectoryProvider) TimeSeries(security string, period TimePeriod, timeframe Timeframe) (*TimeSeries, error) {
+	name := url.PathEscape(security)
+
+	for _, ext := range []string{".csv", ".tsv"} {
+		path := filepath.Join(p.Dir, fmt.Sprintf("%v_%v%v", name, timeframe, ext))
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		options := p.Options
+		if options.Duration == 0 && timeframe.Unit <= HOURS {
+			options.Duration = timeframe.duration()
+		}
+
+		series, err := LoadTimeSeriesCsv(path, options)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+
+		return seriesWithin(series.Candles, period), nil
+	}
+
+	path := filepath.Join(p.Dir, name+".csv")
+	series, err := LoadTimeSeriesCsv(path, p.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	resampled, err := ResampleTimeSeries(series, timeframe, p.Resample, true)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return seriesWithin(resampled.Candles, period), nil
+}
+
+// CachedProvider is a DataProvider which keeps the candles of an upstream provider in a local file
+// cache. Every security and timeframe is stored in its own csv file, along with the periods already
+// fetched, and only the parts of a requested period which have not been fetched before are
+// requested from the upstream provider.
+//
+// Bars which have not ended yet are returned but never cached, and the cache only records periods
+// as fetched up to the current time, or the start of such a bar, so requests reaching into the
+// future always fetch their most recent part again. This makes the same provider usable for backtests and for live trading.
+//
+// A CachedProvider is safe for concurrent use. Readers of a file share it, and a file is only
+// replaced, atomically, while no other goroutine of the provider reads it.
+type CachedProvider struct {
+	Upstream DataProvider
+	dir      string
+	mutex    sync.Mutex
+	locks    map[string]*sync.RWMutex
+	now      func() time.Time
+}
+
+// NewCachedProvider returns a CachedProvider storing the candles of upstream in dir, which is
+// created if it does not exist.
+func NewCachedProvider(dir string, upstream DataProvider) (*CachedProvider, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &CachedProvider{
+		Upstream: upstream,
+		dir:      dir,
+		locks:    make(map[string]*sync.RWMutex),
+		now:      time.Now,
+	}, nil
+}
+
+// TimeSeries returns the candles of a security which start within the period, fetching the parts
+// of the period missing from the cache from the upstream provider.
+func (cp *CachedProvider) TimeSeries(security string, period TimePeriod, timeframe Timeframe) (*TimeSeries, error) {
+	path := cp.Path(security, timeframe)
+	lock := cp.lock(path)
+
+	lock.RLock()
+	cache, err := readCacheFile(path)
+	lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missingPeriods(cache.fetched, period)) == 0 {
+		return seriesWithin(cache.candles, period), nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// another goroutine may have fetched the period while the lock was released
+	cache, err = readCacheFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	now := cp.now()
+	forming := []*Candle{}
+	updated := false
+
+	for _, missing := range missingPeriods(cache.fetched, period) {
+		fetched, err := cp.Upstream.TimeSeries(security, missing, timeframe)
+		if err != nil {
+			return nil, err
+		}
+
+		candles := seriesWithin(fetched.Candles, missing).Candles
+		if missing.End.After(now) {
+			missing.End = now
+		}
+
+		// the period of a bar which has not ended is fetched again by the next request
+		complete := []*Candle{}
+		for _, candle := range candles {
+			if candle.Period.End.After(now) {
+				if candle.Period.Start.Before(missing.End) {
+					missing.End = candle.Period.Start
+				}
+				forming = append(forming, candle)
+			} else {
+				complete = append(complete, candle)
+			}
+		}
+
+		cache.candles = mergeCandles(cache.candles, complete)
+		if missing.End.After(missing.Start) {
+			cache.fetched = addPeriod(cache.fetched, missing)
+			updated = true
+		}
+	}
+
+	if updated {
+		if err := cache.write(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return seriesWithin(mergeCandles(cache.candles, forming), period), nil
+}
+
+// Path returns the file the candles of a security and timeframe are cached in.
+func (cp *CachedProvider) Path(security string, timeframe Timeframe) string {
+	return filepath.Join(cp.dir, url.PathEscape(security), timeframe.String()+".csv")
+}
+
+func (cp *CachedProvider) lock(path string) *sync.RWMutex {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	lock, ok := cp.locks[path]
+	if !ok {
+		lock = new(sync.RWMutex)
+		cp.locks[path] = lock
+	}
+
+	return lock
+}
+
+// cacheFile is the content of a cache file. Every row is either a fetched period or a candle.
+type cacheFile struct {
+	fetched []TimePeriod
+	candles []*Candle
+}
+
+const (
+	cacheFetched = "fetched"
+	cacheCandle  = "candle"
+)
+
+var cacheCsvHeader = []string{"record", "start", "end", "open", "high", "low", "close", "volume", "trade_count"}
+
+func readCacheFile(path string) (*cacheFile, error) {
+	cache := &cacheFile{fetched: []TimePeriod{}, candles: []*Candle{}}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+
+		if line == 1 || len(row) < 3 {
+			continue
+		}
+
+		period, err := parseCsvPeriod(row[1], row[2])
+		if err != nil {
+			return nil, fmt.Errorf("%v: line %v: %v", path, line, err)
+		}
+
+		if row[0] == cacheFetched {
+			cache.fetched = append(cache.fetched, period)
+			continue
+		}
+
+		if len(row) < len(cacheCsvHeader) {
+			return nil, fmt.Errorf("%v: line %v: missing candle columns", path, line)
+		}
+
+		candle := NewCandle(period)
+		for i, price := range []*big.Decimal{&candle.OpenPrice, &candle.MaxPrice, &candle.MinPrice, &candle.ClosePrice, &candle.Volume} {
+			if *price, err = decodeDecimal(row[3+i]); err != nil {
+				return nil, fmt.Errorf("%v: line %v: %v", path, line, err)
+			}
+		}
+
+		count, err := strconv.ParseUint(row[8], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%v: line %v: invalid trade_count %q", path, line, row[8])
+		}
+		candle.TradeCount = uint(count)
+
+		cache.candles = append(cache.candles, candle)
+	}
+
+	return cache, nil
+}
+
+// write replaces the cache file by renaming a complete temporary file over it, so readers never
+// see a partially written file.
+func (cache *cacheFile) write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	rows := [][]string{cacheCsvHeader}
+	for _, period := range cache.fetched {
+		rows = append(rows, []string{cacheFetched, formatCsvTime(period.Start), formatCsvTime(period.End)})
+	}
+
+	for _, c := range cache.candles {
+		rows = append(rows, []string{
+			cacheCandle,
+			formatCsvTime(c.Period.Start),
+			formatCsvTime(c.Period.End),
+			encodeDecimal(c.OpenPrice),
+			encodeDecimal(c.MaxPrice),
+			encodeDecimal(c.MinPrice),
+			encodeDecimal(c.ClosePrice),
+			encodeDecimal(c.Volume),
+			strconv.FormatUint(uint64(c.TradeCount), 10),
+		})
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// seriesWithin returns a series of the candles which start within the period.
+func seriesWithin(candles []*Candle, period TimePeriod) *TimeSeries {
+	series := NewTimeSeries()
+
+	for _, candle := range candles {
+		if !candle.Period.Start.Before(period.Start) && candle.Period.Start.Before(period.End) {
+			series.AddCandle(candle)
+		}
+	}
+
+	return series
+}
+
+// mergeCandles returns the candles of both slices in chronological order. A candle of added
+// replaces a candle of candles starting at the same time.
+func mergeCandles(candles, added []*Candle) []*Candle {
+	if len(added) == 0 {
+		return candles
+	}
+
+	byStart := make(map[int64]*Candle, len(candles)+len(added))
+	for _, list := range [][]*Candle{candles, added} {
+		for _, candle := range list {
+			byStart[candle.Period.Start.UnixNano()] = candle
+		}
+	}
+
+	merged := make([]*Candle, 0, len(byStart))
+	for _, candle := range byStart {
+		merged = append(merged, candle)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Period.Start.Before(merged[j].Period.Start)
+	})
+
+	return merged
+}
+
+// missingPeriods returns the parts of period not covered by the sorted, non overlapping periods.
+func missingPeriods(covered []TimePeriod, period TimePeriod) []TimePeriod {
+	missing := []TimePeriod{}
+	start := period.Start
+
+	for _, c := range covered {
+		if !start.Before(period.End) {
+			break
+		}
+
+		if !c.End.After(start) {
+			continue
+		}
+
+		if c.Start.After(start) {
+			end := c.Start
+			if end.After(period.End) {
+				end = period.End
+			}
+
+			missing = append(missing, TimePeriod{Start: start, End: end})
+		}
+
+		start = c.End
+	}
+
+	if start.Before(period.End) {
+		missing = append(missing, TimePeriod{Start: start, End: period.End})
+	}
+
+	return missing
+}
+
+// addPeriod adds a period to sorted, non overlapping periods, merging it with any periods it
+// overlaps or touches.
+func addPeriod(periods []TimePeriod, period TimePeriod) []TimePeriod {
+	merged := []TimePeriod{}
+	added := false
+
+	for _, p := range periods {
+		switch {
+		case p.End.Before(period.Start):
+			merged = append(merged, p)
+		case period.End.Before(p.Start):
+			if !added {
+				merged = append(merged, period)
+				added = true
+			}
+			merged = append(merged, p)
+		default:
+			if p.Start.Before(period.Start) {
+				period.Start = p.Start
+			}
+			if p.End.After(period.End) {
+				period.End = p.End
+			}
+		}
+	}
+
+	if !added {
+		merged = append(merged, period)
+	}
+
+	return merged
+}
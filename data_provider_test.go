@@ -0,0 +1,250 @@
+package techan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockProvider struct {
+	series   *TimeSeries
+	requests []TimePeriod
+	mutex    sync.Mutex
+}
+
+func (mp *mockProvider) TimeSeries(security string, period TimePeriod, timeframe Timeframe) (*TimeSeries, error) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	mp.requests = append(mp.requests, period)
+	return seriesWithin(mp.series.Candles, period), nil
+}
+
+func unixPeriod(start, end float64) TimePeriod {
+	return TimePeriod{
+		Start: time.Unix(0, int64(start*float64(time.Second))).UTC(),
+		End:   time.Unix(0, int64(end*float64(time.Second))).UTC(),
+	}
+}
+
+func mockUpstream() *mockProvider {
+	series := mockTimeSeriesOCHL(
+		[]float64{1, 2, 3, 0.5},
+		[]float64{2, 3, 4, 1.5},
+		[]float64{3, 4, 5, 2.5},
+		[]float64{4, 5, 6, 3.5},
+		[]float64{5, 6, 7, 4.5},
+		[]float64{6, 7, 8, 5.5},
+		[]float64{7, 8, 9, 6.5},
+		[]float64{8, 9, 10, 7.5},
+		[]float64{9, 10, 11, 8.5},
+		[]float64{10, 11, 12, 9.5},
+	)
+
+	for i, candle := range series.Candles {
+		candle.TradeCount = uint(i * 10)
+	}
+
+	return &mockProvider{series: series}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "techan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestCsvDirectoryProvider(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	daily := "date,open,high,low,close,volume\n" +
+		"2021-01-04,1,2,0.5,1.5,100\n" +
+		"2021-01-05,1.5,3,1,2.5,200\n" +
+		"2021-01-06,2.5,4,2,3.5,300\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "SPY_1d.csv"), []byte(daily), 0644))
+
+	minutes := "time,open,high,low,close,volume\n" +
+		"2021-01-04T14:30:00Z,1,2,1,2,10\n" +
+		"2021-01-04T14:31:00Z,2,4,2,3,20\n" +
+		"2021-01-04T14:32:00Z,3,3,1,1,30\n" +
+		"2021-01-04T14:33:00Z,1,2,1,2,40\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "QQQ.csv"), []byte(minutes), 0644))
+
+	provider := NewCsvDirectoryProvider(dir, CsvOptions{})
+
+	t.Run("reads the file of the timeframe", func(t *testing.T) {
+		start := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+		series, err := provider.TimeSeries("SPY", NewTimePeriod(start, 7*24*time.Hour), Days(1))
+		assert.Nil(t, err)
+
+		assert.EqualValues(t, 2, len(series.Candles))
+		assert.EqualValues(t, start, series.Candles[0].Period.Start)
+		assert.EqualValues(t, 24*time.Hour, series.Candles[0].Period.Length())
+		decimalEquals(t, 3.5, series.Candles[1].ClosePrice)
+	})
+
+	t.Run("resamples the security's file", func(t *testing.T) {
+		start := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+		series, err := provider.TimeSeries("QQQ", NewTimePeriod(start, 24*time.Hour), Minutes(2))
+		assert.Nil(t, err)
+
+		assert.EqualValues(t, 2, len(series.Candles))
+		decimalEquals(t, 1, series.Candles[0].OpenPrice)
+		decimalEquals(t, 4, series.Candles[0].MaxPrice)
+		decimalEquals(t, 3, series.Candles[0].ClosePrice)
+		decimalEquals(t, 70, series.Candles[1].Volume)
+	})
+
+	t.Run("missing security", func(t *testing.T) {
+		_, err := provider.TimeSeries("DIA", unixPeriod(0, 10), Days(1))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCachedProvider(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	upstream := mockUpstream()
+
+	provider, err := NewCachedProvider(dir, upstream)
+	assert.Nil(t, err)
+	provider.now = func() time.Time { return time.Unix(100, 0) }
+
+	series, err := provider.TimeSeries("SPY", unixPeriod(2, 5), Seconds(1))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, len(series.Candles))
+	assert.EqualValues(t, []TimePeriod{unixPeriod(2, 5)}, upstream.requests)
+
+	t.Run("fetches only missing periods", func(t *testing.T) {
+		upstream.requests = nil
+
+		series, err := provider.TimeSeries("SPY", unixPeriod(0, 8), Seconds(1))
+		assert.Nil(t, err)
+		assert.EqualValues(t, 8, len(series.Candles))
+		assert.EqualValues(t, []TimePeriod{unixPeriod(0, 2), unixPeriod(5, 8)}, upstream.requests)
+
+		for i, candle := range series.Candles {
+			assert.EqualValues(t, upstream.series.Candles[i].Period.Start.Unix(), candle.Period.Start.Unix())
+		}
+	})
+
+	t.Run("serves cached periods", func(t *testing.T) {
+		upstream.requests = nil
+
+		series, err := provider.TimeSeries("SPY", unixPeriod(1, 7), Seconds(1))
+		assert.Nil(t, err)
+		assert.EqualValues(t, 6, len(series.Candles))
+		assert.Empty(t, upstream.requests)
+	})
+
+	t.Run("persists the cache", func(t *testing.T) {
+		upstream.requests = nil
+
+		reopened, err := NewCachedProvider(dir, upstream)
+		assert.Nil(t, err)
+
+		series, err := reopened.TimeSeries("SPY", unixPeriod(0, 8), Seconds(1))
+		assert.Nil(t, err)
+		assert.Empty(t, upstream.requests)
+
+		for i, candle := range series.Candles {
+			expected := upstream.series.Candles[i]
+			assert.True(t, expected.Period.Start.Equal(candle.Period.Start))
+			assert.True(t, expected.Period.End.Equal(candle.Period.End))
+			assert.EqualValues(t, expected.OpenPrice.String(), candle.OpenPrice.String())
+			assert.EqualValues(t, expected.ClosePrice.String(), candle.ClosePrice.String())
+			assert.EqualValues(t, expected.MaxPrice.String(), candle.MaxPrice.String())
+			assert.EqualValues(t, expected.MinPrice.String(), candle.MinPrice.String())
+			assert.EqualValues(t, expected.Volume.String(), candle.Volume.String())
+			assert.EqualValues(t, expected.TradeCount, candle.TradeCount)
+		}
+	})
+
+	t.Run("timeframes are cached separately", func(t *testing.T) {
+		upstream.requests = nil
+
+		_, err := provider.TimeSeries("SPY", unixPeriod(0, 8), Seconds(2))
+		assert.Nil(t, err)
+		assert.EqualValues(t, []TimePeriod{unixPeriod(0, 8)}, upstream.requests)
+		assert.NotEqual(t, provider.Path("SPY", Seconds(1)), provider.Path("SPY", Seconds(2)))
+	})
+}
+
+func TestCachedProvider_FormingBars(t *testing.T) {
+	upstream := mockUpstream()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	provider, err := NewCachedProvider(dir, upstream)
+	assert.Nil(t, err)
+	provider.now = func() time.Time { return time.Unix(5, int64(500*time.Millisecond)) }
+
+	series, err := provider.TimeSeries("SPY", unixPeriod(0, 10), Seconds(1))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, len(series.Candles))
+
+	upstream.requests = nil
+	series, err = provider.TimeSeries("SPY", unixPeriod(0, 10), Seconds(1))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, len(series.Candles))
+	assert.EqualValues(t, []TimePeriod{unixPeriod(5, 10)}, upstream.requests)
+
+	cache, err := readCacheFile(provider.Path("SPY", Seconds(1)))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 5, len(cache.candles))
+}
+
+func TestCachedProvider_ConcurrentReaders(t *testing.T) {
+	upstream := mockUpstream()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	provider, err := NewCachedProvider(dir, upstream)
+	assert.Nil(t, err)
+	provider.now = func() time.Time { return time.Unix(100, 0) }
+
+	var wg sync.WaitGroup
+	lengths := make([]int, 8)
+
+	for i := range lengths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			series, err := provider.TimeSeries("SPY", unixPeriod(0, 10), Seconds(1))
+			if err == nil {
+				lengths[i] = len(series.Candles)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 1, len(upstream.requests))
+	for _, length := range lengths {
+		assert.EqualValues(t, 10, length)
+	}
+}
+
+func TestMissingPeriods(t *testing.T) {
+	covered := []TimePeriod{unixPeriod(2, 4), unixPeriod(6, 8)}
+
+	assert.EqualValues(t, []TimePeriod{unixPeriod(0, 2), unixPeriod(4, 6), unixPeriod(8, 10)}, missingPeriods(covered, unixPeriod(0, 10)))
+	assert.EqualValues(t, []TimePeriod{unixPeriod(4, 5)}, missingPeriods(covered, unixPeriod(3, 5)))
+	assert.Empty(t, missingPeriods(covered, unixPeriod(6, 8)))
+
+	assert.EqualValues(t, []TimePeriod{unixPeriod(1, 4), unixPeriod(6, 8)}, addPeriod(covered, unixPeriod(1, 2)))
+	assert.EqualValues(t, []TimePeriod{unixPeriod(2, 8)}, addPeriod(covered, unixPeriod(3, 7)))
+	assert.EqualValues(t, []TimePeriod{unixPeriod(2, 4), unixPeriod(5, 5.5), unixPeriod(6, 8)}, addPeriod(covered, unixPeriod(5, 5.5)))
+}
@@ -0,0 +1,463 @@
+package techan
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/schmidthole/big"
+)
+
+// FloatSeries holds the candles of a TimeSeries as float64 arrays, for calculating indicators with
+// the float64 engine. Offset is the absolute index of the first values, the first index of the
+// series they were taken from.
+type FloatSeries struct {
+	Open   []float64
+	High   []float64
+	Low    []float64
+	Close  []float64
+	Volume []float64
+	Offset int
+}
+
+// NewFloatSeries converts the candles of a TimeSeries to a FloatSeries
+func NewFloatSeries(series *TimeSeries) *FloatSeries {
+	size := len(series.Candles)
+	fs := &FloatSeries{
+		Open:   make([]float64, size),
+		High:   make([]float64, size),
+		Low:    make([]float64, size),
+		Close:  make([]float64, size),
+		Volume: make([]float64, size),
+		Offset: series.FirstIndex(),
+	}
+
+	for i, candle := range series.Candles {
+		fs.Open[i] = candle.OpenPrice.Float()
+		fs.High[i] = candle.MaxPrice.Float()
+		fs.Low[i] = candle.MinPrice.Float()
+		fs.Close[i] = candle.ClosePrice.Float()
+		fs.Volume[i] = candle.Volume.Float()
+	}
+
+	return fs
+}
+
+// A FloatIndicator calculates the values of an indicator for every candle of a FloatSeries at once
+// using float64 arithmetic, which is much faster than calculating decimals one index at a time and
+// suited to research over many securities and parameters.
+//
+// The float indicators follow the definitions of the decimal indicators, including the zeros
+// returned before a full window, and agree with them to within FloatTolerance. Moving sums are kept
+// as compensated running sums, which round differently from the running sums of the decimal
+// indicators, and this is where most of the difference comes from.
+type FloatIndicator func(series *FloatSeries) []float64
+
+// FloatTolerance is the largest difference between the values of a FloatIndicator and the
+// corresponding decimal Indicator, relative to the magnitude of the value, or absolute for values
+// of a magnitude below one.
+const FloatTolerance = 1e-9
+
+// FloatOpen is a FloatIndicator of the open prices of a series
+func FloatOpen(series *FloatSeries) []float64 { return series.Open }
+
+// FloatHigh is a FloatIndicator of the high prices of a series
+func FloatHigh(series *FloatSeries) []float64 { return series.High }
+
+// FloatLow is a FloatIndicator of the low prices of a series
+func FloatLow(series *FloatSeries) []float64 { return series.Low }
+
+// FloatClose is a FloatIndicator of the close prices of a series
+func FloatClose(series *FloatSeries) []float64 { return series.Close }
+
+// FloatVolume is a FloatIndicator of the volumes of a series
+func FloatVolume(series *FloatSeries) []float64 { return series.Volume }
+
+// FloatSMA returns a FloatIndicator of the simple moving average of an indicator. See
+// NewSimpleMovingAverage.
+func FloatSMA(indicator FloatIndicator, window int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		return smaValues(indicator(series), window)
+	}
+}
+
+// FloatEMA returns a FloatIndicator of the exponential moving average of an indicator. See
+// NewEMAIndicator.
+func FloatEMA(indicator FloatIndicator, window int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		return emaValues(indicator(series), window)
+	}
+}
+
+// FloatMMA returns a FloatIndicator of the modified moving average of an indicator. See
+// NewMMAIndicator.
+func FloatMMA(indicator FloatIndicator, window int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		return mmaValues(indicator(series), window)
+	}
+}
+
+// FloatRSI returns a FloatIndicator of the relative strength index of an indicator. See
+// NewRelativeStrengthIndexIndicator.
+func FloatRSI(indicator FloatIndicator, timeframe int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		values := indicator(series)
+		gains, losses := make([]float64, len(values)), make([]float64, len(values))
+
+		for i := 1; i < len(values); i++ {
+			if delta := values[i] - values[i-1]; delta > 0 {
+				gains[i] = delta
+			}
+
+			if delta := (values[i] - values[i-1]) * -1; delta > 0 {
+				losses[i] = delta
+			}
+		}
+
+		avgGains, avgLosses := mmaValues(gains, timeframe), mmaValues(losses, timeframe)
+		rsi := make([]float64, len(values))
+
+		for i := range rsi {
+			relativeStrength := 0.0
+			if i >= timeframe-1 {
+				if avgLosses[i] == 0 {
+					relativeStrength = math.Inf(1)
+				} else {
+					relativeStrength = avgGains[i] / avgLosses[i]
+				}
+			}
+
+			rsi[i] = 100 - 100/(1+relativeStrength)
+		}
+
+		return rsi
+	}
+}
+
+// FloatATR returns a FloatIndicator of the average true range of a series. See
+// NewAverageTrueRangeIndicator.
+func FloatATR(window int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		ranges := make([]float64, len(series.Close))
+		for i := 1; i < len(ranges); i++ {
+			previousClose := series.Close[i-1]
+			ranges[i] = math.Max(series.High[i], previousClose) - math.Min(series.Low[i], previousClose)
+		}
+
+		sums := windowSums(ranges, window)
+		atr := make([]float64, len(ranges))
+
+		for i := window; i < len(atr); i++ {
+			atr[i] = sums[i] / float64(window)
+		}
+
+		return atr
+	}
+}
+
+// FloatMACD returns a FloatIndicator of the difference between the exponential moving averages of
+// an indicator over a short and a long window. See NewMACDIndicator.
+func FloatMACD(indicator FloatIndicator, shortwindow, longwindow int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		values := indicator(series)
+		short, long := emaValues(values, shortwindow), emaValues(values, longwindow)
+
+		for i := range short {
+			short[i] -= long[i]
+		}
+
+		return short
+	}
+}
+
+// FloatMACDHistogram returns a FloatIndicator of a MACD minus its exponential moving average over
+// the signal line window. See NewMACDHistogramIndicator.
+func FloatMACDHistogram(macd FloatIndicator, signalLinewindow int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		values := macd(series)
+		signal := emaValues(values, signalLinewindow)
+
+		histogram := make([]float64, len(values))
+		for i := range histogram {
+			histogram[i] = values[i] - signal[i]
+		}
+
+		return histogram
+	}
+}
+
+// FloatBollingerUpperBand returns a FloatIndicator of the upper bound of a bollinger band on an
+// indicator. See NewBollingerUpperBandIndicator.
+func FloatBollingerUpperBand(indicator FloatIndicator, window int, sigma float64) FloatIndicator {
+	return floatBollingerBand(indicator, window, sigma)
+}
+
+// FloatBollingerLowerBand returns a FloatIndicator of the lower bound of a bollinger band on an
+// indicator. See NewBollingerLowerBandIndicator.
+func FloatBollingerLowerBand(indicator FloatIndicator, window int, sigma float64) FloatIndicator {
+	return floatBollingerBand(indicator, window, -sigma)
+}
+
+func floatBollingerBand(indicator FloatIndicator, window int, muladd float64) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		values := indicator(series)
+		averages := smaValues(values, window)
+		bands := make([]float64, len(values))
+
+		for i := range bands {
+			// the deviations from the average are summed over each window, which avoids the
+			// cancellation of running sums of squares
+			start := Max(0, i-window+1)
+			variance := 0.0
+			for j := start; j <= i; j++ {
+				deviation := values[j] - averages[i]
+				variance += deviation * deviation
+			}
+
+			bands[i] = averages[i] + math.Sqrt(variance/float64(i-start+1))*muladd
+		}
+
+		return bands
+	}
+}
+
+// FloatFastStochastic returns a FloatIndicator of the fast stochastic indicator (%K) of a series.
+// See NewFastStochasticIndicator.
+func FloatFastStochastic(timeframe int) FloatIndicator {
+	return func(series *FloatSeries) []float64 {
+		lows := windowExtremes(series.Low, timeframe, func(a, b float64) bool { return a < b })
+		highs := windowExtremes(series.High, timeframe, func(a, b float64) bool { return a > b })
+		k := make([]float64, len(series.Close))
+
+		for i := range k {
+			if lows[i] == highs[i] {
+				k[i] = math.Inf(1)
+			} else {
+				k[i] = (series.Close[i] - lows[i]) / (highs[i] - lows[i]) * 100
+			}
+		}
+
+		return k
+	}
+}
+
+// FloatSlowStochastic returns a FloatIndicator of the slow stochastic indicator (%D), the simple
+// moving average of a fast stochastic indicator. See NewSlowStochasticIndicator.
+func FloatSlowStochastic(k FloatIndicator, window int) FloatIndicator {
+	return FloatSMA(k, window)
+}
+
+// A FloatEngine calculates float indicators for many series in parallel.
+type FloatEngine struct {
+	workers int
+}
+
+// NewFloatEngine returns a FloatEngine which calculates on the given number of goroutines, or one
+// per CPU if workers is not positive.
+func NewFloatEngine(workers int) *FloatEngine {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &FloatEngine{workers: workers}
+}
+
+// Calculate returns the values of every indicator for every series, in the order of the series and
+// by the names of the indicators.
+func (fe *FloatEngine) Calculate(series []*FloatSeries, indicators map[string]FloatIndicator) []map[string][]float64 {
+	type job struct {
+		series int
+		name   string
+	}
+
+	jobs := make(chan job)
+	results := make([]map[string][]float64, len(series))
+	var mutex sync.Mutex
+
+	for i := range results {
+		results[i] = make(map[string][]float64, len(indicators))
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < fe.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				values := indicators[j.name](series[j.series])
+
+				mutex.Lock()
+				results[j.series][j.name] = values
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := range series {
+		for name := range indicators {
+			jobs <- job{i, name}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+type floatValuesIndicator struct {
+	values   []float64
+	offset   int
+	lookback int
+}
+
+// NewFloatValuesIndicator returns an Indicator of the values of a FloatIndicator calculated for a
+// series, so that they can be used in rules and strategies like any other indicator. Indexes are
+// absolute, as for the TimeSeries the FloatSeries was taken from.
+//
+// Lookback is the number of leading values which are still warming up, such as the zeros before a
+// full window, and is the Lookback of the corresponding decimal indicator. Those values are not
+// valid, so rules and backtests on them wait for the indicator to warm up.
+func NewFloatValuesIndicator(series *FloatSeries, values []float64, lookback int) Indicator {
+	return floatValuesIndicator{
+		values:   values,
+		offset:   series.Offset,
+		lookback: lookback,
+	}
+}
+
+func (fvi floatValuesIndicator) Calculate(index int) big.Decimal {
+	if index < fvi.offset {
+		panic(EvictedIndexError{Index: index, FirstIndex: fvi.offset})
+	}
+
+	return big.NewDecimal(fvi.values[index-fvi.offset])
+}
+
+func (fvi floatValuesIndicator) FirstIndex() int {
+	return fvi.offset
+}
+
+func (fvi floatValuesIndicator) Lookback() int {
+	return fvi.lookback
+}
+
+func (fvi floatValuesIndicator) IsValid(index int) bool {
+	return index >= fvi.offset+fvi.lookback && index < fvi.offset+len(fvi.values)
+}
+
+func smaValues(values []float64, window int) []float64 {
+	sums := windowSums(values, window)
+	averages := make([]float64, len(values))
+
+	for i := window - 1; i < len(averages); i++ {
+		averages[i] = sums[i] / float64(window)
+	}
+
+	return averages
+}
+
+func emaValues(values []float64, window int) []float64 {
+	alpha := 2 / float64(window+1)
+
+	return smoothedValues(values, window, func(previous, value float64) float64 {
+		return value*alpha + previous*(1-alpha)
+	})
+}
+
+func mmaValues(values []float64, window int) []float64 {
+	factor := 1.0 / float64(window)
+
+	return smoothedValues(values, window, func(previous, value float64) float64 {
+		return previous + factor*(value-previous)
+	})
+}
+
+// smoothedValues calculates a recursive moving average, seeded with the simple moving average of
+// its first window
+func smoothedValues(values []float64, window int, smooth func(previous, value float64) float64) []float64 {
+	smoothed := make([]float64, len(values))
+	if window <= 0 || len(values) < window {
+		return smoothed
+	}
+
+	seed := 0.0
+	for i := window - 1; i >= 0; i-- {
+		seed += values[i]
+	}
+	smoothed[window-1] = seed / float64(window)
+
+	for i := window; i < len(values); i++ {
+		smoothed[i] = smooth(smoothed[i-1], values[i])
+	}
+
+	return smoothed
+}
+
+// windowSums returns the sums of the values of each full window, kept as a compensated running
+// sum. Infinite and NaN values are counted apart, so that they only affect the windows they are in.
+func windowSums(values []float64, window int) []float64 {
+	sums := make([]float64, len(values))
+	if window <= 0 {
+		return sums
+	}
+
+	var sum, compensation float64
+	var positive, negative, nan int
+
+	add := func(value float64, sign float64) {
+		switch {
+		case math.IsNaN(value):
+			nan += int(sign)
+		case math.IsInf(value, 1):
+			positive += int(sign)
+		case math.IsInf(value, -1):
+			negative += int(sign)
+		default:
+			// neumaier's variant of kahan summation
+			value *= sign
+			total := sum + value
+			if math.Abs(sum) >= math.Abs(value) {
+				compensation += (sum - total) + value
+			} else {
+				compensation += (value - total) + sum
+			}
+			sum = total
+		}
+	}
+
+	for i, value := range values {
+		add(value, 1)
+		if i >= window {
+			add(values[i-window], -1)
+		}
+
+		switch {
+		case nan > 0 || (positive > 0 && negative > 0):
+			sums[i] = math.NaN()
+		case positive > 0:
+			sums[i] = math.Inf(1)
+		case negative > 0:
+			sums[i] = math.Inf(-1)
+		default:
+			sums[i] = sum + compensation
+		}
+	}
+
+	return sums
+}
+
+// windowExtremes returns the extreme value of each window of up to the given size, as chosen by
+// better, using a monotonic queue of the indexes of candidate values
+func windowExtremes(values []float64, window int, better func(a, b float64) bool) []float64 {
+	extremes := make([]float64, len(values))
+	queue := newExtremeQueue(func(a, b int) bool { return better(values[a], values[b]) })
+
+	for i := range values {
+		queue.push(i, window)
+		extremes[i] = values[queue.first()]
+	}
+
+	return extremes
+}
@@ -0,0 +1,183 @@
+package techan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// floatCases pairs every float indicator with the decimal indicator it follows
+var floatCases = map[string]struct {
+	float   FloatIndicator
+	decimal func(series *TimeSeries) Indicator
+}{
+	"sma": {
+		FloatSMA(FloatClose, 20),
+		func(series *TimeSeries) Indicator { return NewSimpleMovingAverage(NewClosePriceIndicator(series), 20) },
+	},
+	"ema": {
+		FloatEMA(FloatClose, 10),
+		func(series *TimeSeries) Indicator { return NewEMAIndicator(NewClosePriceIndicator(series), 10) },
+	},
+	"mma": {
+		FloatMMA(FloatVolume, 10),
+		func(series *TimeSeries) Indicator { return NewMMAIndicator(NewVolumeIndicator(series), 10) },
+	},
+	"rsi": {
+		FloatRSI(FloatClose, 14),
+		func(series *TimeSeries) Indicator {
+			return NewRelativeStrengthIndexIndicator(NewClosePriceIndicator(series), 14)
+		},
+	},
+	"atr": {
+		FloatATR(14),
+		func(series *TimeSeries) Indicator { return NewAverageTrueRangeIndicator(series, 14) },
+	},
+	"macd": {
+		FloatMACD(FloatClose, 12, 26),
+		func(series *TimeSeries) Indicator { return NewMACDIndicator(NewClosePriceIndicator(series), 12, 26) },
+	},
+	"macd histogram": {
+		FloatMACDHistogram(FloatMACD(FloatClose, 12, 26), 9),
+		func(series *TimeSeries) Indicator {
+			return NewMACDHistogramIndicator(NewMACDIndicator(NewClosePriceIndicator(series), 12, 26), 9)
+		},
+	},
+	"bollinger upper": {
+		FloatBollingerUpperBand(FloatClose, 20, 2),
+		func(series *TimeSeries) Indicator {
+			return NewBollingerUpperBandIndicator(NewClosePriceIndicator(series), 20, 2)
+		},
+	},
+	"bollinger lower": {
+		FloatBollingerLowerBand(FloatClose, 20, 2),
+		func(series *TimeSeries) Indicator {
+			return NewBollingerLowerBandIndicator(NewClosePriceIndicator(series), 20, 2)
+		},
+	},
+	"fast stochastic": {
+		FloatFastStochastic(14),
+		func(series *TimeSeries) Indicator { return NewFastStochasticIndicator(series, 14) },
+	},
+	"slow stochastic": {
+		FloatSlowStochastic(FloatFastStochastic(14), 3),
+		func(series *TimeSeries) Indicator {
+			return NewSlowStochasticIndicator(NewFastStochasticIndicator(series, 14), 3)
+		},
+	},
+}
+
+func assertWithinFloatTolerance(t *testing.T, expected, actual float64, msgAndArgs ...interface{}) {
+	if math.IsInf(expected, 0) || math.IsNaN(expected) {
+		assert.Equal(t, math.Float64bits(expected), math.Float64bits(actual), msgAndArgs...)
+		return
+	}
+
+	assert.InDelta(t, expected, actual, FloatTolerance*math.Max(1, math.Abs(expected)), msgAndArgs...)
+}
+
+func TestFloatIndicators(t *testing.T) {
+	series := randomTimeSeries(500)
+	floatSeries := NewFloatSeries(series)
+
+	for name, test := range floatCases {
+		t.Run(name, func(t *testing.T) {
+			decimal := test.decimal(series)
+			values := test.float(floatSeries)
+			assert.Len(t, values, len(series.Candles))
+
+			for i, value := range values {
+				assertWithinFloatTolerance(t, decimal.Calculate(i).Float(), value, "index %v", i)
+			}
+		})
+	}
+}
+
+func TestFloatIndicators_ShortSeries(t *testing.T) {
+	floatSeries := NewFloatSeries(mockTimeSeriesFl(1, 2, 3))
+
+	assert.EqualValues(t, []float64{0, 0, 0}, FloatEMA(FloatClose, 5)(floatSeries))
+	assert.EqualValues(t, []float64{0, 0, 0}, FloatSMA(FloatClose, 5)(floatSeries))
+	assert.EqualValues(t, []float64{0, 1.5, 2.5}, FloatSMA(FloatClose, 2)(floatSeries))
+}
+
+func TestFloatEngine(t *testing.T) {
+	series := []*TimeSeries{randomTimeSeries(100), randomTimeSeries(150), randomTimeSeries(200)}
+	floatSeries := make([]*FloatSeries, len(series))
+	for i := range series {
+		floatSeries[i] = NewFloatSeries(series[i])
+	}
+
+	indicators := map[string]FloatIndicator{}
+	for name, test := range floatCases {
+		indicators[name] = test.float
+	}
+
+	results := NewFloatEngine(0).Calculate(floatSeries, indicators)
+	assert.Len(t, results, len(series))
+
+	for i, result := range results {
+		assert.Len(t, result, len(indicators))
+
+		for name, values := range result {
+			assert.EqualValues(t, indicators[name](floatSeries[i]), values, name)
+		}
+	}
+}
+
+func TestFloatValuesIndicator(t *testing.T) {
+	bounded := NewBoundedTimeSeries(5)
+	for _, candle := range mockTimeSeriesFl(1, 2, 3, 4, 5, 6, 7).Candles {
+		bounded.AddCandle(candle)
+	}
+
+	floatSeries := NewFloatSeries(bounded)
+	sma := NewFloatValuesIndicator(floatSeries, FloatSMA(FloatClose, 2)(floatSeries), 1)
+
+	assert.Equal(t, 2, firstIndex(sma))
+	decimalEquals(t, 0, sma.Calculate(2))
+	decimalEquals(t, 6.5, sma.Calculate(6))
+	assert.Panics(t, func() {
+		sma.Calculate(1)
+	})
+
+	rule := NewCrossUpIndicatorRule(NewConstantIndicator(5), NewFloatValuesIndicator(floatSeries, floatSeries.Close, 0))
+	assert.True(t, rule.IsSatisfied(6))
+}
+
+func TestFloatValuesIndicator_WarmUp(t *testing.T) {
+	series := randomTimeSeries(50)
+	floatSeries := NewFloatSeries(series)
+	decimal := NewSimpleMovingAverage(NewClosePriceIndicator(series), 20)
+	sma := NewFloatValuesIndicator(floatSeries, FloatSMA(FloatClose, 20)(floatSeries), Lookback(decimal))
+
+	assert.Equal(t, 19, Lookback(sma))
+	assert.False(t, IsValid(sma, 0))
+	assert.False(t, IsValid(sma, 18))
+	assert.True(t, IsValid(sma, 19))
+	assert.False(t, IsValid(sma, 50))
+	assert.Equal(t, 23, Lookback(NewSimpleMovingAverage(sma, 5)))
+
+	rule := OverIndicatorRule{First: NewConstantIndicator(math.MaxFloat64), Second: sma}
+	assert.False(t, rule.IsSatisfied(0))
+	assert.True(t, rule.IsSatisfied(19))
+}
+
+func TestWindowSums(t *testing.T) {
+	inf := math.Inf(1)
+
+	sums := windowSums([]float64{1, inf, 2, 3, 4}, 2)
+	assert.EqualValues(t, []float64{1, inf, inf, 5, 7}, sums)
+	assert.True(t, math.IsNaN(windowSums([]float64{inf, -inf}, 2)[1]))
+}
+
+func BenchmarkFloatEMA(b *testing.B) {
+	series := NewFloatSeries(randomTimeSeries(10000))
+	ema := FloatEMA(FloatClose, 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ema(series)
+	}
+}
@@ -5,6 +5,58 @@ import "github.com/schmidthole/big"
 // Indicator is an interface that describes a methodology by which to analyze a trading record for a specific property
 // or trend. For example. MovingAverageIndicator implements the Indicator interface and, for a given index in the timeSeries,
 // returns the current moving average of the prices in that series.
+//
+// The built-in indicators may be calculated from several goroutines at once, as long as the series they are calculated
+// on is not changed meanwhile.
 type Indicator interface {
 	Calculate(int) big.Decimal
 }
+
+// LookbackIndicator is an Indicator which needs a number of values, its lookback, before its own values are valid.
+// Before then, most indicators return zero or a value calculated from a partial window. Recursive indicators such as
+// the EMA are valid after their lookback, but remain influenced by the value they were seeded with for some time.
+type LookbackIndicator interface {
+	Indicator
+	Lookback() int
+}
+
+// Lookback returns the lookback of an indicator, the number of values at the start of its series which are calculated
+// from too few candles to be valid. Indicators which do not implement LookbackIndicator have no lookback.
+func Lookback(indicator Indicator) int {
+	if lookback, ok := indicator.(LookbackIndicator); ok {
+		return lookback.Lookback()
+	}
+
+	return 0
+}
+
+// IsValid returns true if the value of an indicator at the given index is valid: the index is past the lookback of the
+// indicator, and within the candles retained by the series it is calculated on. An indicator can also report this
+// itself with an IsValid(index int) bool method, as fixed indicators do for the values they hold.
+func IsValid(indicator Indicator, index int) bool {
+	if validated, ok := indicator.(interface{ IsValid(index int) bool }); ok {
+		return validated.IsValid(index)
+	}
+
+	if index < Lookback(indicator) || index < firstIndex(indicator) {
+		return false
+	}
+
+	for _, series := range seriesOf(indicator) {
+		if index > series.LastIndex() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// maxLookback returns the largest lookback of the given indicators
+func maxLookback(indicators ...Indicator) int {
+	lookback := 0
+	for _, indicator := range indicators {
+		lookback = Max(lookback, Lookback(indicator))
+	}
+
+	return lookback
+}
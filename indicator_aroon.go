@@ -10,45 +10,35 @@ type aroonIndicator struct {
 	indicator Indicator
 	window    int
 	direction big.Decimal
-	lowIndex  int
 }
 
-func (ai *aroonIndicator) Calculate(index int) big.Decimal {
+func (ai aroonIndicator) Calculate(index int) big.Decimal {
 	if index < ai.window-1 {
 		return big.ZERO
 	}
 
 	oneHundred := big.TEN.Mul(big.TEN)
-	ai.lowIndex = ai.findLowIndex(index)
-	pSince := big.NewDecimal(float64(index - ai.lowIndex))
+	pSince := big.NewDecimal(float64(index - ai.findLowIndex(index)))
 	windowAsDecimal := big.NewDecimal(float64(ai.window))
 
 	return windowAsDecimal.Sub(pSince).Div(windowAsDecimal).Mul(oneHundred)
 }
 
+// findLowIndex returns the index of the first lowest value of the window ending at index. The
+// window is scanned for every index, so that values do not depend on the order they are
+// calculated in, and the indicator can be calculated from several goroutines.
 func (ai aroonIndicator) findLowIndex(index int) int {
-	if ai.lowIndex < 1 || ai.lowIndex < index-ai.window {
-		lv := big.NewDecimal(math.MaxFloat64)
-		lowIndex := -1
-		for i := (index + 1) - ai.window; i <= index; i++ {
-			value := ai.indicator.Calculate(i).Mul(ai.direction)
-			if value.LT(lv) {
-				lv = value
-				lowIndex = i
-			}
+	lv := big.NewDecimal(math.MaxFloat64)
+	lowIndex := -1
+	for i := (index + 1) - ai.window; i <= index; i++ {
+		value := ai.indicator.Calculate(i).Mul(ai.direction)
+		if value.LT(lv) {
+			lv = value
+			lowIndex = i
 		}
-
-		return lowIndex
-	}
-
-	v1 := ai.indicator.Calculate(index).Mul(ai.direction)
-	v2 := ai.indicator.Calculate(ai.lowIndex).Mul(ai.direction)
-
-	if v1.LT(v2) {
-		return index
 	}
 
-	return ai.lowIndex
+	return lowIndex
 }
 
 // NewAroonUpIndicator returns a derivative indicator that will return a value based on
@@ -57,12 +47,11 @@ func (ai aroonIndicator) findLowIndex(index int) int {
 //
 // Note: this indicator should be constructed with a either a HighPriceIndicator or a derivative thereof
 func NewAroonUpIndicator(indicator Indicator, window int) Indicator {
-	return &aroonIndicator{
+	return cacheIndicator(aroonIndicator{
 		indicator: indicator,
 		window:    window,
 		direction: big.ONE.Neg(),
-		lowIndex:  -1,
-	}
+	}, indicator)
 }
 
 // NewAroonDownIndicator returns a derivative indicator that will return a value based on
@@ -71,10 +60,24 @@ func NewAroonUpIndicator(indicator Indicator, window int) Indicator {
 //
 // Note: this indicator should be constructed with a either a LowPriceIndicator or a derivative thereof
 func NewAroonDownIndicator(indicator Indicator, window int) Indicator {
-	return &aroonIndicator{
+	return cacheIndicator(aroonIndicator{
 		indicator: indicator,
 		window:    window,
 		direction: big.ONE,
-		lowIndex:  -1,
-	}
+	}, indicator)
+}
+
+// NewAroon returns a MultiOutputIndicator of the aroon up indicator on high prices and the aroon down indicator on
+// low prices, for the given window.
+// https://www.investopedia.com/terms/a/aroon.asp
+func NewAroon(series *TimeSeries, window int) MultiOutputIndicator {
+	return NewMultiOutputIndicator(
+		[]string{OutputUp, OutputDown},
+		NewAroonUpIndicator(NewHighPriceIndicator(series), window),
+		NewAroonDownIndicator(NewLowPriceIndicator(series), window),
+	)
+}
+
+func (ai aroonIndicator) Lookback() int {
+	return Lookback(ai.indicator) + ai.window - 1
 }
@@ -10,21 +10,25 @@ type averageIndicator struct {
 // NewAverageGainsIndicator Returns a new average gains indicator, which returns the average gains
 // in the given window based on the given indicator.
 func NewAverageGainsIndicator(indicator Indicator, window int) Indicator {
-	return averageIndicator{
+	return cacheIndicator(averageIndicator{
 		NewCumulativeGainsIndicator(indicator, window),
 		window,
-	}
+	}, indicator)
 }
 
 // NewAverageLossesIndicator Returns a new average losses indicator, which returns the average losses
 // in the given window based on the given indicator.
 func NewAverageLossesIndicator(indicator Indicator, window int) Indicator {
-	return averageIndicator{
+	return cacheIndicator(averageIndicator{
 		NewCumulativeLossesIndicator(indicator, window),
 		window,
-	}
+	}, indicator)
 }
 
 func (ai averageIndicator) Calculate(index int) big.Decimal {
 	return ai.Indicator.Calculate(index).Div(big.NewDecimal(float64(Min(index+1, ai.window))))
 }
+
+func (ai averageIndicator) Lookback() int {
+	return Lookback(ai.Indicator)
+}
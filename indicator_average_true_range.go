@@ -3,18 +3,18 @@ package techan
 import "github.com/schmidthole/big"
 
 type averageTrueRangeIndicator struct {
-	series *TimeSeries
-	window int
+	trueRanges Indicator
+	window     int
 }
 
 // NewAverageTrueRangeIndicator returns a base indicator that calculates the average true range of the
 // underlying over a window
 // https://www.investopedia.com/terms/a/atr.asp
 func NewAverageTrueRangeIndicator(series *TimeSeries, window int) Indicator {
-	return averageTrueRangeIndicator{
-		series: series,
-		window: window,
-	}
+	return NewCachedIndicator(averageTrueRangeIndicator{
+		trueRanges: newWindowSumIndicator(NewTrueRangeIndicator(series), window),
+		window:     window,
+	}, series)
 }
 
 func (atr averageTrueRangeIndicator) Calculate(index int) big.Decimal {
@@ -22,11 +22,9 @@ func (atr averageTrueRangeIndicator) Calculate(index int) big.Decimal {
 		return big.ZERO
 	}
 
-	sum := big.ZERO
-
-	for i := index; i > index-atr.window; i-- {
-		sum = sum.Add(NewTrueRangeIndicator(atr.series).Calculate(i))
-	}
+	return atr.trueRanges.Calculate(index).Div(big.NewFromInt(atr.window))
+}
 
-	return sum.Div(big.NewFromInt(atr.window))
+func (atr averageTrueRangeIndicator) Lookback() int {
+	return atr.window
 }
@@ -13,11 +13,11 @@ type binaryFrequencyIndicator struct {
 // Binary Frequency is a specialized indicator which returns the number of times an
 // indicator was greater than a defined threshold over a lookback window.
 func NewBinaryFrequencyIndicator(indicators []Indicator, window int, threshold float64) Indicator {
-	return binaryFrequencyIndicator{
+	return cacheIndicator(binaryFrequencyIndicator{
 		indicators: indicators,
 		window:     window,
 		threshold:  big.NewDecimal(threshold),
-	}
+	}, indicators...)
 }
 
 func (s binaryFrequencyIndicator) Calculate(index int) big.Decimal {
@@ -37,3 +37,7 @@ func (s binaryFrequencyIndicator) Calculate(index int) big.Decimal {
 
 	return big.NewFromInt(frequency)
 }
+
+func (s binaryFrequencyIndicator) Lookback() int {
+	return maxLookback(s.indicators...) + s.window - 1
+}
@@ -8,26 +8,38 @@ type bbandIndicator struct {
 	muladd big.Decimal
 }
 
+// NewBollingerBands returns a MultiOutputIndicator of the upper, middle and lower bollinger bands on the underlying
+// indicator. The middle band is the window's simple moving average, and the bands are sigma standard deviations above
+// and below it.
+// https://www.investopedia.com/terms/b/bollingerbands.asp
+func NewBollingerBands(indicator Indicator, window int, sigma float64) MultiOutputIndicator {
+	ma := NewSimpleMovingAverage(indicator, window)
+	stdev := NewWindowedStandardDeviationIndicator(indicator, window)
+
+	return NewMultiOutputIndicator(
+		[]string{OutputUpper, OutputMiddle, OutputLower},
+		cacheIndicator(bbandIndicator{ma: ma, stdev: stdev, muladd: big.NewDecimal(sigma)}, indicator),
+		ma,
+		cacheIndicator(bbandIndicator{ma: ma, stdev: stdev, muladd: big.NewDecimal(-sigma)}, indicator),
+	)
+}
+
 // NewBollingerUpperBandIndicator a a derivative indicator which returns the upper bound of a bollinger band
 // on the underlying indicator
 func NewBollingerUpperBandIndicator(indicator Indicator, window int, sigma float64) Indicator {
-	return bbandIndicator{
-		ma:     NewSimpleMovingAverage(indicator, window),
-		stdev:  NewWindowedStandardDeviationIndicator(indicator, window),
-		muladd: big.NewDecimal(sigma),
-	}
+	return NewBollingerBands(indicator, window, sigma).Output(OutputUpper)
 }
 
 // NewBollingerLowerBandIndicator returns a a derivative indicator which returns the lower bound of a bollinger band
 // on the underlying indicator
 func NewBollingerLowerBandIndicator(indicator Indicator, window int, sigma float64) Indicator {
-	return bbandIndicator{
-		ma:     NewSimpleMovingAverage(indicator, window),
-		stdev:  NewWindowedStandardDeviationIndicator(indicator, window),
-		muladd: big.NewDecimal(-sigma),
-	}
+	return NewBollingerBands(indicator, window, sigma).Output(OutputLower)
 }
 
 func (bbi bbandIndicator) Calculate(index int) big.Decimal {
 	return bbi.ma.Calculate(index).Add(bbi.stdev.Calculate(index).Mul(bbi.muladd))
 }
+
+func (bbi bbandIndicator) Lookback() int {
+	return maxLookback(bbi.ma, bbi.stdev)
+}
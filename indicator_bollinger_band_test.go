@@ -125,3 +125,13 @@ func TestBollingerBandIndicator(t *testing.T) {
 		decimalAlmostEquals(t, big.NewFromString(BBWs[j]), bbUP.Calculate(i).Sub(bbLO.Calculate((i))), 0.01)
 	}
 }
+
+func TestWindowedStandardDeviationIndicator_Flat(t *testing.T) {
+	ts := mockTimeSeriesFl(1.1, 1.1, 1.1, 2.3, 0.7, 1.1, 1.1, 1.1, 1.1)
+	stdev := NewWindowedStandardDeviationIndicator(NewClosePriceIndicator(ts), 3)
+
+	for i := 7; i <= ts.LastIndex(); i++ {
+		assert.False(t, stdev.Calculate(i).NaN(), "index %v", i)
+		assert.InDelta(t, 0, stdev.Calculate(i).Float(), 1e-9, "index %v", i)
+	}
+}
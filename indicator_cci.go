@@ -3,23 +3,32 @@ package techan
 import "github.com/schmidthole/big"
 
 type commidityChannelIndexIndicator struct {
-	series *TimeSeries
-	window int
+	typicalPrice    Indicator
+	typicalPriceSma Indicator
+	meanDeviation   Indicator
+	window          int
 }
 
 // NewCCIIndicator Returns a new Commodity Channel Index Indicator
 // http://stockcharts.com/school/doku.php?id=chart_school:technical_indicators:commodity_channel_index_cci
 func NewCCIIndicator(ts *TimeSeries, window int) Indicator {
-	return commidityChannelIndexIndicator{
-		series: ts,
-		window: window,
-	}
+	typicalPrice := NewTypicalPriceIndicator(ts)
+
+	return NewCachedIndicator(commidityChannelIndexIndicator{
+		typicalPrice:    typicalPrice,
+		typicalPriceSma: NewSimpleMovingAverage(typicalPrice, window),
+		meanDeviation:   NewMeanDeviationIndicator(NewClosePriceIndicator(ts), window),
+		window:          window,
+	}, ts)
 }
 
 func (ccii commidityChannelIndexIndicator) Calculate(index int) big.Decimal {
-	typicalPrice := NewTypicalPriceIndicator(ccii.series)
-	typicalPriceSma := NewSimpleMovingAverage(typicalPrice, ccii.window)
-	meanDeviation := NewMeanDeviationIndicator(NewClosePriceIndicator(ccii.series), ccii.window)
+	typicalPrice := ccii.typicalPrice.Calculate(index)
+	mean := ccii.typicalPriceSma.Calculate(index)
+
+	return typicalPrice.Sub(mean).Div(ccii.meanDeviation.Calculate(index).Mul(big.NewFromString("0.015")))
+}
 
-	return typicalPrice.Calculate(index).Sub(typicalPriceSma.Calculate(index)).Div(meanDeviation.Calculate(index).Mul(big.NewFromString("0.015")))
+func (ccii commidityChannelIndexIndicator) Lookback() int {
+	return ccii.window - 1
 }
@@ -17,3 +17,12 @@ func (di DerivativeIndicator) Calculate(index int) big.Decimal {
 
 	return di.Indicator.Calculate(index).Sub(di.Indicator.Calculate(index - 1))
 }
+
+func (di DerivativeIndicator) sourceSeries() []*TimeSeries {
+	return seriesOf(di.Indicator)
+}
+
+// Lookback returns the lookback of the underlying indicator plus one, for the previous value
+func (di DerivativeIndicator) Lookback() int {
+	return Lookback(di.Indicator) + 1
+}
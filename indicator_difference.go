@@ -10,12 +10,16 @@ type differenceIndicator struct {
 // NewDifferenceIndicator returns an indicator which returns the difference between one indicator (minuend) and a second
 // indicator (subtrahend).
 func NewDifferenceIndicator(minuend, subtrahend Indicator) Indicator {
-	return differenceIndicator{
+	return cacheIndicator(differenceIndicator{
 		minuend:    minuend,
 		subtrahend: subtrahend,
-	}
+	}, minuend, subtrahend)
 }
 
 func (di differenceIndicator) Calculate(index int) big.Decimal {
 	return di.minuend.Calculate(index).Sub(di.subtrahend.Calculate(index))
 }
+
+func (di differenceIndicator) Lookback() int {
+	return maxLookback(di.minuend, di.subtrahend)
+}
@@ -1,12 +1,18 @@
 package techan
 
-import "github.com/schmidthole/big"
+import (
+	"sync"
+
+	"github.com/schmidthole/big"
+)
 
 type emaIndicator struct {
 	indicator   Indicator
 	window      int
 	alpha       big.Decimal
 	resultCache resultCache
+	mutex       *sync.Mutex
+	series      []*TimeSeries
 }
 
 // NewEMAIndicator returns a derivative indicator which returns the average of the current and preceding values in
@@ -17,13 +23,15 @@ func NewEMAIndicator(indicator Indicator, window int) Indicator {
 		indicator:   indicator,
 		window:      window,
 		alpha:       big.ONE.Frac(2).Div(big.NewFromInt(window + 1)),
-		resultCache: make([]*big.Decimal, 1000),
+		resultCache: newResultCache(1000),
+		mutex:       &sync.Mutex{},
+		series:      seriesOf(indicator),
 	}
 }
 
 func (ema *emaIndicator) Calculate(index int) big.Decimal {
 	if cachedValue := returnIfCached(ema, index, func(i int) big.Decimal {
-		return NewSimpleMovingAverage(ema.indicator, ema.window).Calculate(i)
+		return windowSum(ema.indicator, i, ema.window).Div(big.NewFromInt(ema.window))
 	}); cachedValue != nil {
 		return *cachedValue
 	}
@@ -31,15 +39,25 @@ func (ema *emaIndicator) Calculate(index int) big.Decimal {
 	todayVal := ema.indicator.Calculate(index).Mul(ema.alpha)
 	result := todayVal.Add(ema.Calculate(index - 1).Mul(big.ONE.Sub(ema.alpha)))
 
+	ema.mutex.Lock()
 	cacheResult(ema, index, result)
+	ema.mutex.Unlock()
 
 	return result
 }
 
-func (ema emaIndicator) cache() resultCache { return ema.resultCache }
+func (ema *emaIndicator) cache() resultCache { return ema.resultCache }
 
 func (ema *emaIndicator) setCache(newCache resultCache) {
 	ema.resultCache = newCache
 }
 
-func (ema emaIndicator) windowSize() int { return ema.window }
+func (ema *emaIndicator) windowSize() int { return ema.window }
+
+func (ema *emaIndicator) locker() sync.Locker { return ema.mutex }
+
+func (ema *emaIndicator) FirstIndex() int { return firstIndex(ema.indicator) }
+
+func (ema *emaIndicator) sourceSeries() []*TimeSeries { return ema.series }
+
+func (ema *emaIndicator) Lookback() int { return Lookback(ema.indicator) + ema.window - 1 }
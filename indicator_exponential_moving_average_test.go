@@ -35,7 +35,28 @@ func TestExponentialMovingAverage(t *testing.T) {
 
 		emaStruct, ok := ema.(cachedIndicator)
 		assert.True(t, ok)
-		assert.EqualValues(t, 1001, len(emaStruct.cache()))
+		assert.EqualValues(t, 1001, len(emaStruct.cache().values))
+	})
+
+	t.Run("Evicts Result Cache With Bounded Series", func(t *testing.T) {
+		full := randomTimeSeries(50)
+		bounded := NewBoundedTimeSeries(10)
+
+		expected := NewEMAIndicator(NewClosePriceIndicator(full), 4)
+		ema := NewEMAIndicator(NewClosePriceIndicator(bounded), 4)
+
+		for i, candle := range full.Candles {
+			bounded.AddCandle(candle)
+			assert.EqualValues(t, expected.Calculate(i).String(), ema.Calculate(i).String())
+		}
+
+		emaStruct := ema.(cachedIndicator)
+		assert.Equal(t, 40, emaStruct.cache().offset)
+		assert.True(t, len(emaStruct.cache().values) <= 1000)
+
+		assert.Panics(t, func() {
+			NewEMAIndicator(NewClosePriceIndicator(bounded), 4).Calculate(45)
+		})
 	})
 }
 
@@ -10,19 +10,19 @@ type gainLossIndicator struct {
 // NewGainIndicator returns a derivative indicator that returns the gains in the underlying indicator in the last bar,
 // if any. If the delta is negative, zero is returned
 func NewGainIndicator(indicator Indicator) Indicator {
-	return gainLossIndicator{
+	return cacheIndicator(gainLossIndicator{
 		Indicator:   indicator,
 		coefficient: big.ONE,
-	}
+	}, indicator)
 }
 
 // NewLossIndicator returns a derivative indicator that returns the losses in the underlying indicator in the last bar,
 // if any. If the delta is positive, zero is returned
 func NewLossIndicator(indicator Indicator) Indicator {
-	return gainLossIndicator{
+	return cacheIndicator(gainLossIndicator{
 		Indicator:   indicator,
 		coefficient: big.ONE.Neg(),
-	}
+	}, indicator)
 }
 
 func (gli gainLossIndicator) Calculate(index int) big.Decimal {
@@ -38,6 +38,14 @@ func (gli gainLossIndicator) Calculate(index int) big.Decimal {
 	return big.ZERO
 }
 
+func (gli gainLossIndicator) FirstIndex() int {
+	return firstIndex(gli.Indicator)
+}
+
+func (gli gainLossIndicator) Lookback() int {
+	return Lookback(gli.Indicator) + 1
+}
+
 type cumulativeIndicator struct {
 	Indicator
 	window int
@@ -47,21 +55,21 @@ type cumulativeIndicator struct {
 // NewCumulativeGainsIndicator returns a derivative indicator which returns all gains made in a base indicator for a given
 // window.
 func NewCumulativeGainsIndicator(indicator Indicator, window int) Indicator {
-	return cumulativeIndicator{
+	return cacheIndicator(cumulativeIndicator{
 		Indicator: indicator,
 		window:    window,
 		mult:      big.ONE,
-	}
+	}, indicator)
 }
 
 // NewCumulativeLossesIndicator returns a derivative indicator which returns all losses in a base indicator for a given
 // window.
 func NewCumulativeLossesIndicator(indicator Indicator, window int) Indicator {
-	return cumulativeIndicator{
+	return cacheIndicator(cumulativeIndicator{
 		Indicator: indicator,
 		window:    window,
 		mult:      big.ONE.Neg(),
-	}
+	}, indicator)
 }
 
 func (ci cumulativeIndicator) Calculate(index int) big.Decimal {
@@ -84,7 +92,7 @@ type percentChangeIndicator struct {
 // NewPercentChangeIndicator returns a derivative indicator which returns the percent change (positive or negative)
 // made in a base indicator up until the given indicator
 func NewPercentChangeIndicator(indicator Indicator) Indicator {
-	return percentChangeIndicator{indicator}
+	return cacheIndicator(percentChangeIndicator{indicator}, indicator)
 }
 
 func (pgi percentChangeIndicator) Calculate(index int) big.Decimal {
@@ -96,3 +104,11 @@ func (pgi percentChangeIndicator) Calculate(index int) big.Decimal {
 	cplast := pgi.Indicator.Calculate(index - 1)
 	return cp.Div(cplast).Sub(big.ONE)
 }
+
+func (ci cumulativeIndicator) Lookback() int {
+	return Lookback(ci.Indicator) + ci.window
+}
+
+func (pgi percentChangeIndicator) Lookback() int {
+	return Lookback(pgi.Indicator) + 1
+}
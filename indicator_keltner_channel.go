@@ -11,22 +11,28 @@ type keltnerChannelIndicator struct {
 	window int
 }
 
+// NewKeltnerChannel returns a MultiOutputIndicator of the upper, middle and lower lines of a keltner channel. The
+// middle line is the window's EMA of close prices, and the upper and lower lines are two average true ranges above
+// and below it.
+// https://www.investopedia.com/terms/k/keltnerchannel.asp
+func NewKeltnerChannel(series *TimeSeries, window int) MultiOutputIndicator {
+	atr := NewAverageTrueRangeIndicator(series, window)
+	ema := NewEMAIndicator(NewClosePriceIndicator(series), window)
+
+	return NewMultiOutputIndicator(
+		[]string{OutputUpper, OutputMiddle, OutputLower},
+		NewCachedIndicator(keltnerChannelIndicator{atr: atr, ema: ema, mul: big.ONE, window: window}, series),
+		ema,
+		NewCachedIndicator(keltnerChannelIndicator{atr: atr, ema: ema, mul: big.ONE.Neg(), window: window}, series),
+	)
+}
+
 func NewKeltnerChannelUpperIndicator(series *TimeSeries, window int) Indicator {
-	return keltnerChannelIndicator{
-		atr:    NewAverageTrueRangeIndicator(series, window),
-		ema:    NewEMAIndicator(NewClosePriceIndicator(series), window),
-		mul:    big.ONE,
-		window: window,
-	}
+	return NewKeltnerChannel(series, window).Output(OutputUpper)
 }
 
 func NewKeltnerChannelLowerIndicator(series *TimeSeries, window int) Indicator {
-	return keltnerChannelIndicator{
-		atr:    NewAverageTrueRangeIndicator(series, window),
-		ema:    NewEMAIndicator(NewClosePriceIndicator(series), window),
-		mul:    big.ONE.Neg(),
-		window: window,
-	}
+	return NewKeltnerChannel(series, window).Output(OutputLower)
 }
 
 func (kci keltnerChannelIndicator) Calculate(index int) big.Decimal {
@@ -38,3 +44,7 @@ func (kci keltnerChannelIndicator) Calculate(index int) big.Decimal {
 
 	return kci.ema.Calculate(index).Add(kci.atr.Calculate(index).Mul(coefficient))
 }
+
+func (kci keltnerChannelIndicator) Lookback() int {
+	return maxLookback(kci.atr, kci.ema)
+}
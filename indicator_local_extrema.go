@@ -71,3 +71,7 @@ func findMinIndexInWindow(ind Indicator, startIndex int, endIndex int) int {
 
 	return minIndex
 }
+
+func (ext localExtremaIndicator) IsValid(index int) bool {
+	return index >= 0 && index < len(ext.extrema)
+}
@@ -13,3 +13,17 @@ func NewMACDIndicator(baseIndicator Indicator, shortwindow, longwindow int) Indi
 func NewMACDHistogramIndicator(macdIdicator Indicator, signalLinewindow int) Indicator {
 	return NewDifferenceIndicator(macdIdicator, NewEMAIndicator(macdIdicator, signalLinewindow))
 }
+
+// NewMACD returns a MultiOutputIndicator of the MACD line, its signalLinewindow EMA signal line, and the histogram of
+// their difference. All three share the same EMAs of the base indicator.
+func NewMACD(baseIndicator Indicator, shortwindow, longwindow, signalLinewindow int) MultiOutputIndicator {
+	macd := NewMACDIndicator(baseIndicator, shortwindow, longwindow)
+	signal := NewEMAIndicator(macd, signalLinewindow)
+
+	return NewMultiOutputIndicator(
+		[]string{OutputMACD, OutputSignal, OutputHistogram},
+		macd,
+		signal,
+		NewDifferenceIndicator(macd, signal),
+	)
+}
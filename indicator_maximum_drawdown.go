@@ -45,3 +45,7 @@ func NewMaximumDrawdownIndicator(indicator Indicator, window int, length int) In
 func (mdi maximumDrawdownIndicator) Calculate(index int) big.Decimal {
 	return mdi.drawdowns[index]
 }
+
+func (mdi maximumDrawdownIndicator) IsValid(index int) bool {
+	return index >= 0 && index < len(mdi.drawdowns)
+}
@@ -6,10 +6,10 @@ import "github.com/schmidthole/big"
 // present in a given window. Use a window value of -1 to include all values in the
 // underlying indicator.
 func NewMaximumValueIndicator(ind Indicator, window int) Indicator {
-	return maximumValueIndicator{
+	return cacheIndicator(maximumValueIndicator{
 		indicator: ind,
 		window:    window,
-	}
+	}, ind)
 }
 
 type maximumValueIndicator struct {
@@ -34,3 +34,7 @@ func (mvi maximumValueIndicator) Calculate(index int) big.Decimal {
 
 	return maxValue
 }
+
+func (mvi maximumValueIndicator) Lookback() int {
+	return Lookback(mvi.indicator) + Max(mvi.window-1, 0)
+}
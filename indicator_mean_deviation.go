@@ -11,11 +11,11 @@ type meanDeviationIndicator struct {
 // NewMeanDeviationIndicator returns a derivative Indicator which returns the mean deviation of a base indicator
 // in a given window. Mean deviation is an average of all values on the base indicator from the mean of that indicator.
 func NewMeanDeviationIndicator(indicator Indicator, window int) Indicator {
-	return meanDeviationIndicator{
+	return cacheIndicator(meanDeviationIndicator{
 		Indicator:     indicator,
 		movingAverage: NewSimpleMovingAverage(indicator, window),
 		window:        window,
-	}
+	}, indicator)
 }
 
 func (mdi meanDeviationIndicator) Calculate(index int) big.Decimal {
@@ -33,3 +33,7 @@ func (mdi meanDeviationIndicator) Calculate(index int) big.Decimal {
 
 	return absoluteDeviations.Div(big.NewDecimal(float64(Min(mdi.window, index-start+1))))
 }
+
+func (mdi meanDeviationIndicator) Lookback() int {
+	return Lookback(mdi.Indicator) + mdi.window - 1
+}
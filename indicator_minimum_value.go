@@ -6,10 +6,10 @@ import "github.com/schmidthole/big"
 // present in a given window. Use a window value of -1 to include all values in the
 // underlying indicator.
 func NewMinimumValueIndicator(ind Indicator, window int) Indicator {
-	return minimumValueIndicator{
+	return cacheIndicator(minimumValueIndicator{
 		indicator: ind,
 		window:    window,
-	}
+	}, ind)
 }
 
 type minimumValueIndicator struct {
@@ -34,3 +34,7 @@ func (mvi minimumValueIndicator) Calculate(index int) big.Decimal {
 
 	return minValue
 }
+
+func (mvi minimumValueIndicator) Lookback() int {
+	return Lookback(mvi.indicator) + Max(mvi.window-1, 0)
+}
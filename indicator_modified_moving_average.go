@@ -1,11 +1,17 @@
 package techan
 
-import "github.com/schmidthole/big"
+import (
+	"sync"
+
+	"github.com/schmidthole/big"
+)
 
 type modifiedMovingAverageIndicator struct {
 	indicator   Indicator
 	window      int
 	resultCache resultCache
+	mutex       *sync.Mutex
+	series      []*TimeSeries
 }
 
 // NewMMAIndicator returns a derivative indciator which returns the modified moving average of the underlying
@@ -15,13 +21,15 @@ func NewMMAIndicator(indicator Indicator, window int) Indicator {
 	return &modifiedMovingAverageIndicator{
 		indicator:   indicator,
 		window:      window,
-		resultCache: make([]*big.Decimal, 10000),
+		resultCache: newResultCache(10000),
+		mutex:       &sync.Mutex{},
+		series:      seriesOf(indicator),
 	}
 }
 
 func (mma *modifiedMovingAverageIndicator) Calculate(index int) big.Decimal {
 	if cachedValue := returnIfCached(mma, index, func(i int) big.Decimal {
-		return NewSimpleMovingAverage(mma.indicator, mma.window).Calculate(i)
+		return windowSum(mma.indicator, i, mma.window).Div(big.NewFromInt(mma.window))
 	}); cachedValue != nil {
 		return *cachedValue
 	}
@@ -30,12 +38,14 @@ func (mma *modifiedMovingAverageIndicator) Calculate(index int) big.Decimal {
 	lastVal := mma.Calculate(index - 1)
 
 	result := lastVal.Add(big.NewDecimal(1.0 / float64(mma.window)).Mul(todayVal.Sub(lastVal)))
+	mma.mutex.Lock()
 	cacheResult(mma, index, result)
+	mma.mutex.Unlock()
 
 	return result
 }
 
-func (mma modifiedMovingAverageIndicator) cache() resultCache {
+func (mma *modifiedMovingAverageIndicator) cache() resultCache {
 	return mma.resultCache
 }
 
@@ -43,6 +53,22 @@ func (mma *modifiedMovingAverageIndicator) setCache(cache resultCache) {
 	mma.resultCache = cache
 }
 
-func (mma modifiedMovingAverageIndicator) windowSize() int {
+func (mma *modifiedMovingAverageIndicator) windowSize() int {
 	return mma.window
 }
+
+func (mma *modifiedMovingAverageIndicator) locker() sync.Locker {
+	return mma.mutex
+}
+
+func (mma *modifiedMovingAverageIndicator) FirstIndex() int {
+	return firstIndex(mma.indicator)
+}
+
+func (mma *modifiedMovingAverageIndicator) sourceSeries() []*TimeSeries {
+	return mma.series
+}
+
+func (mma *modifiedMovingAverageIndicator) Lookback() int {
+	return Lookback(mma.indicator) + mma.window - 1
+}
@@ -0,0 +1,85 @@
+package techan
+
+import (
+	"fmt"
+
+	"github.com/schmidthole/big"
+)
+
+// Names of the outputs of the built-in multi-output indicators
+const (
+	OutputUpper      = "upper"
+	OutputMiddle     = "middle"
+	OutputLower      = "lower"
+	OutputMACD       = "macd"
+	OutputSignal     = "signal"
+	OutputHistogram  = "histogram"
+	OutputK          = "k"
+	OutputD          = "d"
+	OutputUp         = "up"
+	OutputDown       = "down"
+	OutputRVI        = "rvi"
+	OutputSupertrend = "supertrend"
+	OutputDirection  = "direction"
+)
+
+// MultiOutputIndicator is an indicator which calculates several related values from shared parts, such as the bands
+// of a channel or the lines of the MACD. Every named output is an Indicator of its own, and can be used in rules and
+// strategies like any other indicator.
+type MultiOutputIndicator interface {
+	// Outputs returns the names of the outputs, in a fixed order
+	Outputs() []string
+	// Output returns the named output. It panics if the indicator has no output of that name
+	Output(name string) Indicator
+	// CalculateAll returns the value of every output at index, keyed by output name
+	CalculateAll(index int) map[string]big.Decimal
+}
+
+type multiOutputIndicator struct {
+	names   []string
+	outputs map[string]Indicator
+}
+
+// NewMultiOutputIndicator returns a MultiOutputIndicator of the given names and indicators, which are paired by
+// position. Indicators which should share work should be built from the same underlying indicators.
+func NewMultiOutputIndicator(names []string, indicators ...Indicator) MultiOutputIndicator {
+	if len(names) != len(indicators) {
+		panic(fmt.Errorf("error creating multi-output indicator: %d names given for %d indicators", len(names), len(indicators)))
+	}
+
+	outputs := make(map[string]Indicator, len(names))
+	for i, name := range names {
+		if _, exists := outputs[name]; exists {
+			panic(fmt.Errorf("error creating multi-output indicator: duplicate output %q", name))
+		}
+
+		outputs[name] = indicators[i]
+	}
+
+	return multiOutputIndicator{
+		names:   append([]string(nil), names...),
+		outputs: outputs,
+	}
+}
+
+func (moi multiOutputIndicator) Outputs() []string {
+	return append([]string(nil), moi.names...)
+}
+
+func (moi multiOutputIndicator) Output(name string) Indicator {
+	output, ok := moi.outputs[name]
+	if !ok {
+		panic(fmt.Errorf("error getting output: no output %q, expected one of %v", name, moi.names))
+	}
+
+	return output
+}
+
+func (moi multiOutputIndicator) CalculateAll(index int) map[string]big.Decimal {
+	values := make(map[string]big.Decimal, len(moi.names))
+	for _, name := range moi.names {
+		values[name] = moi.outputs[name].Calculate(index)
+	}
+
+	return values
+}
@@ -0,0 +1,150 @@
+package techan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiOutputIndicator(t *testing.T) {
+	bands := NewMultiOutputIndicator(
+		[]string{OutputUpper, OutputLower},
+		NewConstantIndicator(2),
+		NewConstantIndicator(1),
+	)
+
+	assert.EqualValues(t, []string{OutputUpper, OutputLower}, bands.Outputs())
+	decimalEquals(t, 2, bands.Output(OutputUpper).Calculate(0))
+
+	values := bands.CalculateAll(0)
+	assert.Len(t, values, 2)
+	decimalEquals(t, 2, values[OutputUpper])
+	decimalEquals(t, 1, values[OutputLower])
+
+	assert.Panics(t, func() {
+		bands.Output(OutputMiddle)
+	})
+
+	assert.Panics(t, func() {
+		NewMultiOutputIndicator([]string{OutputUpper}, NewConstantIndicator(1), NewConstantIndicator(2))
+	})
+
+	assert.Panics(t, func() {
+		NewMultiOutputIndicator([]string{OutputUpper, OutputUpper}, NewConstantIndicator(1), NewConstantIndicator(2))
+	})
+}
+
+func TestMultiOutputIndicator_MatchesSingleOutputs(t *testing.T) {
+	series := randomTimeSeries(100)
+	closePrice := NewClosePriceIndicator(series)
+	macd := NewMACDIndicator(closePrice, 12, 26)
+
+	for _, test := range []struct {
+		name      string
+		indicator MultiOutputIndicator
+		outputs   map[string]Indicator
+	}{
+		{
+			"bollinger bands",
+			NewBollingerBands(closePrice, 20, 2),
+			map[string]Indicator{
+				OutputUpper:  NewBollingerUpperBandIndicator(closePrice, 20, 2),
+				OutputMiddle: NewSimpleMovingAverage(closePrice, 20),
+				OutputLower:  NewBollingerLowerBandIndicator(closePrice, 20, 2),
+			},
+		},
+		{
+			"keltner channel",
+			NewKeltnerChannel(series, 10),
+			map[string]Indicator{
+				OutputUpper:  NewKeltnerChannelUpperIndicator(series, 10),
+				OutputMiddle: NewEMAIndicator(closePrice, 10),
+				OutputLower:  NewKeltnerChannelLowerIndicator(series, 10),
+			},
+		},
+		{
+			"macd",
+			NewMACD(closePrice, 12, 26, 9),
+			map[string]Indicator{
+				OutputMACD:      macd,
+				OutputSignal:    NewEMAIndicator(macd, 9),
+				OutputHistogram: NewMACDHistogramIndicator(macd, 9),
+			},
+		},
+		{
+			"stochastic oscillator",
+			NewStochasticOscillator(series, 14, 3),
+			map[string]Indicator{
+				OutputK: NewFastStochasticIndicator(series, 14),
+				OutputD: NewSlowStochasticIndicator(NewFastStochasticIndicator(series, 14), 3),
+			},
+		},
+		{
+			"aroon",
+			NewAroon(series, 25),
+			map[string]Indicator{
+				OutputUp:   NewAroonUpIndicator(NewHighPriceIndicator(series), 25),
+				OutputDown: NewAroonDownIndicator(NewLowPriceIndicator(series), 25),
+			},
+		},
+		{
+			"relative vigor index",
+			NewRelativeVigorIndex(series),
+			map[string]Indicator{
+				OutputRVI:    NewRelativeVigorIndexIndicator(series),
+				OutputSignal: NewRelativeVigorSignalLine(series),
+			},
+		},
+		{
+			"supertrend",
+			NewSupertrend(series, 10, 3),
+			map[string]Indicator{
+				OutputSupertrend: NewSupertrendIndicator(series, 10, 3),
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i <= series.LastIndex(); i++ {
+				values := test.indicator.CalculateAll(i)
+
+				for name, expected := range test.outputs {
+					assert.EqualValues(t, expected.Calculate(i).String(), values[name].String(), "%v at index %v", name, i)
+					assert.Equal(t, Lookback(expected), Lookback(test.indicator.Output(name)), name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSupertrend(t *testing.T) {
+	ts := mockTimeSeriesOCHL(
+		[]float64{1.0, 2.0, 2.5, 0.5},
+		[]float64{2.0, 3.0, 3.5, 1.5},
+		[]float64{3.0, 4.0, 4.5, 2.5},
+		[]float64{4.0, 5.0, 5.5, 3.5},
+	)
+
+	supertrend := NewSupertrend(ts, 3, 2)
+
+	indicatorEquals(t, []float64{1.5, 2.5, 3.5, 3.5}, supertrend.Output(OutputSupertrend))
+	indicatorEquals(t, []float64{1, 1, 1, 1}, supertrend.Output(OutputDirection))
+	assert.False(t, IsValid(supertrend.Output(OutputDirection), 2))
+	assert.True(t, IsValid(supertrend.Output(OutputDirection), 3))
+}
+
+func TestNewSupertrend_Direction(t *testing.T) {
+	ts := mockTimeSeriesOCHL(
+		[]float64{2.0, 2.0, 2.0, 2.0},
+		[]float64{2.0, 3.0, 3.0, 2.0},
+		[]float64{3.0, 1.0, 3.0, 1.0},
+		[]float64{1.0, 1.0, 1.0, 1.0},
+		[]float64{1.0, 2.0, 2.0, 1.0},
+	)
+
+	// without a multiplier both bands are the average price, and the trend only turns when the close
+	// crosses them
+	supertrend := NewSupertrend(ts, 2, 0)
+
+	indicatorEquals(t, []float64{2.0, 2.5, 2.0, 1.0, 1.5}, supertrend.Output(OutputSupertrend))
+	indicatorEquals(t, []float64{1, 1, -1, -1, 1}, supertrend.Output(OutputDirection))
+}
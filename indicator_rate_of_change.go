@@ -10,7 +10,7 @@ type rateOfChangeIndicator struct {
 // The rate of change indicator calculates the total change over a window divided by the number
 // of candles in the window.
 func NewRateOfChangeIndicator(indicator Indicator, window int) Indicator {
-	return rateOfChangeIndicator{indicator: indicator, window: window}
+	return cacheIndicator(rateOfChangeIndicator{indicator: indicator, window: window}, indicator)
 }
 
 func (roc rateOfChangeIndicator) Calculate(index int) big.Decimal {
@@ -23,3 +23,7 @@ func (roc rateOfChangeIndicator) Calculate(index int) big.Decimal {
 
 	return end.Sub(start).Div(big.NewFromInt(roc.window))
 }
+
+func (roc rateOfChangeIndicator) Lookback() int {
+	return Lookback(roc.indicator) + Max(roc.window-1, 0)
+}
@@ -15,10 +15,10 @@ type relativeStrengthIndexIndicator struct {
 // in a given time frame. A more in-depth explanation of relative strength index can be found here:
 // https://www.investopedia.com/terms/r/rsi.asp
 func NewRelativeStrengthIndexIndicator(indicator Indicator, timeframe int) Indicator {
-	return relativeStrengthIndexIndicator{
+	return cacheIndicator(relativeStrengthIndexIndicator{
 		rsIndicator: NewRelativeStrengthIndicator(indicator, timeframe),
 		oneHundred:  big.NewFromString("100"),
-	}
+	}, indicator)
 }
 
 func (rsi relativeStrengthIndexIndicator) Calculate(index int) big.Decimal {
@@ -37,11 +37,11 @@ type relativeStrengthIndicator struct {
 // in a given time frame. Relative strength is the average again of up periods during the time frame divided by the
 // average loss of down period during the same time frame
 func NewRelativeStrengthIndicator(indicator Indicator, timeframe int) Indicator {
-	return relativeStrengthIndicator{
+	return cacheIndicator(relativeStrengthIndicator{
 		avgGain: NewMMAIndicator(NewGainIndicator(indicator), timeframe),
 		avgLoss: NewMMAIndicator(NewLossIndicator(indicator), timeframe),
 		window:  timeframe,
-	}
+	}, indicator)
 }
 
 func (rs relativeStrengthIndicator) Calculate(index int) big.Decimal {
@@ -58,3 +58,11 @@ func (rs relativeStrengthIndicator) Calculate(index int) big.Decimal {
 
 	return avgGain.Div(avgLoss)
 }
+
+func (rsi relativeStrengthIndexIndicator) Lookback() int {
+	return Lookback(rsi.rsIndicator)
+}
+
+func (rs relativeStrengthIndicator) Lookback() int {
+	return Max(maxLookback(rs.avgGain, rs.avgLoss), rs.window-1)
+}
@@ -12,10 +12,10 @@ type relativeVigorIndexIndicator struct {
 // by the difference between the previous four days high and low prices. A more in-depth explanation of relative vigor
 // index can be found here: https://www.fidelity.com/learning-center/trading-investing/technical-analysis/technical-indicator-guide/relative-vigor-index
 func NewRelativeVigorIndexIndicator(series *TimeSeries) Indicator {
-	return relativeVigorIndexIndicator{
+	return NewCachedIndicator(relativeVigorIndexIndicator{
 		numerator:   NewDifferenceIndicator(NewClosePriceIndicator(series), NewOpenPriceIndicator(series)),
 		denominator: NewDifferenceIndicator(NewHighPriceIndicator(series), NewLowPriceIndicator(series)),
-	}
+	}, series)
 }
 
 func (rvii relativeVigorIndexIndicator) Calculate(index int) big.Decimal {
@@ -49,9 +49,19 @@ type relativeVigorIndexSignalLine struct {
 // NewRelativeVigorSignalLine returns an Indicator intended to be used in conjunction with Relative vigor index, which
 // returns the average value of the last 4 indices of the RVI indicator.
 func NewRelativeVigorSignalLine(series *TimeSeries) Indicator {
-	return relativeVigorIndexSignalLine{
-		relativeVigorIndex: NewRelativeVigorIndexIndicator(series),
-	}
+	return NewRelativeVigorIndex(series).Output(OutputSignal)
+}
+
+// NewRelativeVigorIndex returns a MultiOutputIndicator of the relative vigor index and its signal line, which is
+// calculated from the same index.
+func NewRelativeVigorIndex(series *TimeSeries) MultiOutputIndicator {
+	rvi := NewRelativeVigorIndexIndicator(series)
+
+	return NewMultiOutputIndicator(
+		[]string{OutputRVI, OutputSignal},
+		rvi,
+		NewCachedIndicator(relativeVigorIndexSignalLine{relativeVigorIndex: rvi}, series),
+	)
 }
 
 func (rvsn relativeVigorIndexSignalLine) Calculate(index int) big.Decimal {
@@ -66,3 +76,11 @@ func (rvsn relativeVigorIndexSignalLine) Calculate(index int) big.Decimal {
 
 	return (rvi.Add(i).Add(j).Add(k)).Div(big.NewFromString("6"))
 }
+
+func (rvii relativeVigorIndexIndicator) Lookback() int {
+	return 3
+}
+
+func (rvsn relativeVigorIndexSignalLine) Lookback() int {
+	return Lookback(rvsn.relativeVigorIndex) + 3
+}
@@ -3,14 +3,14 @@ package techan
 import "github.com/schmidthole/big"
 
 type smaIndicator struct {
-	indicator Indicator
-	window    int
+	sum    Indicator
+	window int
 }
 
 // NewSimpleMovingAverage returns a derivative Indicator which returns the average of the current value and preceding
 // values in the given windowSize.
 func NewSimpleMovingAverage(indicator Indicator, window int) Indicator {
-	return smaIndicator{indicator, window}
+	return cacheIndicator(smaIndicator{newWindowSumIndicator(indicator, window), window}, indicator)
 }
 
 func (sma smaIndicator) Calculate(index int) big.Decimal {
@@ -18,12 +18,9 @@ func (sma smaIndicator) Calculate(index int) big.Decimal {
 		return big.ZERO
 	}
 
-	sum := big.ZERO
-	for i := index; i > index-sma.window; i-- {
-		sum = sum.Add(sma.indicator.Calculate(i))
-	}
-
-	result := sum.Div(big.NewFromInt(sma.window))
+	return sma.sum.Calculate(index).Div(big.NewFromInt(sma.window))
+}
 
-	return result
+func (sma smaIndicator) Lookback() int {
+	return Lookback(sma.sum)
 }
@@ -1,6 +1,11 @@
 package techan
 
-import "testing"
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func TestSimpleMovingAverage(t *testing.T) {
 	expectedValues := []float64{
@@ -22,3 +27,12 @@ func TestSimpleMovingAverage(t *testing.T) {
 
 	indicatorEquals(t, expectedValues, NewSimpleMovingAverage(closePriceIndicator, 3))
 }
+
+func TestSimpleMovingAverage_NaN(t *testing.T) {
+	indicator := NewSimpleMovingAverage(NewFixedIndicator(1, 2, math.NaN(), 4, 5, 6), 2)
+
+	assert.True(t, indicator.Calculate(2).NaN())
+	assert.True(t, indicator.Calculate(3).NaN())
+	decimalEquals(t, 4.5, indicator.Calculate(4))
+	decimalEquals(t, 5.5, indicator.Calculate(5))
+}
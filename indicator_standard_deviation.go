@@ -7,9 +7,9 @@ import (
 // NewStandardDeviationIndicator calculates the standard deviation of a base indicator.
 // See https://www.investopedia.com/terms/s/standarddeviation.asp
 func NewStandardDeviationIndicator(ind Indicator) Indicator {
-	return standardDeviationIndicator{
+	return cacheIndicator(standardDeviationIndicator{
 		indicator: NewVarianceIndicator(ind),
-	}
+	}, ind)
 }
 
 type standardDeviationIndicator struct {
@@ -20,3 +20,7 @@ type standardDeviationIndicator struct {
 func (sdi standardDeviationIndicator) Calculate(index int) big.Decimal {
 	return sdi.indicator.Calculate(index).Sqrt()
 }
+
+func (sdi standardDeviationIndicator) Lookback() int {
+	return Lookback(sdi.indicator)
+}
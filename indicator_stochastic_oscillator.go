@@ -17,12 +17,12 @@ type kIndicator struct {
 // given window.
 // https://www.investopedia.com/terms/s/stochasticoscillator.asp
 func NewFastStochasticIndicator(series *TimeSeries, timeframe int) Indicator {
-	return kIndicator{
+	return NewCachedIndicator(kIndicator{
 		closePrice: NewClosePriceIndicator(series),
 		minValue:   NewMinimumValueIndicator(NewLowPriceIndicator(series), timeframe),
 		maxValue:   NewMaximumValueIndicator(NewHighPriceIndicator(series), timeframe),
 		window:     timeframe,
-	}
+	}, series)
 }
 
 func (k kIndicator) Calculate(index int) big.Decimal {
@@ -39,6 +39,7 @@ func (k kIndicator) Calculate(index int) big.Decimal {
 
 type dIndicator struct {
 	k      Indicator
+	sma    Indicator
 	window int
 }
 
@@ -46,9 +47,26 @@ type dIndicator struct {
 // given window.
 // https://www.investopedia.com/terms/s/stochasticoscillator.asp
 func NewSlowStochasticIndicator(k Indicator, window int) Indicator {
-	return dIndicator{k, window}
+	return cacheIndicator(dIndicator{k, NewSimpleMovingAverage(k, window), window}, k)
 }
 
 func (d dIndicator) Calculate(index int) big.Decimal {
-	return NewSimpleMovingAverage(d.k, d.window).Calculate(index)
+	return d.sma.Calculate(index)
+}
+
+// NewStochasticOscillator returns a MultiOutputIndicator of the fast stochastic (%K) for the given timeframe, and the
+// slow stochastic (%D) which averages it over window.
+// https://www.investopedia.com/terms/s/stochasticoscillator.asp
+func NewStochasticOscillator(series *TimeSeries, timeframe, window int) MultiOutputIndicator {
+	k := NewFastStochasticIndicator(series, timeframe)
+
+	return NewMultiOutputIndicator([]string{OutputK, OutputD}, k, NewSlowStochasticIndicator(k, window))
+}
+
+func (k kIndicator) Lookback() int {
+	return maxLookback(k.minValue, k.maxValue)
+}
+
+func (d dIndicator) Lookback() int {
+	return Lookback(d.k) + d.window - 1
 }
@@ -5,7 +5,9 @@ import (
 )
 
 type supertrendIndicator struct {
-	supertrend []big.Decimal
+	values   []big.Decimal
+	offset   int
+	lookback int
 }
 
 // NewSupertrendIndicator returns a derivative indicator which calculates the well-known
@@ -16,6 +18,12 @@ type supertrendIndicator struct {
 //
 // https://www.investopedia.com/supertrend-indicator-7976167
 func NewSupertrendIndicator(series *TimeSeries, window int, multiplier int) Indicator {
+	return NewSupertrend(series, window, multiplier).Output(OutputSupertrend)
+}
+
+// NewSupertrend returns a MultiOutputIndicator of the supertrend and its direction, which is 1 while the supertrend
+// follows the lower band in an uptrend and -1 while it follows the upper band in a downtrend.
+func NewSupertrend(series *TimeSeries, window int, multiplier int) MultiOutputIndicator {
 	atr := NewAverageTrueRangeIndicator(series, window)
 	highs := NewHighPriceIndicator(series)
 	lows := NewLowPriceIndicator(series)
@@ -24,60 +32,99 @@ func NewSupertrendIndicator(series *TimeSeries, window int, multiplier int) Indi
 	multiplierAsDecimal := big.NewDecimal(float64(multiplier))
 	avgDivisorAsDecimal := big.NewDecimal(2.0)
 
-	basicUpperBand := make([]big.Decimal, series.LastIndex()+1)
-	basicLowerBand := make([]big.Decimal, series.LastIndex()+1)
-	finalUpperBand := make([]big.Decimal, series.LastIndex()+1)
-	finalLowerBand := make([]big.Decimal, series.LastIndex()+1)
+	// only the retained candles of a bounded series are calculated, and the average true range is
+	// zero until its window no longer reaches back to evicted candles
+	first := series.FirstIndex()
+	size := len(series.Candles)
+
+	basicUpperBand := make([]big.Decimal, size)
+	basicLowerBand := make([]big.Decimal, size)
+	finalUpperBand := make([]big.Decimal, size)
+	finalLowerBand := make([]big.Decimal, size)
+
+	supertrend := make([]big.Decimal, size)
+	direction := make([]big.Decimal, size)
 
-	supertrend := make([]big.Decimal, series.LastIndex()+1)
+	// the trend is tracked rather than inferred from which band the supertrend equals, as both bands
+	// may be the same
+	uptrend := true
+
+	for p := 0; p < size; p++ {
+		i := first + p
 
-	for i := 0; i <= series.LastIndex(); i++ {
 		avgPrice := highs.Calculate(i).Add(lows.Calculate(i)).Div(avgDivisorAsDecimal)
-		atrValue := atr.Calculate(i)
+		atrValue := big.ZERO
+		if p >= Lookback(atr) {
+			atrValue = atr.Calculate(i)
+		}
 
 		atrDiff := multiplierAsDecimal.Mul(atrValue)
 
-		basicUpperBand[i] = avgPrice.Add(atrDiff)
-		basicLowerBand[i] = avgPrice.Sub(atrDiff)
+		basicUpperBand[p] = avgPrice.Add(atrDiff)
+		basicLowerBand[p] = avgPrice.Sub(atrDiff)
 
-		if i == 0 {
-			finalUpperBand[i] = basicUpperBand[i]
-			finalLowerBand[i] = basicLowerBand[i]
-			supertrend[i] = basicLowerBand[i] // Initialize to first final lower band
+		if p == 0 {
+			finalUpperBand[p] = basicUpperBand[p]
+			finalLowerBand[p] = basicLowerBand[p]
+			supertrend[p] = basicLowerBand[p] // Initialize to first final lower band
+			direction[p] = big.ONE
 			continue
 		}
 
 		close := closes.Calculate(i)
 		lastClose := closes.Calculate(i - 1)
 
-		if basicUpperBand[i].LT(finalUpperBand[i-1]) || lastClose.GT(finalUpperBand[i-1]) {
-			finalUpperBand[i] = basicUpperBand[i]
+		if basicUpperBand[p].LT(finalUpperBand[p-1]) || lastClose.GT(finalUpperBand[p-1]) {
+			finalUpperBand[p] = basicUpperBand[p]
 		} else {
-			finalUpperBand[i] = finalUpperBand[i-1]
+			finalUpperBand[p] = finalUpperBand[p-1]
 		}
 
-		if basicLowerBand[i].GT(finalLowerBand[i-1]) || lastClose.LT(finalLowerBand[i-1]) {
-			finalLowerBand[i] = basicLowerBand[i]
+		if basicLowerBand[p].GT(finalLowerBand[p-1]) || lastClose.LT(finalLowerBand[p-1]) {
+			finalLowerBand[p] = basicLowerBand[p]
 		} else {
-			finalLowerBand[i] = finalLowerBand[i-1]
+			finalLowerBand[p] = finalLowerBand[p-1]
+		}
+
+		if lastClose.LTE(finalUpperBand[p-1]) && close.GT(finalUpperBand[p]) {
+			uptrend = true
+		} else if lastClose.GTE(finalLowerBand[p-1]) && close.LT(finalLowerBand[p]) {
+			uptrend = false
 		}
 
-		if lastClose.LTE(finalUpperBand[i-1]) && close.GT(finalUpperBand[i]) {
-			supertrend[i] = finalLowerBand[i]
-		} else if lastClose.GTE(finalLowerBand[i-1]) && close.LT(finalLowerBand[i]) {
-			supertrend[i] = finalUpperBand[i]
+		if uptrend {
+			supertrend[p] = finalLowerBand[p]
+			direction[p] = big.ONE
 		} else {
-			if supertrend[i-1].EQ(finalUpperBand[i-1]) {
-				supertrend[i] = finalUpperBand[i]
-			} else {
-				supertrend[i] = finalLowerBand[i]
-			}
+			supertrend[p] = finalUpperBand[p]
+			direction[p] = big.ONE.Neg()
 		}
 	}
 
-	return supertrendIndicator{supertrend: supertrend}
+	return NewMultiOutputIndicator(
+		[]string{OutputSupertrend, OutputDirection},
+		supertrendIndicator{values: supertrend, offset: first, lookback: Lookback(atr)},
+		supertrendIndicator{values: direction, offset: first, lookback: Lookback(atr)},
+	)
 }
 
 func (s supertrendIndicator) Calculate(index int) big.Decimal {
-	return s.supertrend[index]
+	if index < s.offset {
+		panic(EvictedIndexError{Index: index, FirstIndex: s.offset})
+	}
+
+	return s.values[index-s.offset]
+}
+
+func (s supertrendIndicator) FirstIndex() int {
+	return s.offset
+}
+
+func (s supertrendIndicator) Lookback() int {
+	return s.lookback
+}
+
+// IsValid returns true once the average true range has a full window of retained candles
+func (s supertrendIndicator) IsValid(index int) bool {
+	return index >= s.offset+s.lookback && index < s.offset+len(s.values)
 }
@@ -1,6 +1,10 @@
 package techan
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func TestNewSupertrendIndicator(t *testing.T) {
 	ts := mockTimeSeriesOCHL(
@@ -10,8 +14,36 @@ func TestNewSupertrendIndicator(t *testing.T) {
 		[]float64{4.0, 5.0, 5.5, 3.5},
 	)
 
+	// the bands are the same until the average true range has a full window, and the rising closes
+	// keep the supertrend on the lower band
 	indicator := NewSupertrendIndicator(ts, 3, 2)
-	expectedValues := []float64{1.5, 2.5, 3.5, 8.5}
+	expectedValues := []float64{1.5, 2.5, 3.5, 3.5}
 
 	indicatorEquals(t, expectedValues, indicator)
 }
+
+func TestNewSupertrendIndicator_Bounded(t *testing.T) {
+	full := randomTimeSeries(100)
+	bounded := NewBoundedTimeSeries(20)
+	retained := NewTimeSeries()
+	for i, candle := range full.Candles {
+		bounded.AddCandle(candle)
+		if i >= 80 {
+			retained.AddCandle(candle)
+		}
+	}
+
+	supertrend := NewSupertrendIndicator(bounded, 5, 3)
+	expected := NewSupertrendIndicator(retained, 5, 3)
+
+	assert.Equal(t, 80, firstIndex(supertrend))
+	assert.False(t, IsValid(supertrend, 84))
+	assert.True(t, IsValid(supertrend, 85))
+	assert.PanicsWithValue(t, EvictedIndexError{Index: 79, FirstIndex: 80}, func() {
+		supertrend.Calculate(79)
+	})
+
+	for i := 80; i <= bounded.LastIndex(); i++ {
+		assert.EqualValues(t, expected.Calculate(i-80).String(), supertrend.Calculate(i).String(), "index %v", i)
+	}
+}
@@ -0,0 +1,85 @@
+package techan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookback(t *testing.T) {
+	series := randomTimeSeries(100)
+	closePrice := NewClosePriceIndicator(series)
+
+	for _, test := range []struct {
+		name      string
+		indicator Indicator
+		lookback  int
+	}{
+		{"close price", closePrice, 0},
+		{"constant", NewConstantIndicator(1), 0},
+		{"sma", NewSimpleMovingAverage(closePrice, 10), 9},
+		{"sma of sma", NewSimpleMovingAverage(NewSimpleMovingAverage(closePrice, 10), 5), 13},
+		{"ema", NewEMAIndicator(closePrice, 10), 9},
+		{"rsi", NewRelativeStrengthIndexIndicator(closePrice, 14), 14},
+		{"atr", NewAverageTrueRangeIndicator(series, 14), 14},
+		{"macd", NewMACDIndicator(closePrice, 12, 26), 25},
+		{"macd histogram", NewMACDHistogramIndicator(NewMACDIndicator(closePrice, 12, 26), 9), 33},
+		{"bollinger", NewBollingerUpperBandIndicator(closePrice, 20, 2), 19},
+		{"stochastic", NewSlowStochasticIndicator(NewFastStochasticIndicator(series, 14), 3), 15},
+		{"trend", NewTrendlineIndicator(closePrice, 5), 4},
+		{"binary frequency", NewBinaryFrequencyIndicator([]Indicator{NewSimpleMovingAverage(closePrice, 3)}, 5, 0), 6},
+		{"derivative", DerivativeIndicator{Indicator: closePrice}, 1},
+		{"variance", NewVarianceIndicator(closePrice), 1},
+		{"relative vigor signal", NewRelativeVigorSignalLine(series), 6},
+		{"supertrend", NewSupertrendIndicator(series, 10, 3), 10},
+		{"sma of supertrend", NewSimpleMovingAverage(NewSupertrendIndicator(series, 10, 3), 5), 14},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.lookback, Lookback(test.indicator))
+
+			if test.lookback > 0 {
+				assert.False(t, IsValid(test.indicator, test.lookback-1))
+			}
+			assert.True(t, IsValid(test.indicator, test.lookback))
+			assert.True(t, IsValid(test.indicator, series.LastIndex()))
+		})
+	}
+
+	t.Run("supertrend is valid after its average true range", func(t *testing.T) {
+		supertrend := NewSupertrendIndicator(series, 10, 3)
+
+		assert.False(t, IsValid(supertrend, 9))
+		assert.True(t, IsValid(supertrend, 10))
+		assert.False(t, IsValid(supertrend, series.LastIndex()+1))
+	})
+}
+
+func TestIsValid(t *testing.T) {
+	t.Run("past the last candle", func(t *testing.T) {
+		series := mockTimeSeriesFl(1, 2, 3)
+
+		assert.True(t, IsValid(NewClosePriceIndicator(series), 2))
+		assert.False(t, IsValid(NewClosePriceIndicator(series), 3))
+		assert.False(t, IsValid(NewSimpleMovingAverage(NewClosePriceIndicator(series), 2), 3))
+	})
+
+	t.Run("evicted candles", func(t *testing.T) {
+		series := NewBoundedTimeSeries(3)
+		for _, candle := range mockTimeSeriesFl(1, 2, 3, 4, 5).Candles {
+			series.AddCandle(candle)
+		}
+
+		assert.False(t, IsValid(NewClosePriceIndicator(series), 1))
+		assert.True(t, IsValid(NewClosePriceIndicator(series), 2))
+	})
+
+	t.Run("fixed values", func(t *testing.T) {
+		fixed := NewFixedIndicator(1, 2, 3)
+
+		assert.True(t, IsValid(fixed, 2))
+		assert.False(t, IsValid(fixed, 3))
+		assert.False(t, IsValid(fixed, -1))
+		assert.False(t, IsValid(NewSimpleMovingAverage(fixed, 2), 0))
+		assert.True(t, IsValid(NewSimpleMovingAverage(fixed, 2), 1))
+	})
+}
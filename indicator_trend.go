@@ -10,10 +10,10 @@ type trendLineIndicator struct {
 // NewTrendlineIndicator returns an indicator whose output is the slope of the trend
 // line given by the values in the window.
 func NewTrendlineIndicator(indicator Indicator, window int) Indicator {
-	return trendLineIndicator{
+	return cacheIndicator(trendLineIndicator{
 		indicator: indicator,
 		window:    window,
-	}
+	}, indicator)
 }
 
 func (tli trendLineIndicator) Calculate(index int) big.Decimal {
@@ -70,3 +70,7 @@ func sumX2(decimals []big.Decimal) big.Decimal {
 
 	return b
 }
+
+func (tli trendLineIndicator) Lookback() int {
+	return Lookback(tli.indicator) + tli.window - 1
+}
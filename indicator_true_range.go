@@ -10,9 +10,9 @@ type trueRangeIndicator struct {
 // which calculates the true range at the current point in time for a series
 // https://www.investopedia.com/terms/a/atr.asp
 func NewTrueRangeIndicator(series *TimeSeries) Indicator {
-	return trueRangeIndicator{
+	return NewCachedIndicator(trueRangeIndicator{
 		series: series,
-	}
+	}, series)
 }
 
 func (tri trueRangeIndicator) Calculate(index int) big.Decimal {
@@ -20,11 +20,19 @@ func (tri trueRangeIndicator) Calculate(index int) big.Decimal {
 		return big.ZERO
 	}
 
-	candle := tri.series.Candles[index]
-	previousClose := tri.series.Candles[index-1].ClosePrice
+	candle := tri.series.Candle(index)
+	previousClose := tri.series.Candle(index - 1).ClosePrice
 
 	trueHigh := big.MaxSlice(candle.MaxPrice, previousClose)
 	trueLow := big.MinSlice(candle.MinPrice, previousClose)
 
 	return trueHigh.Sub(trueLow)
 }
+
+func (tri trueRangeIndicator) FirstIndex() int {
+	return tri.series.FirstIndex()
+}
+
+func (tri trueRangeIndicator) Lookback() int {
+	return 1
+}
@@ -5,9 +5,9 @@ import "github.com/schmidthole/big"
 // NewVarianceIndicator provides a way to find the variance in a base indicator, where variances is the sum of squared
 // deviations from the mean at any given index in the time series.
 func NewVarianceIndicator(ind Indicator) Indicator {
-	return varianceIndicator{
+	return cacheIndicator(varianceIndicator{
 		Indicator: ind,
-	}
+	}, ind)
 }
 
 type varianceIndicator struct {
@@ -20,8 +20,8 @@ func (vi varianceIndicator) Calculate(index int) big.Decimal {
 		return big.ZERO
 	}
 
-	avgIndicator := NewSimpleMovingAverage(vi.Indicator, index+1)
-	avg := avgIndicator.Calculate(index)
+	// the window grows with the index, so the average is not worth caching
+	avg := windowSum(vi.Indicator, index, index+1).Div(big.NewFromInt(index + 1))
 	variance := big.ZERO
 
 	for i := 0; i <= index; i++ {
@@ -31,3 +31,7 @@ func (vi varianceIndicator) Calculate(index int) big.Decimal {
 
 	return variance.Div(big.NewDecimal(float64(index + 1)))
 }
+
+func (vi varianceIndicator) Lookback() int {
+	return Lookback(vi.Indicator) + 1
+}
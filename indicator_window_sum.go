@@ -0,0 +1,116 @@
+package techan
+
+import (
+	"math"
+	"sync"
+
+	"github.com/schmidthole/big"
+)
+
+// windowSumIndicator keeps the running sum of the values of an indicator over a window: each sum is
+// the one before it plus the newest value, minus the value which left the window. The first sum adds
+// up its window, as do sums whose oldest value is within the lookback of the indicator, so that
+// the values of a bounded series are not calculated on evicted candles. The streams keep their sums
+// the same way, so that they match the indicators bit for bit.
+type windowSumIndicator struct {
+	indicator   Indicator
+	window      int
+	lookback    int
+	resultCache resultCache
+	mutex       *sync.Mutex
+	series      []*TimeSeries
+}
+
+func newWindowSumIndicator(indicator Indicator, window int) *windowSumIndicator {
+	return &windowSumIndicator{
+		indicator:   indicator,
+		window:      window,
+		lookback:    Lookback(indicator),
+		resultCache: newResultCache(1000),
+		mutex:       &sync.Mutex{},
+		series:      seriesOf(indicator),
+	}
+}
+
+func (ws *windowSumIndicator) Calculate(index int) big.Decimal {
+	sum := func(i int) big.Decimal {
+		return windowSum(ws.indicator, i, ws.window)
+	}
+
+	if cachedValue := returnIfCached(ws, index, sum); cachedValue != nil {
+		return *cachedValue
+	}
+
+	var result big.Decimal
+	if index-ws.window < firstIndex(ws.indicator)+ws.lookback {
+		result = sum(index)
+	} else {
+		result = slideWindowSum(ws.Calculate(index-1), ws.indicator.Calculate(index),
+			ws.indicator.Calculate(index-ws.window), func() big.Decimal { return sum(index) })
+	}
+
+	ws.mutex.Lock()
+	cacheResult(ws, index, result)
+	ws.mutex.Unlock()
+
+	return result
+}
+
+func (ws *windowSumIndicator) cache() resultCache { return ws.resultCache }
+
+func (ws *windowSumIndicator) setCache(newCache resultCache) {
+	ws.resultCache = newCache
+}
+
+func (ws *windowSumIndicator) windowSize() int { return ws.window }
+
+func (ws *windowSumIndicator) locker() sync.Locker { return ws.mutex }
+
+func (ws *windowSumIndicator) FirstIndex() int { return firstIndex(ws.indicator) }
+
+func (ws *windowSumIndicator) sourceSeries() []*TimeSeries { return ws.series }
+
+func (ws *windowSumIndicator) Lookback() int { return ws.lookback + ws.window - 1 }
+
+// windowSum adds up the values of an indicator over the window ending at index, from the newest to
+// the oldest
+func windowSum(indicator Indicator, index, window int) big.Decimal {
+	sum := big.ZERO
+	for i := index; i > index-window; i-- {
+		sum = sum.Add(indicator.Calculate(i))
+	}
+
+	return sum
+}
+
+// slideWindowSum returns the sum of a window from the sum of the window before it. The window is
+// summed again if the previous sum is not finite, so that a NaN or infinite value does not stay in
+// the sum after it has left the window.
+func slideWindowSum(previous, newest, oldest big.Decimal, sum func() big.Decimal) big.Decimal {
+	if !isFinite(previous) {
+		return sum()
+	}
+
+	return previous.Add(newest).Sub(oldest)
+}
+
+// slideSquaredDeviations returns the sum of the squared deviations of a window from their average,
+// from that of the window before it and the averages of both windows. The window is summed again if
+// the previous sum is not finite, and rounding errors which would make the sum negative are
+// dropped.
+func slideSquaredDeviations(previous, newest, oldest, average, previousAverage big.Decimal, sum func() big.Decimal) big.Decimal {
+	if !isFinite(previous) {
+		return sum()
+	}
+
+	delta := newest.Sub(oldest).Mul(newest.Sub(average).Add(oldest.Sub(previousAverage)))
+	if squares := previous.Add(delta); !squares.LT(big.ZERO) {
+		return squares
+	}
+
+	return big.ZERO
+}
+
+func isFinite(value big.Decimal) bool {
+	return !value.NaN() && !math.IsInf(value.Float(), 0)
+}
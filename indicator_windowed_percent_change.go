@@ -12,10 +12,10 @@ type windowedPercentChangeIndicator struct {
 // The window percent change indicator calculates the change percentage over a fixed
 // lookback window.
 func NewWindowedPercentChangeIndicator(indicator Indicator, window int) Indicator {
-	return windowedPercentChangeIndicator{
+	return cacheIndicator(windowedPercentChangeIndicator{
 		indicator: indicator,
 		window:    window,
-	}
+	}, indicator)
 }
 
 func (s windowedPercentChangeIndicator) Calculate(index int) big.Decimal {
@@ -43,3 +43,7 @@ func (s windowedPercentChangeIndicator) Calculate(index int) big.Decimal {
 
 	return end.Sub(start).Div(start).Mul(big.NewFromInt(100))
 }
+
+func (s windowedPercentChangeIndicator) Lookback() int {
+	return Lookback(s.indicator) + Max(s.window-1, 0)
+}
@@ -1,31 +1,106 @@
 package techan
 
-import "github.com/schmidthole/big"
+import (
+	"sync"
+
+	"github.com/schmidthole/big"
+)
 
 type windowedStandardDeviationIndicator struct {
-	Indicator
-	movingAverage Indicator
-	window        int
+	squares Indicator
+	window  int
 }
 
 // NewWindowedStandardDeviationIndicator returns a indicator which calculates the standard deviation of the underlying
 // indicator over a window
 func NewWindowedStandardDeviationIndicator(ind Indicator, window int) Indicator {
-	return windowedStandardDeviationIndicator{
-		Indicator:     ind,
-		movingAverage: NewSimpleMovingAverage(ind, window),
+	return cacheIndicator(windowedStandardDeviationIndicator{
+		squares: newSquaredDeviationsIndicator(ind, NewSimpleMovingAverage(ind, window), window),
+		window:  window,
+	}, ind)
+}
+
+func (sdi windowedStandardDeviationIndicator) Calculate(index int) big.Decimal {
+	realwindow := Min(sdi.window, index+1)
+
+	return sdi.squares.Calculate(index).Div(big.NewDecimal(float64(realwindow))).Sqrt()
+}
+
+func (sdi windowedStandardDeviationIndicator) Lookback() int {
+	return Lookback(sdi.squares)
+}
+
+// squaredDeviationsIndicator keeps the running sum of the squared deviations of the values of an
+// indicator over a window from their moving average, which is updated from the sum of the window
+// before it as the window slides. The sums of the first windows, and of those whose oldest value is
+// within the lookback of the indicator, add up the deviations of their window from the oldest to
+// the newest.
+type squaredDeviationsIndicator struct {
+	indicator     Indicator
+	movingAverage Indicator
+	window        int
+	lookback      int
+	resultCache   resultCache
+	mutex         *sync.Mutex
+	series        []*TimeSeries
+}
+
+func newSquaredDeviationsIndicator(indicator, movingAverage Indicator, window int) *squaredDeviationsIndicator {
+	return &squaredDeviationsIndicator{
+		indicator:     indicator,
+		movingAverage: movingAverage,
 		window:        window,
+		lookback:      Lookback(indicator),
+		resultCache:   newResultCache(1000),
+		mutex:         &sync.Mutex{},
+		series:        seriesOf(indicator),
 	}
 }
 
-func (sdi windowedStandardDeviationIndicator) Calculate(index int) big.Decimal {
-	avg := sdi.movingAverage.Calculate(index)
-	variance := big.ZERO
-	for i := Max(0, index-sdi.window+1); i <= index; i++ {
-		pow := sdi.Indicator.Calculate(i).Sub(avg).Pow(2)
-		variance = variance.Add(pow)
+func (sd *squaredDeviationsIndicator) Calculate(index int) big.Decimal {
+	if cachedValue := returnIfCached(sd, index, sd.sum); cachedValue != nil {
+		return *cachedValue
+	}
+
+	var result big.Decimal
+	if index-sd.window < firstIndex(sd.indicator)+sd.lookback {
+		result = sd.sum(index)
+	} else {
+		result = slideSquaredDeviations(sd.Calculate(index-1), sd.indicator.Calculate(index),
+			sd.indicator.Calculate(index-sd.window), sd.movingAverage.Calculate(index),
+			sd.movingAverage.Calculate(index-1), func() big.Decimal { return sd.sum(index) })
+	}
+
+	sd.mutex.Lock()
+	cacheResult(sd, index, result)
+	sd.mutex.Unlock()
+
+	return result
+}
+
+func (sd *squaredDeviationsIndicator) sum(index int) big.Decimal {
+	avg := sd.movingAverage.Calculate(index)
+	squares := big.ZERO
+	for i := Max(0, index-sd.window+1); i <= index; i++ {
+		squares = squares.Add(sd.indicator.Calculate(i).Sub(avg).Pow(2))
 	}
-	realwindow := Min(sdi.window, index+1)
 
-	return variance.Div(big.NewDecimal(float64(realwindow))).Sqrt()
+	return squares
 }
+
+func (sd *squaredDeviationsIndicator) cache() resultCache { return sd.resultCache }
+
+func (sd *squaredDeviationsIndicator) setCache(newCache resultCache) {
+	sd.resultCache = newCache
+}
+
+// windowSize is one, as the sums of partial windows before the first full one are kept too
+func (sd *squaredDeviationsIndicator) windowSize() int { return 1 }
+
+func (sd *squaredDeviationsIndicator) locker() sync.Locker { return sd.mutex }
+
+func (sd *squaredDeviationsIndicator) FirstIndex() int { return firstIndex(sd.indicator) }
+
+func (sd *squaredDeviationsIndicator) sourceSeries() []*TimeSeries { return sd.series }
+
+func (sd *squaredDeviationsIndicator) Lookback() int { return sd.lookback + sd.window - 1 }
@@ -0,0 +1,1113 @@
+package techan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	mathbig "math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file holds a minimal Parquet codec for flat tables, so candles and results can be exchanged
+// with Python and other tools without further dependencies. Reading supports required and optional
+// columns of every physical type, PLAIN and dictionary encoded values in version 1 and 2 data pages,
+// and uncompressed, snappy or gzip compressed pages, which covers files written by pandas, pyarrow
+// and most data vendors. Files are written uncompressed with PLAIN encoded required columns in a
+// single row group.
+// https://parquet.apache.org/docs/file-format/
+
+const parquetMagic = "PAR1"
+
+// limits which keep a damaged or hostile file from exhausting memory before it can be rejected
+const (
+	parquetMaxRows   = 1 << 26
+	parquetMaxScale  = 1 << 10
+	parquetMaxNested = 64
+)
+
+// parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetInt96     = 3
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+	parquetFixedLen  = 7
+)
+
+// parquet converted types, the predecessors of logical types which are still written for
+// compatibility
+const (
+	parquetUtf8            = 0
+	parquetDecimal         = 5
+	parquetDate            = 6
+	parquetTimestampMillis = 9
+	parquetTimestampMicros = 10
+)
+
+// parquet page types and encodings
+const (
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+
+	parquetPlain           = 0
+	parquetPlainDictionary = 2
+	parquetRle             = 3
+	parquetRleDictionary   = 8
+)
+
+var parquetCodecs = []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "BROTLI", "LZ4", "ZSTD", "LZ4_RAW"}
+
+// parquetColumn is a decoded column of a file. Values are bool, int64, float64, []byte or, for
+// INT96 timestamps, time.Time, and nil for nulls.
+type parquetColumn struct {
+	name      string
+	kind      int64
+	converted int64
+	scale     int64
+	unit      time.Duration
+	date      bool
+	optional  bool
+	length    int
+	values    []interface{}
+}
+
+// readParquet decodes every column of a Parquet file.
+func readParquet(data []byte) ([]*parquetColumn, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if length <= 0 || length > len(data)-12 {
+		return nil, fmt.Errorf("invalid parquet footer")
+	}
+
+	reader := &thriftReader{data: data[len(data)-8-length : len(data)-8]}
+	metadata := reader.readStruct()
+	if reader.err != nil {
+		return nil, fmt.Errorf("invalid parquet metadata: %v", reader.err)
+	}
+
+	columns, err := parquetSchema(metadata.list(2))
+	if err != nil {
+		return nil, err
+	}
+
+	total := int64(0)
+	for _, g := range metadata.list(4) {
+		group, ok := g.(thriftStruct)
+		if !ok {
+			return nil, fmt.Errorf("invalid parquet row group")
+		}
+
+		rows := group.int(3)
+		if rows < 0 || rows > parquetMaxRows-total {
+			return nil, fmt.Errorf("invalid parquet row count %v", rows)
+		}
+		total += rows
+
+		chunks := group.list(1)
+		if len(chunks) != len(columns) {
+			return nil, fmt.Errorf("row group has %v columns, the schema has %v", len(chunks), len(columns))
+		}
+
+		for i, c := range chunks {
+			chunk, ok := c.(thriftStruct)
+			if !ok {
+				return nil, fmt.Errorf("column %v: invalid column chunk", columns[i].name)
+			}
+
+			if err := columns[i].readChunk(data, chunk.child(3), rows); err != nil {
+				return nil, fmt.Errorf("column %v: %v", columns[i].name, err)
+			}
+
+			if int64(len(columns[i].values)) != total {
+				return nil, fmt.Errorf("column %v has %v values, expected %v", columns[i].name, len(columns[i].values), total)
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+func parquetSchema(elements []interface{}) ([]*parquetColumn, error) {
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("parquet file has no schema")
+	}
+
+	columns := []*parquetColumn{}
+	for _, e := range elements[1:] {
+		element, ok := e.(thriftStruct)
+		if !ok {
+			return nil, fmt.Errorf("invalid parquet schema")
+		}
+
+		if element.int(5) > 0 {
+			return nil, fmt.Errorf("nested parquet column %v is not supported", element.str(4))
+		}
+
+		if element.int(3) == 2 {
+			return nil, fmt.Errorf("repeated parquet column %v is not supported", element.str(4))
+		}
+
+		if length := element.int(2); element.int(1) == parquetFixedLen && (length <= 0 || length > math.MaxInt32) {
+			return nil, fmt.Errorf("parquet column %v has an invalid length %v", element.str(4), length)
+		}
+
+		column := &parquetColumn{
+			name:      element.str(4),
+			kind:      element.int(1),
+			converted: -1,
+			scale:     element.int(7),
+			optional:  element.int(3) == 1,
+			length:    int(element.int(2)),
+			values:    []interface{}{},
+		}
+
+		if element.has(6) {
+			column.converted = element.int(6)
+		}
+
+		switch column.converted {
+		case parquetTimestampMillis:
+			column.unit = time.Millisecond
+		case parquetTimestampMicros:
+			column.unit = time.Microsecond
+		case parquetDate:
+			column.date = true
+		}
+
+		if logical := element.child(10); logical != nil {
+			if timestamp := logical.child(8); timestamp != nil {
+				unit := timestamp.child(2)
+				switch {
+				case unit.has(1):
+					column.unit = time.Millisecond
+				case unit.has(2):
+					column.unit = time.Microsecond
+				case unit.has(3):
+					column.unit = time.Nanosecond
+				}
+			}
+
+			if decimal := logical.child(5); decimal != nil {
+				column.converted = parquetDecimal
+				column.scale = decimal.int(1)
+			}
+
+			column.date = column.date || logical.has(6)
+		}
+
+		if column.scale < 0 || column.scale > parquetMaxScale {
+			return nil, fmt.Errorf("parquet column %v has an invalid scale %v", column.name, column.scale)
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// readChunk reads the values of a column chunk, which holds rows values in a flat table
+func (c *parquetColumn) readChunk(data []byte, metadata thriftStruct, rows int64) error {
+	codec := metadata.int(4)
+	offset := metadata.int(9)
+	if dictionary := metadata.int(11); dictionary > 0 && dictionary < offset {
+		offset = dictionary
+	}
+
+	size := metadata.int(7)
+	if offset < 4 || offset > int64(len(data)) || size < 0 || size > int64(len(data))-offset {
+		return fmt.Errorf("column chunk is outside of the file")
+	}
+
+	chunk := data[offset : offset+size]
+	remaining := metadata.int(5)
+	if remaining < 0 || remaining > rows {
+		return fmt.Errorf("column chunk has %v values, the row group has %v rows", remaining, rows)
+	}
+
+	var dictionary []interface{}
+
+	for remaining > 0 && len(chunk) > 0 {
+		reader := &thriftReader{data: chunk}
+		header := reader.readStruct()
+		if reader.err != nil {
+			return reader.err
+		}
+
+		size := header.int(3)
+		if size < 0 || size > int64(len(chunk)-reader.pos) {
+			return fmt.Errorf("page is outside of the column chunk")
+		}
+
+		page := chunk[reader.pos : int64(reader.pos)+size]
+		chunk = chunk[int64(reader.pos)+size:]
+
+		switch header.int(1) {
+		case parquetDictionaryPage:
+			page, err := decompressParquet(codec, page, header.int(2))
+			if err != nil {
+				return err
+			}
+
+			count := header.child(7).int(1)
+			if count < 0 || count > int64(len(page))*8 {
+				return fmt.Errorf("invalid dictionary size %v", count)
+			}
+
+			if dictionary, _, err = c.plainValues(page, int(count)); err != nil {
+				return err
+			}
+		case parquetDataPage:
+			page, err := decompressParquet(codec, page, header.int(2))
+			if err != nil {
+				return err
+			}
+
+			pageHeader := header.child(5)
+			count := pageHeader.int(1)
+			if count < 0 || count > remaining {
+				return fmt.Errorf("page has %v values, %v remain in the column chunk", count, remaining)
+			}
+
+			levels := page[:0]
+			if c.optional {
+				if len(page) < 4 {
+					return fmt.Errorf("truncated definition levels")
+				}
+
+				size := int64(binary.LittleEndian.Uint32(page))
+				if size > int64(len(page)-4) {
+					return fmt.Errorf("truncated definition levels")
+				}
+
+				levels, page = page[4:4+size], page[4+size:]
+			}
+
+			if err := c.readPage(page, levels, int(count), pageHeader.int(2), dictionary); err != nil {
+				return err
+			}
+
+			remaining -= count
+		case parquetDataPageV2:
+			pageHeader := header.child(8)
+			count := pageHeader.int(1)
+			if count < 0 || count > remaining {
+				return fmt.Errorf("page has %v values, %v remain in the column chunk", count, remaining)
+			}
+
+			definitionSize, repetitionSize := pageHeader.int(5), pageHeader.int(6)
+			if definitionSize < 0 || repetitionSize < 0 || definitionSize > int64(len(page)) || repetitionSize > int64(len(page))-definitionSize {
+				return fmt.Errorf("truncated definition levels")
+			}
+
+			levels := page[repetitionSize : repetitionSize+definitionSize]
+			values := page[repetitionSize+definitionSize:]
+
+			if !pageHeader.has(7) || pageHeader.bool(7) {
+				var err error
+				uncompressed := header.int(2) - repetitionSize - definitionSize
+				if values, err = decompressParquet(codec, values, uncompressed); err != nil {
+					return err
+				}
+			}
+
+			if err := c.readPage(values, levels, int(count), pageHeader.int(4), dictionary); err != nil {
+				return err
+			}
+
+			remaining -= count
+		}
+	}
+
+	if remaining > 0 {
+		return fmt.Errorf("column chunk is missing %v values", remaining)
+	}
+
+	return nil
+}
+
+func (c *parquetColumn) readPage(page, levels []byte, count int, encoding int64, dictionary []interface{}) error {
+	defined := make([]bool, count)
+	present := count
+
+	if c.optional {
+		definitions, err := decodeHybrid(levels, 1, count)
+		if err != nil {
+			return err
+		}
+
+		present = 0
+		for i, level := range definitions {
+			defined[i] = level == 1
+			if defined[i] {
+				present++
+			}
+		}
+	} else {
+		for i := range defined {
+			defined[i] = true
+		}
+	}
+
+	var values []interface{}
+	switch encoding {
+	case parquetPlain:
+		var err error
+		if values, _, err = c.plainValues(page, present); err != nil {
+			return err
+		}
+	case parquetPlainDictionary, parquetRleDictionary:
+		if len(page) == 0 {
+			return fmt.Errorf("truncated dictionary indexes")
+		}
+
+		indexes, err := decodeHybrid(page[1:], int(page[0]), present)
+		if err != nil {
+			return err
+		}
+
+		values = make([]interface{}, present)
+		for i, index := range indexes {
+			if index >= uint64(len(dictionary)) {
+				return fmt.Errorf("dictionary index %v is out of range", index)
+			}
+			values[i] = dictionary[index]
+		}
+	default:
+		return fmt.Errorf("unsupported parquet encoding %v", encoding)
+	}
+
+	next := 0
+	for _, isDefined := range defined {
+		if isDefined {
+			c.values = append(c.values, values[next])
+			next++
+		} else {
+			c.values = append(c.values, nil)
+		}
+	}
+
+	return nil
+}
+
+// plainValues decodes count PLAIN encoded values, returning them and the number of bytes read
+func (c *parquetColumn) plainValues(data []byte, count int) ([]interface{}, int, error) {
+	values := make([]interface{}, count)
+	pos := 0
+	truncated := fmt.Errorf("truncated page")
+
+	if c.kind == parquetBoolean && (count+7)/8 > len(data) {
+		return nil, 0, truncated
+	}
+
+	for i := 0; i < count; i++ {
+		switch c.kind {
+		case parquetBoolean:
+			values[i] = data[i/8]&(1<<uint(i%8)) != 0
+		case parquetInt32:
+			if pos+4 > len(data) {
+				return nil, 0, truncated
+			}
+			values[i] = int64(int32(binary.LittleEndian.Uint32(data[pos:])))
+			pos += 4
+		case parquetInt64:
+			if pos+8 > len(data) {
+				return nil, 0, truncated
+			}
+			values[i] = int64(binary.LittleEndian.Uint64(data[pos:]))
+			pos += 8
+		case parquetInt96:
+			if pos+12 > len(data) {
+				return nil, 0, truncated
+			}
+			nanos := int64(binary.LittleEndian.Uint64(data[pos:]))
+			julian := int64(binary.LittleEndian.Uint32(data[pos+8:]))
+			values[i] = time.Unix((julian-2440588)*86400, nanos).UTC()
+			pos += 12
+		case parquetFloat:
+			if pos+4 > len(data) {
+				return nil, 0, truncated
+			}
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[pos:])))
+			pos += 4
+		case parquetDouble:
+			if pos+8 > len(data) {
+				return nil, 0, truncated
+			}
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[pos:]))
+			pos += 8
+		case parquetByteArray:
+			if pos+4 > len(data) {
+				return nil, 0, truncated
+			}
+			size := int64(binary.LittleEndian.Uint32(data[pos:]))
+			if size > int64(len(data)-pos-4) {
+				return nil, 0, truncated
+			}
+			values[i] = data[pos+4 : pos+4+int(size)]
+			pos += 4 + int(size)
+		case parquetFixedLen:
+			if pos+c.length > len(data) {
+				return nil, 0, truncated
+			}
+			values[i] = data[pos : pos+c.length]
+			pos += c.length
+		default:
+			return nil, 0, fmt.Errorf("unsupported parquet type %v", c.kind)
+		}
+	}
+
+	if c.kind == parquetBoolean {
+		pos = (count + 7) / 8
+	}
+
+	return values, pos, nil
+}
+
+// text returns a value of the column as text, with numbers formatted so that they parse back to the
+// same decimal. Null values are returned as an empty string.
+func (c *parquetColumn) text(index int) string {
+	switch value := c.values[index].(type) {
+	case nil:
+		return ""
+	case bool:
+		return fmt.Sprint(value)
+	case int64:
+		if c.converted == parquetDecimal {
+			return scaledDecimal(mathbig.NewInt(value), c.scale)
+		}
+		return fmt.Sprint(value)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case []byte:
+		if c.converted == parquetDecimal {
+			unscaled := new(mathbig.Int).SetBytes(value)
+			if len(value) > 0 && value[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(mathbig.Int).Lsh(mathbig.NewInt(1), uint(len(value)*8)))
+			}
+			return scaledDecimal(unscaled, c.scale)
+		}
+		return string(value)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+
+	return ""
+}
+
+// time returns a value of the column as a time, for timestamp, date, INT96 and text columns. Integer
+// columns without a timestamp type are read as epoch timestamps of the magnitude detected by
+// AUTO_TIMESTAMP.
+func (c *parquetColumn) time(index int, location *time.Location) (time.Time, error) {
+	switch value := c.values[index].(type) {
+	case nil:
+		return time.Time{}, fmt.Errorf("missing time")
+	case time.Time:
+		return value.In(location), nil
+	case int64:
+		if c.date {
+			return time.Unix(value*86400, 0).UTC(), nil
+		}
+
+		if c.unit != 0 {
+			return time.Unix(0, 0).Add(time.Duration(value) * c.unit).In(location), nil
+		}
+	}
+
+	return parseTimestamp(c.text(index), AUTO_TIMESTAMP, location)
+}
+
+func scaledDecimal(unscaled *mathbig.Int, scale int64) string {
+	text := new(mathbig.Int).Abs(unscaled).String()
+	if scale > 0 {
+		if pad := int(scale) + 1 - len(text); pad > 0 {
+			text = strings.Repeat("0", pad) + text
+		}
+		text = text[:len(text)-int(scale)] + "." + text[len(text)-int(scale):]
+	}
+
+	if unscaled.Sign() < 0 {
+		text = "-" + text
+	}
+
+	return text
+}
+
+// decompressParquet decompresses a page of the given uncompressed size, which is only trusted as
+// far as the data actually decompresses to it
+func decompressParquet(codec int64, data []byte, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid uncompressed page size %v", size)
+	}
+
+	switch codec {
+	case 0:
+		return data, nil
+	case 1:
+		return decodeSnappy(data)
+	case 2:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		var buffer bytes.Buffer
+		if _, err = io.Copy(&buffer, io.LimitReader(reader, size+1)); err != nil {
+			return nil, err
+		}
+
+		if int64(buffer.Len()) > size {
+			return nil, fmt.Errorf("page is larger than its uncompressed size %v", size)
+		}
+
+		return buffer.Bytes(), nil
+	}
+
+	if codec > 0 && int(codec) < len(parquetCodecs) {
+		return nil, fmt.Errorf("unsupported parquet compression %v", parquetCodecs[codec])
+	}
+
+	return nil, fmt.Errorf("unsupported parquet compression %v", codec)
+}
+
+// decodeSnappy decompresses a snappy block.
+// https://github.com/google/snappy/blob/main/format_description.txt
+func decodeSnappy(data []byte) ([]byte, error) {
+	corrupt := fmt.Errorf("corrupt snappy data")
+
+	size, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, corrupt
+	}
+
+	// no element expands more than 64 times, which bounds the size of valid data
+	if size > uint64(len(data))*64 {
+		return nil, corrupt
+	}
+
+	decoded := make([]byte, 0, size)
+	pos := n
+
+	for pos < len(data) {
+		tag := data[pos]
+		pos++
+
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			length = int(tag>>2) + 1
+			if length > 60 {
+				bytes := length - 60
+				if pos+bytes > len(data) {
+					return nil, corrupt
+				}
+
+				length = 0
+				for i := 0; i < bytes; i++ {
+					length |= int(data[pos+i]) << uint(8*i)
+				}
+				length++
+				pos += bytes
+			}
+
+			if length < 0 || length > len(data)-pos || uint64(len(decoded)+length) > size {
+				return nil, corrupt
+			}
+
+			decoded = append(decoded, data[pos:pos+length]...)
+			pos += length
+			continue
+		case 1:
+			if pos >= len(data) {
+				return nil, corrupt
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag&0xe0)<<3 | int(data[pos])
+			pos++
+		case 2:
+			if pos+2 > len(data) {
+				return nil, corrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(data[pos:]))
+			pos += 2
+		case 3:
+			if pos+4 > len(data) {
+				return nil, corrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		}
+
+		if offset <= 0 || offset > len(decoded) || uint64(len(decoded)+length) > size {
+			return nil, corrupt
+		}
+
+		// copies may overlap the bytes they produce, so they are made a byte at a time
+		start := len(decoded) - offset
+		for i := 0; i < length; i++ {
+			decoded = append(decoded, decoded[start+i])
+		}
+	}
+
+	if uint64(len(decoded)) != size {
+		return nil, corrupt
+	}
+
+	return decoded, nil
+}
+
+// decodeHybrid decodes count values of the given bit width in the RLE/bit-packing hybrid encoding
+// used for definition levels and dictionary indexes.
+func decodeHybrid(data []byte, width, count int) ([]uint64, error) {
+	corrupt := fmt.Errorf("corrupt rle data")
+	if width < 0 || width > 64 {
+		return nil, corrupt
+	}
+
+	values := make([]uint64, 0, count)
+	pos := 0
+
+	for len(values) < count {
+		header, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, corrupt
+		}
+		pos += n
+
+		if header&1 == 0 {
+			run := header >> 1
+			size := (width + 7) / 8
+			if pos+size > len(data) {
+				return nil, corrupt
+			}
+
+			var value uint64
+			for i := 0; i < size; i++ {
+				value |= uint64(data[pos+i]) << uint(8*i)
+			}
+			pos += size
+
+			for i := uint64(0); i < run && len(values) < count; i++ {
+				values = append(values, value)
+			}
+			continue
+		}
+
+		groups := header >> 1
+		if groups > uint64(len(data)) || int(groups)*width > len(data)-pos {
+			return nil, corrupt
+		}
+
+		for i := 0; i < int(groups)*8 && len(values) < count; i++ {
+			var value uint64
+			for b := 0; b < width; b++ {
+				bit := i*width + b
+				if data[pos+bit/8]&(1<<uint(bit%8)) != 0 {
+					value |= 1 << uint(b)
+				}
+			}
+			values = append(values, value)
+		}
+		pos += int(groups) * width
+	}
+
+	return values, nil
+}
+
+// parquetField is a column to write. Values are float64, int64, string or time.Time, which are
+// written as DOUBLE, INT64, UTF8 strings and microsecond UTC timestamps.
+type parquetField struct {
+	name   string
+	values interface{}
+}
+
+// writeParquet writes a file of required, PLAIN encoded columns in a single row group.
+func writeParquet(w io.Writer, fields []parquetField) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("no parquet columns to write")
+	}
+
+	rows := -1
+	for _, field := range fields {
+		count := 0
+		switch values := field.values.(type) {
+		case []float64:
+			count = len(values)
+		case []int64:
+			count = len(values)
+		case []string:
+			count = len(values)
+		case []time.Time:
+			count = len(values)
+		default:
+			return fmt.Errorf("unsupported parquet values %T", values)
+		}
+
+		if rows >= 0 && count != rows {
+			return fmt.Errorf("column %v has %v values, expected %v", field.name, count, rows)
+		}
+		rows = count
+	}
+
+	file := []byte(parquetMagic)
+	schema := []interface{}{[]thriftField{{4, "schema"}, {5, int32(len(fields))}}}
+	chunks := []interface{}{}
+
+	for _, field := range fields {
+		values := []byte{}
+		element := []thriftField{{3, int32(0)}, {4, field.name}}
+		var kind int32
+
+		switch typed := field.values.(type) {
+		case []float64:
+			kind = parquetDouble
+			for _, value := range typed {
+				values = appendUint64(values, math.Float64bits(value))
+			}
+		case []int64:
+			kind = parquetInt64
+			for _, value := range typed {
+				values = appendUint64(values, uint64(value))
+			}
+		case []string:
+			kind = parquetByteArray
+			element = append(element, thriftField{6, int32(parquetUtf8)}, thriftField{10, []thriftField{{1, []thriftField{}}}})
+			for _, value := range typed {
+				values = appendUint32(values, uint32(len(value)))
+				values = append(values, value...)
+			}
+		case []time.Time:
+			kind = parquetInt64
+			unit := []thriftField{{1, true}, {2, []thriftField{{2, []thriftField{}}}}}
+			element = append(element, thriftField{6, int32(parquetTimestampMicros)}, thriftField{10, []thriftField{{8, unit}}})
+			for _, value := range typed {
+				values = appendUint64(values, uint64(value.UnixNano()/int64(time.Microsecond)))
+			}
+		}
+
+		schema = append(schema, append([]thriftField{{1, kind}}, element...))
+
+		header := writeThrift([]thriftField{
+			{1, int32(parquetDataPage)},
+			{2, int32(len(values))},
+			{3, int32(len(values))},
+			{5, []thriftField{
+				{1, int32(rows)},
+				{2, int32(parquetPlain)},
+				{3, int32(parquetRle)},
+				{4, int32(parquetRle)},
+			}},
+		})
+
+		offset := int64(len(file))
+		size := int64(len(header) + len(values))
+		file = append(append(file, header...), values...)
+
+		chunks = append(chunks, []thriftField{
+			{2, offset},
+			{3, []thriftField{
+				{1, kind},
+				{2, thriftList{5, []interface{}{int32(parquetPlain), int32(parquetRle)}}},
+				{3, thriftList{8, []interface{}{field.name}}},
+				{4, int32(0)},
+				{5, int64(rows)},
+				{6, size},
+				{7, size},
+				{9, offset},
+			}},
+		})
+	}
+
+	metadata := writeThrift([]thriftField{
+		{1, int32(1)},
+		{2, thriftList{12, schema}},
+		{3, int64(rows)},
+		{4, thriftList{12, []interface{}{[]thriftField{
+			{1, thriftList{12, chunks}},
+			{2, int64(len(file) - len(parquetMagic))},
+			{3, int64(rows)},
+		}}}},
+		{6, "techan"},
+	})
+
+	file = append(file, metadata...)
+	file = appendUint32(file, uint32(len(metadata)))
+	file = append(file, parquetMagic...)
+
+	_, err := w.Write(file)
+	return err
+}
+
+func appendUint32(data []byte, value uint32) []byte {
+	var scratch [4]byte
+	binary.LittleEndian.PutUint32(scratch[:], value)
+	return append(data, scratch[:]...)
+}
+
+// thriftStruct is a struct decoded from the thrift compact protocol, keyed by field id. Integers
+// are decoded as int64, lists as []interface{} and structs as thriftStruct.
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s thriftStruct) int(id int16) int64 {
+	value, _ := s[id].(int64)
+	return value
+}
+
+func (s thriftStruct) bool(id int16) bool {
+	value, _ := s[id].(bool)
+	return value
+}
+
+func (s thriftStruct) str(id int16) string {
+	value, _ := s[id].([]byte)
+	return string(value)
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	value, _ := s[id].([]interface{})
+	return value
+}
+
+func (s thriftStruct) child(id int16) thriftStruct {
+	value, _ := s[id].(thriftStruct)
+	return value
+}
+
+// thriftReader decodes the thrift compact protocol used for Parquet metadata.
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+type thriftReader struct {
+	data  []byte
+	pos   int
+	depth int
+	err   error
+}
+
+func (r *thriftReader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("truncated thrift data")
+	}
+	r.pos = len(r.data)
+}
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.fail()
+		return 0
+	}
+
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) varint() int64 {
+	value := r.uvarint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) readStruct() thriftStruct {
+	s := thriftStruct{}
+	var id int16
+
+	for r.err == nil {
+		header := r.byte()
+		if header == 0 {
+			break
+		}
+
+		if delta := int16(header >> 4); delta > 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+
+		s[id] = r.readValue(header & 0x0f)
+	}
+
+	return s
+}
+
+func (r *thriftReader) readValue(kind byte) interface{} {
+	if r.depth++; r.depth > parquetMaxNested {
+		if r.err == nil {
+			r.err = fmt.Errorf("thrift data is nested too deeply")
+		}
+		r.pos = len(r.data)
+		return nil
+	}
+	defer func() { r.depth-- }()
+
+	switch kind {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.varint()
+	case 7:
+		if r.pos+8 > len(r.data) {
+			r.fail()
+			return 0.0
+		}
+		value := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return value
+	case 8:
+		size := int(r.uvarint())
+		if size < 0 || r.pos+size > len(r.data) {
+			r.fail()
+			return []byte{}
+		}
+		value := r.data[r.pos : r.pos+size]
+		r.pos += size
+		return value
+	case 9, 10:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+
+		values := make([]interface{}, 0)
+		for i := 0; i < size && r.err == nil; i++ {
+			element := header & 0x0f
+			if element == 1 || element == 2 {
+				values = append(values, r.byte() == 1)
+				continue
+			}
+			values = append(values, r.readValue(element))
+		}
+		return values
+	case 11:
+		size := int(r.uvarint())
+		if size > 0 {
+			types := r.byte()
+			for i := 0; i < size && r.err == nil; i++ {
+				r.readValue(types >> 4)
+				r.readValue(types & 0x0f)
+			}
+		}
+		return nil
+	case 12:
+		return r.readStruct()
+	}
+
+	if r.err == nil {
+		r.err = fmt.Errorf("unknown thrift type %v", kind)
+	}
+	r.pos = len(r.data)
+	return nil
+}
+
+// thriftField is a field of a struct to encode in the thrift compact protocol. Values are bool,
+// int32, int64, string, thriftList or, for nested structs, []thriftField.
+type thriftField struct {
+	id    int16
+	value interface{}
+}
+
+type thriftList struct {
+	kind   byte
+	values []interface{}
+}
+
+func writeThrift(fields []thriftField) []byte {
+	var id int16
+	data := []byte{}
+
+	for _, field := range fields {
+		kind := thriftKind(field.value)
+		if b, ok := field.value.(bool); ok && !b {
+			kind = 2
+		}
+
+		if delta := field.id - id; delta > 0 && delta <= 15 {
+			data = append(data, byte(delta)<<4|kind)
+		} else {
+			data = append(data, kind)
+			data = appendVarint(data, int64(field.id))
+		}
+		id = field.id
+
+		if kind != 1 && kind != 2 {
+			data = appendThrift(data, field.value)
+		}
+	}
+
+	return append(data, 0)
+}
+
+func thriftKind(value interface{}) byte {
+	switch value.(type) {
+	case bool:
+		return 1
+	case int32:
+		return 5
+	case int64:
+		return 6
+	case string:
+		return 8
+	case thriftList:
+		return 9
+	}
+
+	return 12
+}
+
+func appendThrift(data []byte, value interface{}) []byte {
+	switch typed := value.(type) {
+	case int32:
+		return appendVarint(data, int64(typed))
+	case int64:
+		return appendVarint(data, typed)
+	case string:
+		return append(appendUvarint(data, uint64(len(typed))), typed...)
+	case thriftList:
+		if len(typed.values) < 15 {
+			data = append(data, byte(len(typed.values))<<4|typed.kind)
+		} else {
+			data = appendUvarint(append(data, 0xf0|typed.kind), uint64(len(typed.values)))
+		}
+
+		for _, element := range typed.values {
+			data = appendThrift(data, element)
+		}
+		return data
+	case []thriftField:
+		return append(data, writeThrift(typed)...)
+	}
+
+	return data
+}
@@ -0,0 +1,285 @@
+package techan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// TimeUnit is the unit of a Timeframe.
+type TimeUnit int
+
+// TimeUnit enumerations. Seconds, minutes and hours are fixed durations, while days, weeks and
+// months follow the calendar of the resampling location. Weeks start on a Monday.
+const (
+	SECONDS TimeUnit = iota
+	MINUTES
+	HOURS
+	DAYS
+	WEEKS
+	MONTHS
+)
+
+// A Timeframe is the size of the bars built by a Resampler, e.g. 5 minutes or 1 month.
+type Timeframe struct {
+	Count int
+	Unit  TimeUnit
+}
+
+// Seconds returns a Timeframe of n seconds
+func Seconds(n int) Timeframe { return Timeframe{n, SECONDS} }
+
+// Minutes returns a Timeframe of n minutes
+func Minutes(n int) Timeframe { return Timeframe{n, MINUTES} }
+
+// Hours returns a Timeframe of n hours
+func Hours(n int) Timeframe { return Timeframe{n, HOURS} }
+
+// Days returns a Timeframe of n calendar days
+func Days(n int) Timeframe { return Timeframe{n, DAYS} }
+
+// Weeks returns a Timeframe of n calendar weeks
+func Weeks(n int) Timeframe { return Timeframe{n, WEEKS} }
+
+// Months returns a Timeframe of n calendar months
+func Months(n int) Timeframe { return Timeframe{n, MONTHS} }
+
+// A Trade is a single execution used to build bars.
+type Trade struct {
+	Time   time.Time
+	Amount big.Decimal
+	Price  big.Decimal
+}
+
+// ResampleOptions configures the bar boundaries of a Resampler. Calendar boundaries are evaluated
+// in the Location, which defaults to UTC. SessionStart offsets the boundaries from midnight, so
+// that hourly bars for a session opening at 9:30 start at 9:30, 10:30 and so on, and daily bars
+// for a market opening at 17:00 run from 17:00 to 17:00. Completed bars are added to Series, or
+// to a new unbounded TimeSeries if it is nil.
+type ResampleOptions struct {
+	Location     *time.Location
+	SessionStart time.Duration
+	Series       *TimeSeries
+}
+
+// A Resampler aggregates trades, or candles of a smaller timeframe, into bars of a Timeframe. The
+// open is the first price, the high and low the extremes, the close the last price, and volumes
+// and trade counts are summed. A bar is completed once input for a later bar arrives, once an
+// input candle reaches the end of the bar, or when the resampler is advanced past the end of it.
+// Until then it is available as the partial bar.
+type Resampler struct {
+	Series    *TimeSeries
+	timeframe Timeframe
+	options   ResampleOptions
+	current   *Candle
+}
+
+// NewResampler returns a Resampler which builds bars of the given timeframe.
+func NewResampler(timeframe Timeframe, options ResampleOptions) *Resampler {
+	if timeframe.Count <= 0 {
+		panic(fmt.Errorf("error creating Resampler: timeframe count must be positive"))
+	}
+
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
+
+	series := options.Series
+	if series == nil {
+		series = NewTimeSeries()
+	}
+
+	return &Resampler{
+		Series:    series,
+		timeframe: timeframe,
+		options:   options,
+	}
+}
+
+// Adds a trade to the bar its time falls in. Trades must be added in chronological order.
+func (r *Resampler) AddTrade(timestamp time.Time, amount, price big.Decimal) error {
+	period := r.Period(timestamp)
+
+	if err := r.rollover(period, timestamp); err != nil {
+		return err
+	}
+
+	if r.current == nil {
+		r.current = NewCandle(period)
+	}
+
+	r.current.AddTrade(amount, price)
+
+	return nil
+}
+
+// Adds a candle of a smaller timeframe to the bar it falls in. Candles must be added in
+// chronological order and may not span more than one bar.
+func (r *Resampler) AddCandle(candle *Candle) error {
+	period := r.Period(candle.Period.Start)
+
+	if candle.Period.End.After(period.End) {
+		return fmt.Errorf("candle %v spans more than one %v bar", candle.Period, r.timeframe)
+	}
+
+	if err := r.rollover(period, candle.Period.Start); err != nil {
+		return err
+	}
+
+	if r.current == nil {
+		r.current = NewCandle(period)
+		r.current.OpenPrice = candle.OpenPrice
+		r.current.MaxPrice = candle.MaxPrice
+		r.current.MinPrice = candle.MinPrice
+	} else {
+		r.current.MaxPrice = big.MaxSlice(r.current.MaxPrice, candle.MaxPrice)
+		r.current.MinPrice = big.MinSlice(r.current.MinPrice, candle.MinPrice)
+	}
+
+	r.current.ClosePrice = candle.ClosePrice
+	r.current.Volume = r.current.Volume.Add(candle.Volume)
+	r.current.TradeCount += candle.TradeCount
+
+	if !candle.Period.End.Before(period.End) {
+		r.complete()
+	}
+
+	return nil
+}
+
+// Completes the partial bar if the given time is at or past its end. This closes bars built from
+// trades when no further trades arrive, e.g. on a timer in a live process.
+func (r *Resampler) Advance(now time.Time) {
+	if r.current != nil && !now.Before(r.current.Period.End) {
+		r.complete()
+	}
+}
+
+// Completes the partial bar regardless of its end, e.g. at the end of a data set.
+func (r *Resampler) Flush() {
+	if r.current != nil {
+		r.complete()
+	}
+}
+
+// Partial returns the bar which is still forming, or nil if there is none.
+func (r *Resampler) Partial() *Candle {
+	return r.current
+}
+
+// Period returns the bar period the given time falls in.
+func (r *Resampler) Period(t time.Time) TimePeriod {
+	location := r.options.Location
+	session := r.options.SessionStart
+	count := r.timeframe.Count
+
+	local := t.In(location)
+	shifted := local.Add(-session)
+	year, month, day := shifted.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, location)
+
+	switch r.timeframe.Unit {
+	case DAYS, WEEKS:
+		days := count
+		if r.timeframe.Unit == WEEKS {
+			days = count * 7
+			// move back to the monday of the week
+			day -= (int(midnight.Weekday()) + 6) % 7
+		}
+
+		// align multi day bars on the number of days since monday, 5 january 1970
+		reference := time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+		elapsed := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Sub(reference).Hours() / 24)
+		day -= floorMod(elapsed, days)
+
+		start := time.Date(year, month, day, 0, 0, 0, 0, location)
+		end := time.Date(year, month, day+days, 0, 0, 0, 0, location)
+
+		return TimePeriod{Start: start.Add(session), End: end.Add(session)}
+	case MONTHS:
+		months := int(year)*12 + int(month) - 1
+		months -= floorMod(months, count)
+
+		start := time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, location)
+		end := time.Date(months/12, time.Month(months%12+1)+time.Month(count), 1, 0, 0, 0, 0, location)
+
+		return TimePeriod{Start: start.Add(session), End: end.Add(session)}
+	default:
+		size := r.timeframe.duration()
+		sessionStart := midnight.Add(session)
+
+		elapsed := local.Sub(sessionStart)
+		start := sessionStart.Add(elapsed - time.Duration(floorMod(int(elapsed), int(size))))
+
+		return NewTimePeriod(start, size)
+	}
+}
+
+// ResampleTimeSeries aggregates a series into bars of the given timeframe. The last bar is only
+// included if it is complete, unless includePartial is set.
+func ResampleTimeSeries(series *TimeSeries, timeframe Timeframe, options ResampleOptions, includePartial bool) (*TimeSeries, error) {
+	resampler := NewResampler(timeframe, options)
+
+	if err := BuildBars(resampler, series, includePartial); err != nil {
+		return nil, err
+	}
+
+	return resampler.Series, nil
+}
+
+// ResampleTrades builds bars of the given timeframe from trades in chronological order. The last
+// bar is only included if includePartial is set, since it cannot be known to be complete.
+func ResampleTrades(trades []Trade, timeframe Timeframe, options ResampleOptions, includePartial bool) (*TimeSeries, error) {
+	resampler := NewResampler(timeframe, options)
+
+	if err := BuildBarsFromTrades(resampler, trades, includePartial); err != nil {
+		return nil, err
+	}
+
+	return resampler.Series, nil
+}
+
+func (r *Resampler) rollover(period TimePeriod, t time.Time) error {
+	if r.current == nil {
+		if last := r.Series.LastCandle(); last != nil && t.Before(last.Period.End) {
+			return fmt.Errorf("%v is before the end of the last bar %v", t, last.Period)
+		}
+
+		return nil
+	}
+
+	if period.Start.Before(r.current.Period.Start) {
+		return fmt.Errorf("%v is before the partial bar %v", t, r.current.Period)
+	}
+
+	if period.Start.After(r.current.Period.Start) {
+		r.complete()
+	}
+
+	return nil
+}
+
+func (r *Resampler) complete() {
+	r.Series.AddCandle(r.current)
+	r.current = nil
+}
+
+func (tf Timeframe) duration() time.Duration {
+	switch tf.Unit {
+	case SECONDS:
+		return time.Second * time.Duration(tf.Count)
+	case MINUTES:
+		return time.Minute * time.Duration(tf.Count)
+	default:
+		return time.Hour * time.Duration(tf.Count)
+	}
+}
+
+func (tf Timeframe) String() string {
+	return fmt.Sprintf("%v%v", tf.Count, []string{"s", "m", "h", "d", "w", "M"}[tf.Unit])
+}
+
+func floorMod(a, b int) int {
+	return ((a % b) + b) % b
+}
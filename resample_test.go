@@ -0,0 +1,180 @@
+package techan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func minuteSeries(start time.Time, closes ...float64) *TimeSeries {
+	series := NewTimeSeries()
+
+	for i, close := range closes {
+		candle := NewCandle(NewTimePeriod(start.Add(time.Minute*time.Duration(i)), time.Minute))
+		candle.OpenPrice = big.NewDecimal(close - 1)
+		candle.ClosePrice = big.NewDecimal(close)
+		candle.MaxPrice = big.NewDecimal(close + 1)
+		candle.MinPrice = big.NewDecimal(close - 2)
+		candle.Volume = big.NewDecimal(10)
+		candle.TradeCount = 2
+
+		series.AddCandle(candle)
+	}
+
+	return series
+}
+
+func TestResampleTimeSeries(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	series := minuteSeries(start, 10, 12, 8, 11, 9, 13, 14)
+
+	resampled, err := ResampleTimeSeries(series, Minutes(5), ResampleOptions{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resampled.Candles))
+
+	candle := resampled.Candles[0]
+	assert.Equal(t, NewTimePeriod(start, time.Minute*5), candle.Period)
+	decimalEquals(t, 9, candle.OpenPrice)
+	decimalEquals(t, 13, candle.MaxPrice)
+	decimalEquals(t, 6, candle.MinPrice)
+	decimalEquals(t, 9, candle.ClosePrice)
+	decimalEquals(t, 50, candle.Volume)
+	assert.EqualValues(t, 10, candle.TradeCount)
+
+	resampled, err = ResampleTimeSeries(series, Minutes(5), ResampleOptions{}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(resampled.Candles))
+	decimalEquals(t, 14, resampled.Candles[1].ClosePrice)
+	assert.EqualValues(t, 4, resampled.Candles[1].TradeCount)
+
+	_, err = ResampleTimeSeries(resampled, Minutes(3), ResampleOptions{}, false)
+	assert.EqualError(t, err, "candle 2020-01-02T09:30:00 -> 2020-01-02T09:35:00 spans more than one 3m bar")
+}
+
+func TestResampler_Trades(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 9, 30, 0, 0, time.UTC)
+	resampler := NewResampler(Minutes(1), ResampleOptions{})
+
+	assert.Nil(t, resampler.AddTrade(start.Add(time.Second*5), big.NewDecimal(1), big.NewDecimal(10)))
+	assert.Nil(t, resampler.AddTrade(start.Add(time.Second*30), big.NewDecimal(2), big.NewDecimal(12)))
+	assert.Equal(t, 0, len(resampler.Series.Candles))
+
+	partial := resampler.Partial()
+	if assert.NotNil(t, partial) {
+		decimalEquals(t, 12, partial.ClosePrice)
+		assert.EqualValues(t, 2, partial.TradeCount)
+	}
+
+	// a trade in the next bar completes the partial bar
+	assert.Nil(t, resampler.AddTrade(start.Add(time.Second*65), big.NewDecimal(3), big.NewDecimal(11)))
+	assert.Equal(t, 1, len(resampler.Series.Candles))
+	decimalEquals(t, 3, resampler.Series.Candles[0].Volume)
+	decimalEquals(t, 10, resampler.Series.Candles[0].MinPrice)
+
+	assert.EqualError(t, resampler.AddTrade(start, big.NewDecimal(1), big.NewDecimal(10)),
+		"2020-01-02 09:30:00 +0000 UTC is before the partial bar 2020-01-02T09:31:00 -> 2020-01-02T09:32:00")
+
+	resampler.Advance(start.Add(time.Second * 119))
+	assert.NotNil(t, resampler.Partial())
+
+	resampler.Advance(start.Add(time.Second * 120))
+	assert.Nil(t, resampler.Partial())
+	assert.Equal(t, 2, len(resampler.Series.Candles))
+
+	assert.NotNil(t, resampler.AddTrade(start.Add(time.Second*90), big.NewDecimal(1), big.NewDecimal(10)))
+}
+
+func TestResampleTrades(t *testing.T) {
+	start := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+	trades := []Trade{
+		{start.Add(time.Hour), big.NewDecimal(1), big.NewDecimal(10)},
+		{start.Add(time.Hour * 30), big.NewDecimal(1), big.NewDecimal(11)},
+		{start.Add(time.Hour * 50), big.NewDecimal(1), big.NewDecimal(12)},
+	}
+
+	series, err := ResampleTrades(trades, Days(1), ResampleOptions{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series.Candles))
+	assert.Equal(t, time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC), series.Candles[1].Period.Start)
+
+	series, err = ResampleTrades(trades, Days(1), ResampleOptions{}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(series.Candles))
+}
+
+func TestResampler_Period(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name      string
+		timeframe Timeframe
+		options   ResampleOptions
+		time      time.Time
+		start     time.Time
+		end       time.Time
+	}{
+		{
+			name:      "5 minutes",
+			timeframe: Minutes(5),
+			time:      time.Date(2020, time.March, 4, 10, 7, 31, 0, time.UTC),
+			start:     time.Date(2020, time.March, 4, 10, 5, 0, 0, time.UTC),
+			end:       time.Date(2020, time.March, 4, 10, 10, 0, 0, time.UTC),
+		},
+		{
+			name:      "session aligned hours",
+			timeframe: Hours(1),
+			options:   ResampleOptions{Location: newYork, SessionStart: time.Hour*9 + time.Minute*30},
+			time:      time.Date(2020, time.March, 4, 10, 45, 0, 0, newYork),
+			start:     time.Date(2020, time.March, 4, 10, 30, 0, 0, newYork),
+			end:       time.Date(2020, time.March, 4, 11, 30, 0, 0, newYork),
+		},
+		{
+			name:      "session aligned day",
+			timeframe: Days(1),
+			options:   ResampleOptions{Location: newYork, SessionStart: time.Hour * 17},
+			time:      time.Date(2020, time.March, 4, 9, 0, 0, 0, newYork),
+			start:     time.Date(2020, time.March, 3, 17, 0, 0, 0, newYork),
+			end:       time.Date(2020, time.March, 4, 17, 0, 0, 0, newYork),
+		},
+		{
+			name:      "day across daylight saving",
+			timeframe: Days(1),
+			options:   ResampleOptions{Location: newYork},
+			time:      time.Date(2020, time.March, 8, 12, 0, 0, 0, newYork),
+			start:     time.Date(2020, time.March, 8, 0, 0, 0, 0, newYork),
+			end:       time.Date(2020, time.March, 9, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:      "week",
+			timeframe: Weeks(1),
+			time:      time.Date(2020, time.March, 8, 12, 0, 0, 0, time.UTC),
+			start:     time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC),
+			end:       time.Date(2020, time.March, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month",
+			timeframe: Months(1),
+			time:      time.Date(2020, time.December, 31, 23, 0, 0, 0, time.UTC),
+			start:     time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC),
+			end:       time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "quarter",
+			timeframe: Months(3),
+			time:      time.Date(2020, time.May, 15, 0, 0, 0, 0, time.UTC),
+			start:     time.Date(2020, time.April, 1, 0, 0, 0, 0, time.UTC),
+			end:       time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := NewResampler(tt.timeframe, tt.options).Period(tt.time)
+			assert.True(t, tt.start.Equal(period.Start), period.String())
+			assert.True(t, tt.end.Equal(period.End), period.String())
+		})
+	}
+}
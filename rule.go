@@ -43,6 +43,10 @@ type OverIndicatorRule struct {
 
 // IsSatisfied returns true when the First Indicator is greater than the Second Indicator
 func (oir OverIndicatorRule) IsSatisfied(index int) bool {
+	if !IsValid(oir.First, index) || !IsValid(oir.Second, index) {
+		return false
+	}
+
 	return oir.First.Calculate(index).GT(oir.Second.Calculate(index))
 }
 
@@ -54,6 +58,10 @@ type UnderIndicatorRule struct {
 
 // IsSatisfied returns true when the First Indicator is less than the Second Indicator
 func (uir UnderIndicatorRule) IsSatisfied(index int) bool {
+	if !IsValid(uir.First, index) || !IsValid(uir.Second, index) {
+		return false
+	}
+
 	return uir.First.Calculate(index).LT(uir.Second.Calculate(index))
 }
 
@@ -63,6 +71,10 @@ type percentChangeRule struct {
 }
 
 func (pgr percentChangeRule) IsSatisfied(index int) bool {
+	if !IsValid(pgr.indicator, index) {
+		return false
+	}
+
 	return pgr.indicator.Calculate(index).Abs().GT(pgr.percent.Abs())
 }
 
@@ -29,12 +29,14 @@ type crossRule struct {
 func (cr crossRule) IsSatisfied(index int) bool {
 	i := index
 
-	if i == 0 {
+	if i == 0 || !cr.isValid(i) {
 		return false
 	}
 
 	if cmp := cr.lower.Calculate(i).Cmp(cr.upper.Calculate(i)); cmp == 0 || cmp == cr.cmp {
-		for ; i >= 0; i-- {
+		// values from before the lookback of either indicator are not compared, so that a cross
+		// is not found against the zeros of a warm up
+		for ; i >= 0 && cr.isValid(i); i-- {
 			if cmp = cr.lower.Calculate(i).Cmp(cr.upper.Calculate(i)); cmp == 0 || cmp == -cr.cmp {
 				return true
 			}
@@ -43,3 +45,7 @@ func (cr crossRule) IsSatisfied(index int) bool {
 
 	return false
 }
+
+func (cr crossRule) isValid(index int) bool {
+	return IsValid(cr.upper, index) && IsValid(cr.lower, index)
+}
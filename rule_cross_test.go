@@ -39,3 +39,19 @@ func TestCrossDownIndicatorRule(t *testing.T) {
 		assert.True(t, rule.IsSatisfied(3))
 	})
 }
+
+func TestCrossRule_WarmUp(t *testing.T) {
+	series := mockTimeSeriesFl(6, 6, 6, 6, 6, 6, 4, 6)
+	sma := NewSimpleMovingAverage(NewClosePriceIndicator(series), 3)
+	threshold := NewConstantIndicator(5)
+
+	// the average is zero, and below the threshold, until it has three closes
+	rule := NewCrossUpIndicatorRule(threshold, sma)
+	assert.False(t, rule.IsSatisfied(1))
+	assert.False(t, rule.IsSatisfied(2))
+	assert.False(t, rule.IsSatisfied(3))
+
+	rule = NewCrossDownIndicatorRule(sma, threshold)
+	assert.False(t, rule.IsSatisfied(2))
+	assert.False(t, rule.IsSatisfied(5))
+}
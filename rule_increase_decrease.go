@@ -9,7 +9,7 @@ type IncreaseRule struct {
 // IsSatisfied returns true when the given Indicator at the given index is greater than the value at the previous
 // index.
 func (ir IncreaseRule) IsSatisfied(index int) bool {
-	if index == 0 {
+	if index == 0 || !IsValid(ir.Indicator, index-1) || !IsValid(ir.Indicator, index) {
 		return false
 	}
 
@@ -25,7 +25,7 @@ type DecreaseRule struct {
 // IsSatisfied returns true when the given Indicator at the given index is less than the value at the previous
 // index.
 func (dr DecreaseRule) IsSatisfied(index int) bool {
-	if index == 0 {
+	if index == 0 || !IsValid(dr.Indicator, index-1) || !IsValid(dr.Indicator, index) {
 		return false
 	}
 
@@ -85,6 +85,17 @@ func TestOverIndicatorRule(t *testing.T) {
 
 		assert.False(t, rule.IsSatisfied(0))
 	})
+
+	t.Run("returns false while an indicator is warming up", func(t *testing.T) {
+		sma := NewSimpleMovingAverage(NewClosePriceIndicator(mockTimeSeriesFl(1, 2, 3)), 2)
+		rule := OverIndicatorRule{
+			First:  highIndicator,
+			Second: sma,
+		}
+
+		assert.False(t, rule.IsSatisfied(0))
+		assert.False(t, rule.IsSatisfied(1))
+	})
 }
 
 func TestUnderIndicatorRule(t *testing.T) {
@@ -0,0 +1,37 @@
+package techan
+
+import "math"
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+// covariance returns the sample covariance of two equal length series.
+func covariance(x, y []float64) float64 {
+	if len(x) < 2 {
+		return 0.0
+	}
+
+	meanX := mean(x)
+	meanY := mean(y)
+
+	sum := 0.0
+	for i := range x {
+		sum += (x[i] - meanX) * (y[i] - meanY)
+	}
+
+	return sum / float64(len(x)-1)
+}
+
+func stdev(values []float64) float64 {
+	return math.Sqrt(covariance(values, values))
+}
@@ -10,6 +10,18 @@ type Strategy struct {
 	Rule       Rule
 }
 
+// IsValid returns true if every indicator of the strategy has a valid value at the given index, i.e. the
+// strategy is past the warm up of its indicators. See IsValid.
+func (s *Strategy) IsValid(index int) bool {
+	for _, indicator := range s.Indicators {
+		if !IsValid(indicator, index) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Helper function to get the last index of the strategy's data.
 func (s *Strategy) LastIndex() int {
 	return s.Timeseries.LastIndex()
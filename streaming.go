@@ -0,0 +1,615 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/schmidthole/big"
+)
+
+// A StreamingIndicator is the push based counterpart of an Indicator for live feeds. Rather than
+// calculating values for indexes of a TimeSeries, candles are pushed in as they arrive and the
+// value for the latest candle is returned. A stream only keeps the state it needs, so the cost of
+// an update does not grow with the number of candles pushed. The values of a stream are identical,
+// bit for bit, to those of the corresponding Indicator on the same candles.
+//
+// Recursive indicators such as the EMA and RSI take constant time per update. Indicators which sum
+// a window, the SMA, ATR and bollinger bands, keep running sums as their batch indicators do, and
+// take constant time per update too. The values only match those of indicators on a series which
+// still has every candle pushed: the indicators on a bounded series sum their windows anew once
+// their running sums would reach evicted candles. The cost of each stream is given on its
+// constructor.
+//
+// Streams of a single value are calculated on the close price of the candles.
+type StreamingIndicator interface {
+	// Next adds a candle after the last one and returns the value for it
+	Next(candle *Candle) big.Decimal
+	// Update replaces the last candle, such as a bar which is still forming, and returns the
+	// updated value for it. The first candle is added if none has been pushed yet.
+	Update(candle *Candle) big.Decimal
+	// Value returns the value for the last candle, or zero if no candle has been pushed
+	Value() big.Decimal
+}
+
+// a candleStream calculates the value of a stream for a new candle, or for a replacement of the
+// last candle
+type candleStream interface {
+	push(candle *Candle, replace bool) big.Decimal
+}
+
+// a valueStream calculates the value of a stream for a new value, or for a replacement of the
+// last value
+type valueStream interface {
+	push(value big.Decimal, replace bool) big.Decimal
+}
+
+type streamingIndicator struct {
+	stream candleStream
+	value  big.Decimal
+	pushed bool
+}
+
+func newStreamingIndicator(stream candleStream) StreamingIndicator {
+	return &streamingIndicator{
+		stream: stream,
+		value:  big.ZERO,
+	}
+}
+
+func (si *streamingIndicator) Next(candle *Candle) big.Decimal {
+	if candle == nil {
+		panic(fmt.Errorf("error pushing Candle: candle cannot be nil"))
+	}
+
+	si.value = si.stream.push(candle, false)
+	si.pushed = true
+
+	return si.value
+}
+
+func (si *streamingIndicator) Update(candle *Candle) big.Decimal {
+	if !si.pushed {
+		return si.Next(candle)
+	}
+
+	if candle == nil {
+		panic(fmt.Errorf("error updating Candle: candle cannot be nil"))
+	}
+
+	si.value = si.stream.push(candle, true)
+
+	return si.value
+}
+
+func (si *streamingIndicator) Value() big.Decimal {
+	return si.value
+}
+
+// closeStream calculates a value stream on the close prices of candles
+type closeStream struct {
+	stream valueStream
+}
+
+func (cs closeStream) push(candle *Candle, replace bool) big.Decimal {
+	return cs.stream.push(candle.ClosePrice, replace)
+}
+
+// NewSimpleMovingAverageStream returns a StreamingIndicator of the simple moving average of the
+// close price over the given window. See NewSimpleMovingAverage. Updates take constant time, except
+// for the one which sums the first window.
+func NewSimpleMovingAverageStream(window int) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newSMAStream(window, 0)})
+}
+
+// NewEMAStream returns a StreamingIndicator of the exponential moving average of the close price
+// over the given window. See NewEMAIndicator. Updates take constant time, except for the one which
+// seeds the average with the sum of the first window.
+func NewEMAStream(window int) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newEMAStream(window)})
+}
+
+// NewMMAStream returns a StreamingIndicator of the modified moving average of the close price over
+// the given window. See NewMMAIndicator. Updates take constant time, except for the one which seeds
+// the average with the sum of the first window.
+func NewMMAStream(window int) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newMMAStream(window)})
+}
+
+// NewRelativeStrengthIndexStream returns a StreamingIndicator of the relative strength index of the
+// close price in the given time frame. See NewRelativeStrengthIndexIndicator. Updates take constant
+// time, except for the one which seeds the averages of gains and losses.
+func NewRelativeStrengthIndexStream(timeframe int) StreamingIndicator {
+	checkStreamWindow(timeframe)
+
+	return newStreamingIndicator(closeStream{&rsiStream{
+		gains:      newMMAStream(timeframe),
+		losses:     newMMAStream(timeframe),
+		window:     timeframe,
+		index:      -1,
+		oneHundred: big.NewFromString("100"),
+	}})
+}
+
+// NewAverageTrueRangeStream returns a StreamingIndicator of the average true range over the given
+// window. See NewAverageTrueRangeIndicator. Updates take constant time, except for the one which
+// sums the true ranges of the first window.
+func NewAverageTrueRangeStream(window int) StreamingIndicator {
+	return newStreamingIndicator(&atrStream{
+		ranges: newWindowSumStream(window, 1),
+		window: window,
+	})
+}
+
+// NewMACDStream returns a StreamingIndicator of the difference between the exponential moving
+// averages of the close price over a short and a long window. See NewMACDIndicator. Updates take
+// constant time, as for NewEMAStream.
+func NewMACDStream(shortwindow, longwindow int) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newMACDStream(shortwindow, longwindow)})
+}
+
+// NewMACDHistogramStream returns a StreamingIndicator of the MACD minus its exponential moving
+// average over the signal line window. See NewMACDHistogramIndicator. Updates take constant time,
+// as for NewEMAStream.
+func NewMACDHistogramStream(shortwindow, longwindow, signalLinewindow int) StreamingIndicator {
+	return newStreamingIndicator(closeStream{&macdHistogramStream{
+		macd:   newMACDStream(shortwindow, longwindow),
+		signal: newEMAStream(signalLinewindow),
+	}})
+}
+
+// NewBollingerUpperBandStream returns a StreamingIndicator of the upper bound of a bollinger band on
+// the close price. See NewBollingerUpperBandIndicator. Updates take constant time, except for those
+// of the first window, which sum its deviations.
+func NewBollingerUpperBandStream(window int, sigma float64) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newBollingerStream(window, sigma)})
+}
+
+// NewBollingerLowerBandStream returns a StreamingIndicator of the lower bound of a bollinger band on
+// the close price. See NewBollingerLowerBandIndicator. Updates take constant time, except for those
+// of the first window, which sum its deviations.
+func NewBollingerLowerBandStream(window int, sigma float64) StreamingIndicator {
+	return newStreamingIndicator(closeStream{newBollingerStream(window, -sigma)})
+}
+
+// NewFastStochasticStream returns a StreamingIndicator of the fast stochastic indicator (%K) for the
+// given window. See NewFastStochasticIndicator. The lowest low and highest high of the window are
+// kept in monotonic queues, so Next takes amortised constant time. Update takes time proportional
+// to the number of values the replaced candle displaced from the queues, at most the window.
+func NewFastStochasticStream(timeframe int) StreamingIndicator {
+	return newStreamingIndicator(newStochasticStream(timeframe))
+}
+
+// NewSlowStochasticStream returns a StreamingIndicator of the slow stochastic indicator (%D), the
+// simple moving average over the given window of the fast stochastic indicator for the timeframe.
+// See NewSlowStochasticIndicator. Updates cost as much as those of NewFastStochasticStream, as the
+// average of the %K values is kept as for NewSimpleMovingAverageStream.
+func NewSlowStochasticStream(timeframe, window int) StreamingIndicator {
+	return newStreamingIndicator(&slowStochasticStream{
+		k: newStochasticStream(timeframe),
+		d: newSMAStream(window, Max(timeframe-1, 0)),
+	})
+}
+
+func checkStreamWindow(window int) {
+	if window <= 0 {
+		panic(fmt.Errorf("error creating stream: window must be positive"))
+	}
+}
+
+// streamWindow holds the most recent values pushed to a stream, the newest of which can be
+// replaced
+type streamWindow struct {
+	values []big.Decimal
+	count  int
+}
+
+func newStreamWindow(size int) *streamWindow {
+	checkStreamWindow(size)
+
+	return &streamWindow{values: make([]big.Decimal, size)}
+}
+
+func (sw *streamWindow) push(value big.Decimal, replace bool) {
+	if !replace {
+		sw.count++
+	}
+
+	sw.values[(sw.count-1)%len(sw.values)] = value
+}
+
+// at returns the value pushed the given number of values before the newest one
+func (sw *streamWindow) at(ago int) big.Decimal {
+	return sw.values[(sw.count-1-ago)%len(sw.values)]
+}
+
+// index returns the index of the newest value, counting every value pushed
+func (sw *streamWindow) index() int {
+	return sw.count - 1
+}
+
+// size returns the number of values held, which is less than the window until it is filled
+func (sw *streamWindow) size() int {
+	return Min(sw.count, len(sw.values))
+}
+
+// sum adds the given number of the newest values from the newest to the oldest, as windowSum does
+func (sw *streamWindow) sum(count int) big.Decimal {
+	sum := big.ZERO
+	for ago := 0; ago < count; ago++ {
+		sum = sum.Add(sw.at(ago))
+	}
+
+	return sum
+}
+
+// extremeQueue is a monotonic queue of the indexes of a sliding window whose values may still be
+// the extreme of a window, from the most extreme at the front. Each index is added and removed at
+// most once, so finding the extreme of every window takes amortised constant time. The indexes the
+// newest one displaced are kept, so that it can be replaced.
+type extremeQueue struct {
+	indexes   []int
+	displaced []int
+	// better returns true if the value at index a is more extreme than the value at index b
+	better func(a, b int) bool
+}
+
+func newExtremeQueue(better func(a, b int) bool) *extremeQueue {
+	return &extremeQueue{better: better}
+}
+
+// push adds the newest index, and drops the indexes which have left the window ending at it
+func (eq *extremeQueue) push(index, window int) {
+	for len(eq.indexes) > 0 && eq.indexes[0] <= index-window {
+		eq.indexes = eq.indexes[1:]
+	}
+
+	eq.displaced = eq.displaced[:0]
+	eq.add(index)
+}
+
+// replace adds the newest index again after its value was replaced, restoring the indexes the
+// previous value displaced
+func (eq *extremeQueue) replace(index int) {
+	eq.indexes = eq.indexes[:len(eq.indexes)-1]
+	for i := len(eq.displaced) - 1; i >= 0; i-- {
+		eq.indexes = append(eq.indexes, eq.displaced[i])
+	}
+
+	eq.displaced = eq.displaced[:0]
+	eq.add(index)
+}
+
+func (eq *extremeQueue) add(index int) {
+	for len(eq.indexes) > 0 && !eq.better(eq.indexes[len(eq.indexes)-1], index) {
+		eq.displaced = append(eq.displaced, eq.indexes[len(eq.indexes)-1])
+		eq.indexes = eq.indexes[:len(eq.indexes)-1]
+	}
+
+	eq.indexes = append(eq.indexes, index)
+}
+
+// first returns the index of the extreme value of the window
+func (eq *extremeQueue) first() int {
+	return eq.indexes[0]
+}
+
+// windowSumStream keeps the running sum of a window of values as windowSumIndicator does, for
+// values of an indicator with the given lookback. It holds one value more than the window, which is
+// the one that left it.
+type windowSumStream struct {
+	values   *streamWindow
+	window   int
+	lookback int
+	previous big.Decimal
+	last     big.Decimal
+}
+
+func newWindowSumStream(window, lookback int) *windowSumStream {
+	checkStreamWindow(window)
+
+	return &windowSumStream{
+		values:   newStreamWindow(window + 1),
+		window:   window,
+		lookback: lookback,
+	}
+}
+
+func (ws *windowSumStream) push(value big.Decimal, replace bool) big.Decimal {
+	if !replace {
+		ws.previous = ws.last
+	}
+
+	ws.values.push(value, replace)
+	sum := func() big.Decimal {
+		return ws.values.sum(ws.window)
+	}
+
+	switch index := ws.values.index(); {
+	case index < ws.window-1:
+		ws.last = big.ZERO
+	case index-ws.window < ws.lookback:
+		ws.last = sum()
+	default:
+		ws.last = slideWindowSum(ws.previous, value, ws.values.at(ws.window), sum)
+	}
+
+	return ws.last
+}
+
+type smaStream struct {
+	sum *windowSumStream
+}
+
+func newSMAStream(window, lookback int) *smaStream {
+	return &smaStream{sum: newWindowSumStream(window, lookback)}
+}
+
+func (ss *smaStream) push(value big.Decimal, replace bool) big.Decimal {
+	sum := ss.sum.push(value, replace)
+
+	if ss.sum.values.index() < ss.sum.window-1 {
+		return big.ZERO
+	}
+
+	return sum.Div(big.NewFromInt(ss.sum.window))
+}
+
+// smoothingStream calculates a recursive moving average, which is seeded with the simple moving
+// average of its first window
+type smoothingStream struct {
+	values   *streamWindow
+	smooth   func(previous, value big.Decimal) big.Decimal
+	previous big.Decimal
+	last     big.Decimal
+}
+
+func newEMAStream(window int) *smoothingStream {
+	alpha := big.ONE.Frac(2).Div(big.NewFromInt(window + 1))
+
+	return &smoothingStream{
+		values: newStreamWindow(window),
+		smooth: func(previous, value big.Decimal) big.Decimal {
+			return value.Mul(alpha).Add(previous.Mul(big.ONE.Sub(alpha)))
+		},
+	}
+}
+
+func newMMAStream(window int) *smoothingStream {
+	return &smoothingStream{
+		values: newStreamWindow(window),
+		smooth: func(previous, value big.Decimal) big.Decimal {
+			return previous.Add(big.NewDecimal(1.0 / float64(window)).Mul(value.Sub(previous)))
+		},
+	}
+}
+
+func (ss *smoothingStream) push(value big.Decimal, replace bool) big.Decimal {
+	if !replace {
+		ss.previous = ss.last
+	}
+
+	ss.values.push(value, replace)
+	window := len(ss.values.values)
+
+	switch index := ss.values.index(); {
+	case index < window-1:
+		ss.last = big.ZERO
+	case index == window-1:
+		ss.last = ss.values.sum(window).Div(big.NewFromInt(window))
+	default:
+		ss.last = ss.smooth(ss.previous, value)
+	}
+
+	return ss.last
+}
+
+type rsiStream struct {
+	gains      valueStream
+	losses     valueStream
+	window     int
+	index      int
+	previous   big.Decimal
+	last       big.Decimal
+	oneHundred big.Decimal
+}
+
+func (rs *rsiStream) push(value big.Decimal, replace bool) big.Decimal {
+	if !replace {
+		rs.previous = rs.last
+		rs.index++
+	}
+	rs.last = value
+
+	gain, loss := big.ZERO, big.ZERO
+	if rs.index > 0 {
+		if delta := value.Sub(rs.previous).Mul(big.ONE); delta.GT(big.ZERO) {
+			gain = delta
+		}
+
+		if delta := value.Sub(rs.previous).Mul(big.ONE.Neg()); delta.GT(big.ZERO) {
+			loss = delta
+		}
+	}
+
+	avgGain := rs.gains.push(gain, replace)
+	avgLoss := rs.losses.push(loss, replace)
+
+	relativeStrength := big.ZERO
+	if rs.index >= rs.window-1 {
+		if avgLoss.EQ(big.ZERO) {
+			relativeStrength = big.NewDecimal(math.Inf(1))
+		} else {
+			relativeStrength = avgGain.Div(avgLoss)
+		}
+	}
+
+	return rs.oneHundred.Sub(rs.oneHundred.Div(big.ONE.Add(relativeStrength)))
+}
+
+type atrStream struct {
+	ranges        *windowSumStream
+	window        int
+	previousClose big.Decimal
+	lastClose     big.Decimal
+}
+
+func (as *atrStream) push(candle *Candle, replace bool) big.Decimal {
+	index := as.ranges.values.count
+	if replace {
+		index--
+	} else {
+		as.previousClose = as.lastClose
+	}
+	as.lastClose = candle.ClosePrice
+
+	trueRange := big.ZERO
+	if index > 0 {
+		trueHigh := big.MaxSlice(candle.MaxPrice, as.previousClose)
+		trueLow := big.MinSlice(candle.MinPrice, as.previousClose)
+		trueRange = trueHigh.Sub(trueLow)
+	}
+
+	sum := as.ranges.push(trueRange, replace)
+
+	if index < as.window {
+		return big.ZERO
+	}
+
+	return sum.Div(big.NewFromInt(as.window))
+}
+
+type macdStream struct {
+	short valueStream
+	long  valueStream
+}
+
+func newMACDStream(shortwindow, longwindow int) *macdStream {
+	return &macdStream{
+		short: newEMAStream(shortwindow),
+		long:  newEMAStream(longwindow),
+	}
+}
+
+func (ms *macdStream) push(value big.Decimal, replace bool) big.Decimal {
+	return ms.short.push(value, replace).Sub(ms.long.push(value, replace))
+}
+
+type macdHistogramStream struct {
+	macd   valueStream
+	signal valueStream
+}
+
+func (mhs *macdHistogramStream) push(value big.Decimal, replace bool) big.Decimal {
+	macd := mhs.macd.push(value, replace)
+
+	return macd.Sub(mhs.signal.push(macd, replace))
+}
+
+// bollingerStream keeps the sum of the squared deviations of its window along with the moving
+// average, as squaredDeviationsIndicator does
+type bollingerStream struct {
+	sum             *windowSumStream
+	muladd          big.Decimal
+	previousAverage big.Decimal
+	previousSquares big.Decimal
+	average         big.Decimal
+	squares         big.Decimal
+}
+
+func newBollingerStream(window int, sigma float64) *bollingerStream {
+	return &bollingerStream{
+		sum:    newWindowSumStream(window, 0),
+		muladd: big.NewDecimal(sigma),
+	}
+}
+
+func (bs *bollingerStream) push(value big.Decimal, replace bool) big.Decimal {
+	if !replace {
+		bs.previousAverage, bs.previousSquares = bs.average, bs.squares
+	}
+
+	sum := bs.sum.push(value, replace)
+	values, window := bs.sum.values, bs.sum.window
+	index := values.index()
+
+	bs.average = big.ZERO
+	if index >= window-1 {
+		bs.average = sum.Div(big.NewFromInt(window))
+	}
+
+	// the deviations are summed from the oldest to the newest value, as the batch indicator does
+	squares := func() big.Decimal {
+		squares := big.ZERO
+		for ago := Min(index, window-1); ago >= 0; ago-- {
+			squares = squares.Add(values.at(ago).Sub(bs.average).Pow(2))
+		}
+
+		return squares
+	}
+
+	if index < window {
+		bs.squares = squares()
+	} else {
+		bs.squares = slideSquaredDeviations(bs.previousSquares, value, values.at(window), bs.average,
+			bs.previousAverage, squares)
+	}
+
+	stdev := bs.squares.Div(big.NewDecimal(float64(Min(index+1, window)))).Sqrt()
+
+	return bs.average.Add(stdev.Mul(bs.muladd))
+}
+
+type stochasticStream struct {
+	lows    *streamWindow
+	highs   *streamWindow
+	lowest  *extremeQueue
+	highest *extremeQueue
+}
+
+func newStochasticStream(timeframe int) *stochasticStream {
+	ss := &stochasticStream{
+		lows:  newStreamWindow(timeframe),
+		highs: newStreamWindow(timeframe),
+	}
+
+	ss.lowest = newExtremeQueue(func(a, b int) bool {
+		return ss.lows.at(ss.lows.index() - a).LT(ss.lows.at(ss.lows.index() - b))
+	})
+	ss.highest = newExtremeQueue(func(a, b int) bool {
+		return ss.highs.at(ss.highs.index() - a).GT(ss.highs.at(ss.highs.index() - b))
+	})
+
+	return ss
+}
+
+func (ss *stochasticStream) push(candle *Candle, replace bool) big.Decimal {
+	ss.lows.push(candle.MinPrice, replace)
+	ss.highs.push(candle.MaxPrice, replace)
+
+	window := len(ss.lows.values)
+	if replace {
+		ss.lowest.replace(ss.lows.index())
+		ss.highest.replace(ss.highs.index())
+	} else {
+		ss.lowest.push(ss.lows.index(), window)
+		ss.highest.push(ss.highs.index(), window)
+	}
+
+	minValue := ss.lows.at(ss.lows.index() - ss.lowest.first())
+	maxValue := ss.highs.at(ss.highs.index() - ss.highest.first())
+
+	if minValue.EQ(maxValue) {
+		return big.NewDecimal(math.Inf(1))
+	}
+
+	return candle.ClosePrice.Sub(minValue).Div(maxValue.Sub(minValue)).Mul(big.NewDecimal(100))
+}
+
+type slowStochasticStream struct {
+	k candleStream
+	d valueStream
+}
+
+func (sss *slowStochasticStream) push(candle *Candle, replace bool) big.Decimal {
+	return sss.d.push(sss.k.push(candle, replace), replace)
+}
@@ -0,0 +1,176 @@
+package techan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamingCases pairs every streaming indicator with the batch indicator it must reproduce
+var streamingCases = map[string]struct {
+	batch  func(series *TimeSeries) Indicator
+	stream func() StreamingIndicator
+}{
+	"sma": {
+		func(series *TimeSeries) Indicator { return NewSimpleMovingAverage(NewClosePriceIndicator(series), 5) },
+		func() StreamingIndicator { return NewSimpleMovingAverageStream(5) },
+	},
+	"ema": {
+		func(series *TimeSeries) Indicator { return NewEMAIndicator(NewClosePriceIndicator(series), 9) },
+		func() StreamingIndicator { return NewEMAStream(9) },
+	},
+	"mma": {
+		func(series *TimeSeries) Indicator { return NewMMAIndicator(NewClosePriceIndicator(series), 9) },
+		func() StreamingIndicator { return NewMMAStream(9) },
+	},
+	"rsi": {
+		func(series *TimeSeries) Indicator {
+			return NewRelativeStrengthIndexIndicator(NewClosePriceIndicator(series), 14)
+		},
+		func() StreamingIndicator { return NewRelativeStrengthIndexStream(14) },
+	},
+	"atr": {
+		func(series *TimeSeries) Indicator { return NewAverageTrueRangeIndicator(series, 14) },
+		func() StreamingIndicator { return NewAverageTrueRangeStream(14) },
+	},
+	"macd": {
+		func(series *TimeSeries) Indicator { return NewMACDIndicator(NewClosePriceIndicator(series), 12, 26) },
+		func() StreamingIndicator { return NewMACDStream(12, 26) },
+	},
+	"macd histogram": {
+		func(series *TimeSeries) Indicator {
+			return NewMACDHistogramIndicator(NewMACDIndicator(NewClosePriceIndicator(series), 12, 26), 9)
+		},
+		func() StreamingIndicator { return NewMACDHistogramStream(12, 26, 9) },
+	},
+	"bollinger upper": {
+		func(series *TimeSeries) Indicator {
+			return NewBollingerUpperBandIndicator(NewClosePriceIndicator(series), 20, 2)
+		},
+		func() StreamingIndicator { return NewBollingerUpperBandStream(20, 2) },
+	},
+	"bollinger lower": {
+		func(series *TimeSeries) Indicator {
+			return NewBollingerLowerBandIndicator(NewClosePriceIndicator(series), 20, 2)
+		},
+		func() StreamingIndicator { return NewBollingerLowerBandStream(20, 2) },
+	},
+	"fast stochastic": {
+		func(series *TimeSeries) Indicator { return NewFastStochasticIndicator(series, 14) },
+		func() StreamingIndicator { return NewFastStochasticStream(14) },
+	},
+	"slow stochastic": {
+		func(series *TimeSeries) Indicator {
+			return NewSlowStochasticIndicator(NewFastStochasticIndicator(series, 14), 3)
+		},
+		func() StreamingIndicator { return NewSlowStochasticStream(14, 3) },
+	},
+}
+
+func assertBitIdentical(t *testing.T, expected, actual big.Decimal, msgAndArgs ...interface{}) {
+	assert.Equal(t, math.Float64bits(expected.Float()), math.Float64bits(actual.Float()), msgAndArgs...)
+}
+
+func TestStreamingIndicator_MatchesBatch(t *testing.T) {
+	series := randomTimeSeries(200)
+
+	for name, test := range streamingCases {
+		t.Run(name, func(t *testing.T) {
+			batch := test.batch(series)
+			stream := test.stream()
+
+			for i, candle := range series.Candles {
+				assertBitIdentical(t, batch.Calculate(i), stream.Next(candle), "index %v", i)
+			}
+
+			assertBitIdentical(t, batch.Calculate(series.LastIndex()), stream.Value())
+		})
+	}
+}
+
+func TestStreamingIndicator_Update(t *testing.T) {
+	series := randomTimeSeries(200)
+	other := randomTimeSeries(200)
+
+	for name, test := range streamingCases {
+		t.Run(name, func(t *testing.T) {
+			batch := test.batch(series)
+			stream := test.stream()
+
+			for i, candle := range series.Candles {
+				// the bar forms through the prices of another series before it completes
+				forming := NewCandle(candle.Period)
+				forming.OpenPrice = other.Candles[i].OpenPrice
+				forming.ClosePrice = other.Candles[i].ClosePrice
+				forming.MaxPrice = other.Candles[i].MaxPrice
+				forming.MinPrice = other.Candles[i].MinPrice
+
+				stream.Next(forming)
+				stream.Update(candle)
+
+				assertBitIdentical(t, batch.Calculate(i), stream.Value(), "index %v", i)
+			}
+		})
+	}
+}
+
+func TestStreamingIndicator(t *testing.T) {
+	series := mockTimeSeriesFl(1, 2, 3, 4)
+	stream := NewSimpleMovingAverageStream(2)
+
+	decimalEquals(t, 0, stream.Value())
+	decimalEquals(t, 0, stream.Update(series.Candles[0]))
+	decimalEquals(t, 1.5, stream.Next(series.Candles[1]))
+	decimalEquals(t, 2, stream.Update(series.Candles[2]))
+	decimalEquals(t, 3.5, stream.Next(series.Candles[3]))
+	decimalEquals(t, 3.5, stream.Value())
+
+	assert.Panics(t, func() {
+		stream.Next(nil)
+	})
+
+	assert.Panics(t, func() {
+		NewEMAStream(0)
+	})
+}
+
+func TestExtremeQueue(t *testing.T) {
+	values := []float64{5, 4, 1, 3}
+	queue := newExtremeQueue(func(a, b int) bool { return values[a] < values[b] })
+
+	queue.push(0, 3)
+	queue.push(1, 3)
+	queue.push(2, 3)
+	assert.Equal(t, 2, queue.first())
+
+	// a replacement of the newest value restores the values it displaced
+	values[2] = 6
+	queue.replace(2)
+	assert.Equal(t, 1, queue.first())
+
+	queue.push(3, 3)
+	assert.Equal(t, 3, queue.first())
+	assert.EqualValues(t, []int{3}, queue.indexes)
+}
+
+func BenchmarkStreamingEMA(b *testing.B) {
+	series := randomTimeSeries(b.N)
+	stream := NewEMAStream(10)
+
+	b.ResetTimer()
+	for _, candle := range series.Candles {
+		stream.Next(candle)
+	}
+}
+
+func BenchmarkStreamingBollinger(b *testing.B) {
+	series := randomTimeSeries(b.N)
+	stream := NewBollingerUpperBandStream(200, 2)
+
+	b.ResetTimer()
+	for _, candle := range series.Candles {
+		stream.Next(candle)
+	}
+}
@@ -84,6 +84,21 @@ func mockTimeSeriesFl(values ...float64) *TimeSeries {
 	return mockTimeSeries(strVals...)
 }
 
+func mockAccountHistory(equities ...float64) *AccountHistory {
+	ah := NewAccountHistory()
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, e := range equities {
+		period := NewTimePeriod(start.AddDate(0, 0, i), time.Hour*24)
+		snap := AccountSnapshot{Period: period, Equity: big.NewDecimal(e), Cash: big.NewDecimal(e)}
+		pricing := PricingSnapshot{Period: period, Prices: Pricing{}}
+
+		ah.ApplySnapshot(&snap, &pricing)
+	}
+
+	return ah
+}
+
 func decimalEquals(t *testing.T, expected float64, actual big.Decimal) {
 	assert.Equal(t, fmt.Sprintf("%.4f", expected), fmt.Sprintf("%.4f", actual.Float()))
 }
@@ -101,8 +116,6 @@ func dump(indicator Indicator) (values []float64) {
 		values = append(values, math.Round(indicator.Calculate(index).Float()*m)/m)
 		index++
 	}
-
-	return
 }
 
 func indicatorEquals(t *testing.T, expected []float64, indicator Indicator) {
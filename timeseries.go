@@ -4,9 +4,25 @@ import (
 	"fmt"
 )
 
-// TimeSeries represents an array of candles
+// TimeSeries represents an array of candles. A bounded TimeSeries only retains its most recent
+// candles, in which case Candles holds the retained candles while indexes passed to Candle and
+// to indicators remain absolute, counting every candle ever added to the series.
 type TimeSeries struct {
-	Candles []*Candle
+	Candles  []*Candle
+	capacity int
+	offset   int
+	revision int
+}
+
+// EvictedIndexError is raised as a panic when a candle, or an indicator value derived from one, is
+// requested for an index that a bounded TimeSeries no longer retains.
+type EvictedIndexError struct {
+	Index      int
+	FirstIndex int
+}
+
+func (e EvictedIndexError) Error() string {
+	return fmt.Sprintf("index %v has been evicted from the timeseries, the first retained index is %v", e.Index, e.FirstIndex)
 }
 
 // NewTimeSeries returns a new, empty, TimeSeries
@@ -17,6 +33,20 @@ func NewTimeSeries() (t *TimeSeries) {
 	return t
 }
 
+// NewBoundedTimeSeries returns a new, empty, TimeSeries which retains at most capacity candles.
+// Once full, adding a candle evicts the oldest one. This keeps memory use constant for long
+// running processes which only need recent history.
+func NewBoundedTimeSeries(capacity int) (t *TimeSeries) {
+	if capacity <= 0 {
+		panic(fmt.Errorf("error creating TimeSeries: capacity must be positive"))
+	}
+
+	t = NewTimeSeries()
+	t.capacity = capacity
+
+	return t
+}
+
 // AddCandle adds the given candle to this TimeSeries if it is not nil and after the last candle in this timeseries.
 // If the candle is added, AddCandle will return true, otherwise it will return false.
 func (ts *TimeSeries) AddCandle(candle *Candle) bool {
@@ -25,13 +55,66 @@ func (ts *TimeSeries) AddCandle(candle *Candle) bool {
 	}
 
 	if ts.LastCandle() == nil || candle.Period.Since(ts.LastCandle().Period) >= 0 {
+		if ts.capacity > 0 && len(ts.Candles) >= ts.capacity {
+			// release the evicted candle; the backing array is reallocated, and the evicted
+			// prefix dropped, whenever append runs out of capacity
+			ts.Candles[0] = nil
+			ts.Candles = ts.Candles[1:]
+			ts.offset++
+		}
+
 		ts.Candles = append(ts.Candles, candle)
+		ts.revision++
 		return true
 	}
 
 	return false
 }
 
+// UpdateLastCandle replaces the last candle of this TimeSeries, such as when a still forming bar
+// receives a new trade. The candle must start at the same time as the candle it replaces. If the
+// candle is replaced, UpdateLastCandle will return true, otherwise it will return false.
+func (ts *TimeSeries) UpdateLastCandle(candle *Candle) bool {
+	if candle == nil {
+		panic(fmt.Errorf("error updating Candle: candle cannot be nil"))
+	}
+
+	if ts.LastCandle() == nil || !candle.Period.Start.Equal(ts.LastCandle().Period.Start) {
+		return false
+	}
+
+	ts.Candles[len(ts.Candles)-1] = candle
+	ts.revision++
+	return true
+}
+
+// Revision returns a counter which changes whenever a candle is added to this series or its last
+// candle is updated. Indicator caches compare it to tell whether their values are still current.
+func (ts *TimeSeries) Revision() int {
+	return ts.revision
+}
+
+// Candle returns the candle at the given absolute index. It panics with an EvictedIndexError if
+// the candle has been evicted from a bounded series.
+func (ts *TimeSeries) Candle(index int) *Candle {
+	if index < ts.offset {
+		panic(EvictedIndexError{Index: index, FirstIndex: ts.offset})
+	}
+
+	return ts.Candles[index-ts.offset]
+}
+
+// HasIndex returns true if the candle at the given absolute index is retained by this series
+func (ts *TimeSeries) HasIndex(index int) bool {
+	return index >= ts.offset && index <= ts.LastIndex()
+}
+
+// Capacity returns the maximum number of candles retained by this series, or zero if the series
+// is unbounded
+func (ts *TimeSeries) Capacity() int {
+	return ts.capacity
+}
+
 // LastCandle will return the lastCandle in this series, or nil if this series is empty
 func (ts *TimeSeries) LastCandle() *Candle {
 	if len(ts.Candles) > 0 {
@@ -41,7 +124,60 @@ func (ts *TimeSeries) LastCandle() *Candle {
 	return nil
 }
 
+// FirstIndex will return the index of the first candle retained by this series, which is only
+// greater than zero once a bounded series has evicted candles
+func (ts *TimeSeries) FirstIndex() int {
+	return ts.offset
+}
+
 // LastIndex will return the index of the last candle in this series
 func (ts *TimeSeries) LastIndex() int {
-	return len(ts.Candles) - 1
+	return ts.offset + len(ts.Candles) - 1
+}
+
+// sourceSeries returns the series an indicator is calculated on. The basic indicators embed their
+// series and so provide it too, which lets derived indicators find the series of their sources.
+func (ts *TimeSeries) sourceSeries() []*TimeSeries {
+	return []*TimeSeries{ts}
+}
+
+// seriesOf returns every distinct series the given indicators are calculated on, in the order they
+// are found. Indicators which combine several securities are calculated on more than one series.
+func seriesOf(indicators ...Indicator) []*TimeSeries {
+	var all []*TimeSeries
+
+	for _, indicator := range indicators {
+		source, ok := indicator.(interface{ sourceSeries() []*TimeSeries })
+		if !ok {
+			continue
+		}
+
+		for _, series := range source.sourceSeries() {
+			if series != nil && !containsSeries(all, series) {
+				all = append(all, series)
+			}
+		}
+	}
+
+	return all
+}
+
+func containsSeries(all []*TimeSeries, series *TimeSeries) bool {
+	for _, s := range all {
+		if s == series {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstIndex returns the first index an indicator can be calculated for. Indicators on a bounded
+// TimeSeries, or derived from one, report this through a FirstIndex method.
+func firstIndex(indicator Indicator) int {
+	if bounded, ok := indicator.(interface{ FirstIndex() int }); ok {
+		return bounded.FirstIndex()
+	}
+
+	return 0
 }
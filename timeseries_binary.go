@@ -0,0 +1,613 @@
+package techan
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// The binary timeseries format starts with a header of the magic bytes, the version and the format
+// flags, followed by blocks of candles and an end marker. Every block starts with its candle count,
+// the start times of its first and last candles and its length, so that readers can skip blocks
+// outside of a time range without decoding them, and ends with its, optionally compressed, payload.
+// The payload holds the price and volume scales of the block, followed by every candle as varint
+// deltas of its start time, duration and fixed-point prices, its fixed-point volume and its trade
+// count. Values which cannot be represented exactly as fixed-point numbers switch their block to
+// raw float64 values, so decoding always restores the exact same candles.
+const (
+	binaryMagic      = "TCSB"
+	binaryVersion    = 1
+	binaryCompressed = 1 << 0
+	binaryRawScale   = 0xFF
+	binaryMaxScale   = 15
+	binaryMaxInteger = 1 << 53
+	// the smallest and largest encodings of a candle, of single byte varints and of ten byte
+	// varints and raw prices, which bound the number of candles of a payload and its size
+	binaryMinCandle = 8
+	binaryMaxCandle = 80
+)
+
+// BinaryOptions configures how a TimeSeries is encoded. The zero value writes uncompressed blocks
+// of 1024 candles.
+type BinaryOptions struct {
+	Compress  bool
+	BlockSize int
+}
+
+// A TimeSeriesEncoder writes candles to a stream in the binary timeseries format. Candles are
+// buffered and written a block at a time, so Close must be called once all candles are encoded.
+type TimeSeriesEncoder struct {
+	w        io.Writer
+	options  BinaryOptions
+	block    []*Candle
+	previous *Candle
+	header   bool
+	closed   bool
+}
+
+// NewTimeSeriesEncoder returns an encoder writing to w with the given options.
+func NewTimeSeriesEncoder(w io.Writer, options BinaryOptions) *TimeSeriesEncoder {
+	if options.BlockSize <= 0 {
+		options.BlockSize = 1024
+	}
+
+	return &TimeSeriesEncoder{
+		w:       w,
+		options: options,
+		block:   make([]*Candle, 0, options.BlockSize),
+	}
+}
+
+// Encode adds a candle to the stream. Candles must be encoded in chronological order.
+func (e *TimeSeriesEncoder) Encode(candle *Candle) error {
+	if e.closed {
+		return fmt.Errorf("cannot encode a candle after the encoder is closed")
+	}
+
+	if e.previous != nil && candle.Period.Start.Before(e.previous.Period.Start) {
+		return fmt.Errorf("candle %v is before the previous candle", candle.Period)
+	}
+
+	e.previous = candle
+	e.block = append(e.block, candle)
+	if len(e.block) >= e.options.BlockSize {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// Close writes any buffered candles and the end of the stream. It does not close the underlying
+// writer.
+func (e *TimeSeriesEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	e.closed = true
+	_, err := e.w.Write(appendUvarint(nil, 0))
+	return err
+}
+
+func (e *TimeSeriesEncoder) flush() error {
+	if !e.header {
+		flags := byte(0)
+		if e.options.Compress {
+			flags |= binaryCompressed
+		}
+
+		if _, err := e.w.Write(append([]byte(binaryMagic), binaryVersion, flags)); err != nil {
+			return err
+		}
+		e.header = true
+	}
+
+	if len(e.block) == 0 {
+		return nil
+	}
+
+	payload := encodeBlock(e.block)
+	if e.options.Compress {
+		var compressed bytes.Buffer
+		writer, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+		writer.Write(payload)
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		payload = compressed.Bytes()
+	}
+
+	header := appendUvarint(nil, uint64(len(e.block)))
+	header = appendVarint(header, e.block[0].Period.Start.UnixNano())
+	header = appendVarint(header, e.block[len(e.block)-1].Period.Start.UnixNano())
+	header = appendUvarint(header, uint64(len(payload)))
+
+	if _, err := e.w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+
+	e.block = e.block[:0]
+	return nil
+}
+
+// A TimeSeriesDecoder reads candles from a stream in the binary timeseries format. Times are
+// decoded in UTC.
+type TimeSeriesDecoder struct {
+	r          io.Reader
+	compressed bool
+	period     *TimePeriod
+	block      []*Candle
+	done       bool
+}
+
+// NewTimeSeriesDecoder returns a decoder reading from r, after reading and checking the header of
+// the stream.
+func NewTimeSeriesDecoder(r io.Reader) (*TimeSeriesDecoder, error) {
+	header := make([]byte, len(binaryMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("cannot read the binary timeseries header: %v", err)
+	}
+
+	if string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, fmt.Errorf("not a binary timeseries")
+	}
+
+	if version := header[len(binaryMagic)]; version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary timeseries version %v", version)
+	}
+
+	return &TimeSeriesDecoder{
+		r:          r,
+		compressed: header[len(binaryMagic)+1]&binaryCompressed != 0,
+	}, nil
+}
+
+// SetRange limits the decoder to the candles which start within the period. Blocks of candles
+// outside of the period are skipped without being decoded, by seeking if the underlying reader is
+// an io.Seeker, and decoding stops at the first block starting after the period.
+func (d *TimeSeriesDecoder) SetRange(period TimePeriod) {
+	d.period = &period
+}
+
+// Decode returns the next candle of the stream, or io.EOF once all candles have been read.
+func (d *TimeSeriesDecoder) Decode() (*Candle, error) {
+	for {
+		for len(d.block) > 0 {
+			candle := d.block[0]
+			d.block = d.block[1:]
+
+			if d.period == nil || (!candle.Period.Start.Before(d.period.Start) && candle.Period.Start.Before(d.period.End)) {
+				return candle, nil
+			}
+		}
+
+		if d.done {
+			return nil, io.EOF
+		}
+
+		if err := d.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *TimeSeriesDecoder) readBlock() error {
+	reader := byteReader{d.r}
+
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if count == 0 {
+		d.done = true
+		return nil
+	}
+
+	first, err := binary.ReadVarint(reader)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	last, err := binary.ReadVarint(reader)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if length > math.MaxInt64 {
+		return fmt.Errorf("invalid binary timeseries block length %v", length)
+	}
+
+	if d.period != nil {
+		if first >= d.period.End.UnixNano() {
+			d.done = true
+			return nil
+		}
+
+		if last < d.period.Start.UnixNano() {
+			return d.skip(int64(length))
+		}
+	}
+
+	// the payload is read as it arrives rather than allocated up front, so a corrupt length fails
+	// at the end of the stream instead of exhausting memory
+	var buffer bytes.Buffer
+	if _, err := io.CopyN(&buffer, d.r, int64(length)); err != nil {
+		return unexpectedEOF(err)
+	}
+	payload := buffer.Bytes()
+
+	if d.compressed {
+		reader := flate.NewReader(bytes.NewReader(payload))
+		defer reader.Close()
+
+		limit := int64(math.MaxInt64)
+		if count < (math.MaxInt64-2)/binaryMaxCandle {
+			limit = 2 + int64(count)*binaryMaxCandle
+		}
+
+		var decompressed bytes.Buffer
+		if _, err = io.Copy(&decompressed, io.LimitReader(reader, limit)); err != nil {
+			return err
+		}
+		payload = decompressed.Bytes()
+	}
+
+	if count > uint64(len(payload))/binaryMinCandle {
+		return fmt.Errorf("binary timeseries block of %v bytes cannot hold %v candles", len(payload), count)
+	}
+
+	d.block, err = decodeBlock(payload, int(count), first)
+	return err
+}
+
+func (d *TimeSeriesDecoder) skip(length int64) error {
+	if seeker, ok := d.r.(io.Seeker); ok {
+		_, err := seeker.Seek(length, io.SeekCurrent)
+		return err
+	}
+
+	_, err := io.CopyN(ioutil.Discard, d.r, length)
+	return unexpectedEOF(err)
+}
+
+// Writes a TimeSeries in the binary timeseries format.
+func WriteTimeSeriesBinary(w io.Writer, series *TimeSeries, options BinaryOptions) error {
+	encoder := NewTimeSeriesEncoder(w, options)
+
+	for _, candle := range series.Candles {
+		if err := encoder.Encode(candle); err != nil {
+			return err
+		}
+	}
+
+	return encoder.Close()
+}
+
+// Exports a TimeSeries to a file in the binary timeseries format, which can be loaded with
+// LoadTimeSeriesBinary.
+func ExportTimeSeriesBinary(filepath string, series *TimeSeries, options BinaryOptions) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return WriteTimeSeriesBinary(file, series, options)
+}
+
+// Reads a TimeSeries written by WriteTimeSeriesBinary.
+func ReadTimeSeriesBinary(r io.Reader) (*TimeSeries, error) {
+	return readTimeSeriesBinary(r, nil)
+}
+
+// Reads the candles of a TimeSeries written by WriteTimeSeriesBinary which start within the
+// period.
+func ReadTimeSeriesBinaryRange(r io.Reader, period TimePeriod) (*TimeSeries, error) {
+	return readTimeSeriesBinary(r, &period)
+}
+
+// Loads a TimeSeries from a file written by ExportTimeSeriesBinary.
+func LoadTimeSeriesBinary(filepath string) (*TimeSeries, error) {
+	return loadTimeSeriesBinary(filepath, nil)
+}
+
+// Loads the candles of a TimeSeries which start within the period from a file written by
+// ExportTimeSeriesBinary. Blocks of candles outside of the period are not read.
+func LoadTimeSeriesBinaryRange(filepath string, period TimePeriod) (*TimeSeries, error) {
+	return loadTimeSeriesBinary(filepath, &period)
+}
+
+func loadTimeSeriesBinary(filepath string, period *TimePeriod) (*TimeSeries, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readTimeSeriesBinary(file, period)
+}
+
+func readTimeSeriesBinary(r io.Reader, period *TimePeriod) (*TimeSeries, error) {
+	decoder, err := NewTimeSeriesDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if period != nil {
+		decoder.SetRange(*period)
+	}
+
+	series := NewTimeSeries()
+	for {
+		candle, err := decoder.Decode()
+		if err == io.EOF {
+			return series, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		series.AddCandle(candle)
+	}
+}
+
+func encodeBlock(candles []*Candle) []byte {
+	prices := make([]float64, 0, len(candles)*4)
+	volumes := make([]float64, 0, len(candles))
+	for _, c := range candles {
+		prices = append(prices, c.OpenPrice.Float(), c.ClosePrice.Float(), c.MaxPrice.Float(), c.MinPrice.Float())
+		volumes = append(volumes, c.Volume.Float())
+	}
+
+	priceScale, volumeScale := fixedPointScale(prices), fixedPointScale(volumes)
+	payload := []byte{priceScale, volumeScale}
+
+	previousStart := candles[0].Period.Start.UnixNano()
+	var previousDuration, previousClose int64
+
+	for i, c := range candles {
+		start, duration := c.Period.Start.UnixNano(), int64(c.Period.Length())
+		payload = appendVarint(payload, start-previousStart)
+		payload = appendVarint(payload, duration-previousDuration)
+		previousStart, previousDuration = start, duration
+
+		if priceScale == binaryRawScale {
+			for _, price := range prices[i*4 : i*4+4] {
+				payload = appendUint64(payload, math.Float64bits(price))
+			}
+		} else {
+			open, close := toFixedPoint(prices[i*4], priceScale), toFixedPoint(prices[i*4+1], priceScale)
+			high, low := toFixedPoint(prices[i*4+2], priceScale), toFixedPoint(prices[i*4+3], priceScale)
+
+			// highs and lows are stored relative to the body of the candle, where they usually are
+			payload = appendVarint(payload, open-previousClose)
+			payload = appendVarint(payload, close-open)
+			payload = appendVarint(payload, high-max64(open, close))
+			payload = appendVarint(payload, min64(open, close)-low)
+			previousClose = close
+		}
+
+		if volumeScale == binaryRawScale {
+			payload = appendUint64(payload, math.Float64bits(volumes[i]))
+		} else {
+			payload = appendVarint(payload, toFixedPoint(volumes[i], volumeScale))
+		}
+
+		payload = appendUvarint(payload, uint64(c.TradeCount))
+	}
+
+	return payload
+}
+
+func decodeBlock(payload []byte, count int, first int64) ([]*Candle, error) {
+	reader := &payloadReader{payload: payload}
+	priceScale, volumeScale := reader.byte(), reader.byte()
+
+	candles := make([]*Candle, 0, count)
+	previousStart := first
+	var previousDuration, previousClose int64
+
+	for i := 0; i < count; i++ {
+		start := previousStart + reader.varint()
+		duration := previousDuration + reader.varint()
+		previousStart, previousDuration = start, duration
+
+		candle := &Candle{Period: TimePeriod{
+			Start: time.Unix(0, start).UTC(),
+			End:   time.Unix(0, start+duration).UTC(),
+		}}
+
+		if priceScale == binaryRawScale {
+			candle.OpenPrice = big.NewDecimal(reader.float())
+			candle.ClosePrice = big.NewDecimal(reader.float())
+			candle.MaxPrice = big.NewDecimal(reader.float())
+			candle.MinPrice = big.NewDecimal(reader.float())
+		} else {
+			open := previousClose + reader.varint()
+			close := open + reader.varint()
+			high := max64(open, close) + reader.varint()
+			low := min64(open, close) - reader.varint()
+			previousClose = close
+
+			candle.OpenPrice = fromFixedPoint(open, priceScale)
+			candle.ClosePrice = fromFixedPoint(close, priceScale)
+			candle.MaxPrice = fromFixedPoint(high, priceScale)
+			candle.MinPrice = fromFixedPoint(low, priceScale)
+		}
+
+		if volumeScale == binaryRawScale {
+			candle.Volume = big.NewDecimal(reader.float())
+		} else {
+			candle.Volume = fromFixedPoint(reader.varint(), volumeScale)
+		}
+
+		candle.TradeCount = uint(reader.uvarint())
+
+		if reader.err != nil {
+			return nil, reader.err
+		}
+
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+// fixedPointScale returns the smallest number of decimal places at which all values are stored
+// exactly as integers, or binaryRawScale if there is none.
+func fixedPointScale(values []float64) byte {
+	for scale := byte(0); scale <= binaryMaxScale; scale++ {
+		exact := true
+
+		for _, value := range values {
+			scaled := math.Round(value * math.Pow10(int(scale)))
+			if math.Abs(scaled) > binaryMaxInteger || scaled/math.Pow10(int(scale)) != value || (value == 0 && math.Signbit(value)) {
+				exact = false
+				break
+			}
+		}
+
+		if exact {
+			return scale
+		}
+	}
+
+	return binaryRawScale
+}
+
+func toFixedPoint(value float64, scale byte) int64 {
+	return int64(math.Round(value * math.Pow10(int(scale))))
+}
+
+// fromFixedPoint restores the value of an integer with the given number of decimal places. Both
+// operands are exact float64 values, so the division rounds to the same value that was encoded.
+func fromFixedPoint(value int64, scale byte) big.Decimal {
+	return big.NewDecimal(float64(value) / math.Pow10(int(scale)))
+}
+
+// appendUvarint, appendVarint and appendUint64 append values in the encodings of encoding/binary
+func appendUvarint(data []byte, value uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(data, scratch[:binary.PutUvarint(scratch[:], value)]...)
+}
+
+func appendVarint(data []byte, value int64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(data, scratch[:binary.PutVarint(scratch[:], value)]...)
+}
+
+func appendUint64(data []byte, value uint64) []byte {
+	var scratch [8]byte
+	binary.LittleEndian.PutUint64(scratch[:], value)
+	return append(data, scratch[:]...)
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+type payloadReader struct {
+	payload []byte
+	err     error
+}
+
+func (pr *payloadReader) fail() {
+	if pr.err == nil {
+		pr.err = fmt.Errorf("corrupt binary timeseries block")
+	}
+	pr.payload = nil
+}
+
+func (pr *payloadReader) byte() byte {
+	if len(pr.payload) == 0 {
+		pr.fail()
+		return 0
+	}
+
+	b := pr.payload[0]
+	pr.payload = pr.payload[1:]
+	return b
+}
+
+func (pr *payloadReader) varint() int64 {
+	value, n := binary.Varint(pr.payload)
+	if n <= 0 {
+		pr.fail()
+		return 0
+	}
+
+	pr.payload = pr.payload[n:]
+	return value
+}
+
+func (pr *payloadReader) uvarint() uint64 {
+	value, n := binary.Uvarint(pr.payload)
+	if n <= 0 {
+		pr.fail()
+		return 0
+	}
+
+	pr.payload = pr.payload[n:]
+	return value
+}
+
+func (pr *payloadReader) float() float64 {
+	if len(pr.payload) < 8 {
+		pr.fail()
+		return 0
+	}
+
+	value := math.Float64frombits(binary.LittleEndian.Uint64(pr.payload))
+	pr.payload = pr.payload[8:]
+	return value
+}
+
+// byteReader reads single bytes from a reader without buffering, so the position of the reader
+// stays at the end of what was read.
+type byteReader struct {
+	r io.Reader
+}
+
+func (br byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(br.r, b[:])
+	return b[0], err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,242 @@
+package techan
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertCandlesEqual(t *testing.T, expected, actual []*Candle) {
+	if !assert.EqualValues(t, len(expected), len(actual)) {
+		return
+	}
+
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		assert.True(t, e.Period.Start.Equal(a.Period.Start), "start of candle %v", i)
+		assert.True(t, e.Period.End.Equal(a.Period.End), "end of candle %v", i)
+
+		for _, prices := range [][2]big.Decimal{
+			{e.OpenPrice, a.OpenPrice},
+			{e.ClosePrice, a.ClosePrice},
+			{e.MaxPrice, a.MaxPrice},
+			{e.MinPrice, a.MinPrice},
+			{e.Volume, a.Volume},
+		} {
+			assert.EqualValues(t, prices[0].String(), prices[1].String(), "candle %v", i)
+			assert.EqualValues(t, prices[0].NaN(), prices[1].NaN(), "candle %v", i)
+			assert.True(t, prices[0].NaN() || prices[0].EQ(prices[1]), "candle %v", i)
+		}
+
+		assert.EqualValues(t, e.TradeCount, a.TradeCount, "trade count of candle %v", i)
+	}
+}
+
+func mockMinuteSeries(size int) *TimeSeries {
+	series := NewTimeSeries()
+	start := time.Date(2021, 1, 4, 14, 30, 0, 0, time.UTC)
+
+	for i := 0; i < size; i++ {
+		candle := NewCandle(NewTimePeriod(start.Add(time.Duration(i)*time.Minute), time.Minute))
+		candle.OpenPrice = big.NewFromString("101.25").Add(big.NewFromInt(i % 7))
+		candle.ClosePrice = big.NewFromString("101.37").Add(big.NewFromInt(i % 5))
+		candle.MaxPrice = big.MaxSlice(candle.OpenPrice, candle.ClosePrice).Add(big.NewFromString("0.05"))
+		candle.MinPrice = big.MinSlice(candle.OpenPrice, candle.ClosePrice).Sub(big.NewFromString("0.11"))
+		candle.Volume = big.NewFromInt(1000 + i)
+		candle.TradeCount = uint(i * 3)
+
+		series.AddCandle(candle)
+	}
+
+	return series
+}
+
+func TestTimeSeriesBinary(t *testing.T) {
+	series := mockMinuteSeries(100)
+
+	for name, options := range map[string]BinaryOptions{
+		"uncompressed": {BlockSize: 16},
+		"compressed":   {BlockSize: 16, Compress: true},
+		"single block": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			assert.Nil(t, WriteTimeSeriesBinary(&buffer, series, options))
+
+			decoded, err := ReadTimeSeriesBinary(&buffer)
+			assert.Nil(t, err)
+			assertCandlesEqual(t, series.Candles, decoded.Candles)
+		})
+	}
+
+	t.Run("compression reduces the size", func(t *testing.T) {
+		var plain, compressed bytes.Buffer
+		assert.Nil(t, WriteTimeSeriesBinary(&plain, series, BinaryOptions{}))
+		assert.Nil(t, WriteTimeSeriesBinary(&compressed, series, BinaryOptions{Compress: true}))
+
+		assert.True(t, compressed.Len() < plain.Len())
+		assert.True(t, plain.Len() < len(series.Candles)*20)
+	})
+
+	t.Run("values without a fixed-point representation", func(t *testing.T) {
+		third := big.ONE.Div(big.NewFromInt(3))
+
+		irregular := NewTimeSeries()
+		for i, price := range []big.Decimal{third, big.NewDecimal(1e300), big.NewFromString("-0.000000000000000001")} {
+			candle := NewCandle(NewTimePeriod(time.Unix(int64(i), 0), time.Second))
+			candle.OpenPrice, candle.ClosePrice, candle.MaxPrice, candle.MinPrice = price, price, price, price
+			candle.Volume = third
+			irregular.AddCandle(candle)
+		}
+		irregular.AddCandle(&Candle{Period: NewTimePeriod(time.Unix(3, 0), time.Second)})
+
+		var buffer bytes.Buffer
+		assert.Nil(t, WriteTimeSeriesBinary(&buffer, irregular, BinaryOptions{BlockSize: 2}))
+
+		decoded, err := ReadTimeSeriesBinary(&buffer)
+		assert.Nil(t, err)
+		assertCandlesEqual(t, irregular.Candles, decoded.Candles)
+	})
+
+	t.Run("empty series", func(t *testing.T) {
+		var buffer bytes.Buffer
+		assert.Nil(t, WriteTimeSeriesBinary(&buffer, NewTimeSeries(), BinaryOptions{}))
+
+		decoded, err := ReadTimeSeriesBinary(&buffer)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(decoded.Candles))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		filepath := "series.bin"
+		assert.Nil(t, ExportTimeSeriesBinary(filepath, series, BinaryOptions{Compress: true}))
+		defer os.Remove(filepath)
+
+		loaded, err := LoadTimeSeriesBinary(filepath)
+		assert.Nil(t, err)
+		assertCandlesEqual(t, series.Candles, loaded.Candles)
+
+		period := TimePeriod{Start: series.Candles[40].Period.Start, End: series.Candles[60].Period.Start}
+		loaded, err = LoadTimeSeriesBinaryRange(filepath, period)
+		assert.Nil(t, err)
+		assertCandlesEqual(t, series.Candles[40:60], loaded.Candles)
+	})
+}
+
+// onlyReader hides any methods of a reader other than Read, such as Seek
+type onlyReader struct {
+	io.Reader
+}
+
+func TestReadTimeSeriesBinaryRange(t *testing.T) {
+	series := mockMinuteSeries(100)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteTimeSeriesBinary(&buffer, series, BinaryOptions{BlockSize: 10, Compress: true}))
+
+	for _, test := range []struct {
+		name  string
+		start int
+		end   int
+	}{
+		{"within a block", 22, 27},
+		{"across blocks", 15, 71},
+		{"first candle", 0, 1},
+		{"to the end", 95, 100},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			period := TimePeriod{
+				Start: series.Candles[0].Period.Start.Add(time.Duration(test.start) * time.Minute),
+				End:   series.Candles[0].Period.Start.Add(time.Duration(test.end) * time.Minute),
+			}
+
+			seeked, err := ReadTimeSeriesBinaryRange(bytes.NewReader(buffer.Bytes()), period)
+			assert.Nil(t, err)
+			assertCandlesEqual(t, series.Candles[test.start:test.end], seeked.Candles)
+
+			streamed, err := ReadTimeSeriesBinaryRange(onlyReader{bytes.NewReader(buffer.Bytes())}, period)
+			assert.Nil(t, err)
+			assertCandlesEqual(t, series.Candles[test.start:test.end], streamed.Candles)
+		})
+	}
+}
+
+func TestTimeSeriesEncoder(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewTimeSeriesEncoder(&buffer, BinaryOptions{BlockSize: 2})
+	series := mockMinuteSeries(5)
+
+	for _, candle := range series.Candles {
+		assert.Nil(t, encoder.Encode(candle))
+	}
+
+	assert.EqualError(t, encoder.Encode(series.Candles[0]), "candle 2021-01-04T14:30:00 -> 2021-01-04T14:31:00 is before the previous candle")
+	assert.Nil(t, encoder.Close())
+	assert.NotNil(t, encoder.Encode(series.Candles[4]))
+
+	decoder, err := NewTimeSeriesDecoder(&buffer)
+	assert.Nil(t, err)
+
+	for _, expected := range series.Candles {
+		candle, err := decoder.Decode()
+		assert.Nil(t, err)
+		assertCandlesEqual(t, []*Candle{expected}, []*Candle{candle})
+	}
+
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTimeSeriesDecoder_Errors(t *testing.T) {
+	_, err := NewTimeSeriesDecoder(bytes.NewReader([]byte("CSV,")))
+	assert.NotNil(t, err)
+
+	_, err = NewTimeSeriesDecoder(bytes.NewReader([]byte("TCSB\x02\x00")))
+	assert.EqualError(t, err, "unsupported binary timeseries version 2")
+
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteTimeSeriesBinary(&buffer, mockMinuteSeries(10), BinaryOptions{}))
+
+	_, err = ReadTimeSeriesBinary(bytes.NewReader(buffer.Bytes()[:buffer.Len()-10]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	block := func(flags byte, count, length uint64, payload []byte) []byte {
+		data := append([]byte(binaryMagic), binaryVersion, flags)
+		var scratch [binary.MaxVarintLen64]byte
+		for _, value := range []uint64{count, 0, 0, length} {
+			data = append(data, scratch[:binary.PutUvarint(scratch[:], value)]...)
+		}
+		return append(data, payload...)
+	}
+
+	// corrupt lengths and counts are rejected without allocating for them
+	_, err = ReadTimeSeriesBinary(bytes.NewReader(block(0, 1, 1<<50, make([]byte, 16))))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = ReadTimeSeriesBinary(bytes.NewReader(block(0, 1, math.MaxUint64, nil)))
+	assert.EqualError(t, err, "invalid binary timeseries block length 18446744073709551615")
+
+	_, err = ReadTimeSeriesBinary(bytes.NewReader(block(0, 1<<60, 16, make([]byte, 16))))
+	assert.EqualError(t, err, "binary timeseries block of 16 bytes cannot hold 1152921504606846976 candles")
+
+	var compressed bytes.Buffer
+	writer, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	writer.Write(make([]byte, 1<<20))
+	writer.Close()
+
+	_, err = ReadTimeSeriesBinary(bytes.NewReader(block(binaryCompressed, 1<<20, uint64(compressed.Len()), compressed.Bytes())))
+	assert.EqualError(t, err, "binary timeseries block of 1048576 bytes cannot hold 1048576 candles")
+
+	// decompression stops at the largest payload the candles of the block can have
+	series, err := ReadTimeSeriesBinary(bytes.NewReader(append(block(binaryCompressed, 1, uint64(compressed.Len()), compressed.Bytes()), 0)))
+	assert.Nil(t, err)
+	assert.Len(t, series.Candles, 1)
+}
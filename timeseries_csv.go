@@ -0,0 +1,484 @@
+package techan
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schmidthole/big"
+)
+
+// TimestampFormat defines how the timestamp column of a csv file is parsed. Any value other than
+// the enumerations below is used as a time layout, as in time.Parse.
+type TimestampFormat string
+
+// TimestampFormat enumerations. AUTO_TIMESTAMP detects epoch timestamps by their magnitude and
+// otherwise tries RFC3339 and the simple date time formats.
+const (
+	AUTO_TIMESTAMP     TimestampFormat = ""
+	EPOCH_SECONDS      TimestampFormat = "epoch_s"
+	EPOCH_MILLISECONDS TimestampFormat = "epoch_ms"
+	EPOCH_MICROSECONDS TimestampFormat = "epoch_us"
+	EPOCH_NANOSECONDS  TimestampFormat = "epoch_ns"
+	RFC3339_TIMESTAMP  TimestampFormat = TimestampFormat(time.RFC3339Nano)
+	DATE_TIMESTAMP     TimestampFormat = SimpleDateFormatV2
+)
+
+// CsvHeader defines whether the first row of a csv file holds the column names.
+type CsvHeader int
+
+// CsvHeader enumerations. DETECT_HEADER treats the first row as a header if none of its fields are
+// numbers.
+const (
+	DETECT_HEADER CsvHeader = iota
+	HAS_HEADER
+	NO_HEADER
+)
+
+// A CsvColumn identifies a column of a csv file by its header name or by its zero based index. The
+// zero value selects the column by its common header names (e.g. "date", "open", "vol") when the
+// file has a header, and by its position in a time, open, high, low, close, volume layout when it
+// does not.
+type CsvColumn struct {
+	name  string
+	index int
+	set   bool
+}
+
+// NoColumn marks a column as not present in the file. Missing open, high and low prices are set to
+// the close price and missing volumes and trade counts are left at zero.
+var NoColumn = CsvColumn{index: -1, set: true}
+
+// ColumnName selects a column by its header name. Names are matched without regard to case.
+func ColumnName(name string) CsvColumn {
+	return CsvColumn{name: name, set: true}
+}
+
+// ColumnIndex selects a column by its zero based index.
+func ColumnIndex(index int) CsvColumn {
+	return CsvColumn{index: index, set: true}
+}
+
+// CsvOptions configures how a csv file is read into a TimeSeries. The zero value reads a comma
+// separated file with an optional header, detects the timestamp format, interprets timestamps
+// without a zone as UTC and infers the candle duration from the smallest gap between timestamps.
+type CsvOptions struct {
+	Comma           rune
+	Header          CsvHeader
+	Time            CsvColumn
+	Open            CsvColumn
+	High            CsvColumn
+	Low             CsvColumn
+	Close           CsvColumn
+	Volume          CsvColumn
+	TradeCount      CsvColumn
+	TimestampFormat TimestampFormat
+	Location        *time.Location
+	Duration        time.Duration
+}
+
+var csvColumnNames = []string{"time", "open", "high", "low", "close", "volume", "trade_count"}
+
+var csvColumnAliases = map[string][]string{
+	"time":        {"time", "timestamp", "date", "datetime", "date_time", "open_time", "t"},
+	"open":        {"open", "o", "open_price"},
+	"high":        {"high", "h", "max", "high_price"},
+	"low":         {"low", "l", "min", "low_price"},
+	"close":       {"close", "c", "close_price", "last"},
+	"volume":      {"volume", "vol", "v"},
+	"trade_count": {"trade_count", "trades", "count", "number_of_trades"},
+}
+
+var csvDefaultIndexes = map[string]int{
+	"time":        0,
+	"open":        1,
+	"high":        2,
+	"low":         3,
+	"close":       4,
+	"volume":      5,
+	"trade_count": -1,
+}
+
+// Reads candles from csv data into a new TimeSeries. Rows must be in chronological order. Any row
+// that cannot be parsed, or whose prices are inconsistent (e.g. a low above the high), results in
+// an error reporting its line number.
+func ReadTimeSeriesCsv(r io.Reader, options CsvOptions) (*TimeSeries, error) {
+	counter := &lineCounter{r: bufio.NewReader(r)}
+	reader := csv.NewReader(counter)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comma = ','
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+
+	location := options.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	rows := [][]string{}
+	lines := []int{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a record starts as many lines before the last line read as it has line breaks in quoted fields
+		line := counter.lines
+		for _, field := range row {
+			line -= strings.Count(field, "\n")
+		}
+
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no csv rows to read")
+	}
+
+	var header []string
+	if options.Header == HAS_HEADER || (options.Header == DETECT_HEADER && isCsvHeader(rows[0])) {
+		header = rows[0]
+		rows, lines = rows[1:], lines[1:]
+	}
+
+	columns := map[string]int{}
+	for i, column := range []CsvColumn{
+		options.Time,
+		options.Open,
+		options.High,
+		options.Low,
+		options.Close,
+		options.Volume,
+		options.TradeCount,
+	} {
+		name := csvColumnNames[i]
+
+		index, err := column.resolve(name, header)
+		if err != nil {
+			return nil, err
+		}
+
+		columns[name] = index
+	}
+
+	// a file without a header need not have a volume after the prices, which is then left at zero
+	// rather than reported missing on every row
+	if header == nil && !options.Volume.set && len(rows) > 0 && columns["volume"] >= len(rows[0]) {
+		columns["volume"] = -1
+	}
+
+	if columns["time"] < 0 || columns["close"] < 0 {
+		return nil, fmt.Errorf("time and close columns are required")
+	}
+
+	starts := make([]time.Time, len(rows))
+	for i, row := range rows {
+		field, err := csvField(row, columns["time"], "time", lines[i])
+		if err != nil {
+			return nil, err
+		}
+
+		starts[i], err = parseTimestamp(field, options.TimestampFormat, location)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid time %q: %v", lines[i], field, err)
+		}
+	}
+
+	return candleSeries(rows, lines, columns, starts, options.Duration)
+}
+
+// Loads candles from a csv file into a new TimeSeries. Files with a .tsv extension are read as tab
+// separated unless a separator is configured.
+func LoadTimeSeriesCsv(filepath string, options CsvOptions) (*TimeSeries, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if options.Comma == 0 && strings.EqualFold(path.Ext(filepath), ".tsv") {
+		options.Comma = '\t'
+	}
+
+	return ReadTimeSeriesCsv(file, options)
+}
+
+// candleSeries builds a series from rows of text fields and the start times parsed from them. The
+// duration of the candles is inferred from the start times if it is zero.
+func candleSeries(rows [][]string, lines []int, columns map[string]int, starts []time.Time, duration time.Duration) (*TimeSeries, error) {
+	if duration == 0 {
+		duration = inferCandleDuration(starts)
+		if duration == 0 {
+			return nil, fmt.Errorf("cannot infer the candle duration from less than two distinct timestamps")
+		}
+	}
+
+	series := NewTimeSeries()
+	for i, row := range rows {
+		candle, err := csvCandle(row, columns, NewTimePeriod(starts[i], duration), lines[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if last := series.LastCandle(); last != nil && !candle.Period.Start.After(last.Period.Start) {
+			return nil, fmt.Errorf("line %v: time %v is not after the previous candle", lines[i], candle.Period.Start)
+		}
+
+		series.AddCandle(candle)
+	}
+
+	return series, nil
+}
+
+func (c CsvColumn) resolve(column string, header []string) (int, error) {
+	if c.set && c.name == "" {
+		return c.index, nil
+	}
+
+	if c.set {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), c.name) {
+				return i, nil
+			}
+		}
+
+		return -1, fmt.Errorf("%v column %q not found in header", column, c.name)
+	}
+
+	for _, alias := range csvColumnAliases[column] {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), alias) {
+				return i, nil
+			}
+		}
+	}
+
+	// columns not named in a header are treated as missing, except for the time which is usually
+	// the first column regardless of its name
+	if header != nil && column != "time" {
+		return -1, nil
+	}
+
+	return csvDefaultIndexes[column], nil
+}
+
+func csvCandle(row []string, columns map[string]int, period TimePeriod, line int) (*Candle, error) {
+	candle := NewCandle(period)
+
+	prices := map[string]*big.Decimal{
+		"close": &candle.ClosePrice,
+		"open":  &candle.OpenPrice,
+		"high":  &candle.MaxPrice,
+		"low":   &candle.MinPrice,
+	}
+
+	for _, name := range []string{"close", "open", "high", "low"} {
+		if columns[name] < 0 {
+			*prices[name] = candle.ClosePrice
+			continue
+		}
+
+		value, err := csvDecimal(row, columns[name], name, line)
+		if err != nil {
+			return nil, err
+		}
+
+		*prices[name] = value
+	}
+
+	if columns["volume"] >= 0 {
+		volume, err := csvDecimal(row, columns["volume"], "volume", line)
+		if err != nil {
+			return nil, err
+		}
+
+		if volume.LT(big.ZERO) {
+			return nil, fmt.Errorf("line %v: negative volume %v", line, volume)
+		}
+
+		candle.Volume = volume
+	}
+
+	if columns["trade_count"] >= 0 {
+		field, err := csvField(row, columns["trade_count"], "trade_count", line)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := strconv.ParseUint(field, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid trade_count %q", line, field)
+		}
+
+		candle.TradeCount = uint(count)
+	}
+
+	if candle.MaxPrice.LT(candle.MinPrice) {
+		return nil, fmt.Errorf("line %v: high %v is below low %v", line, candle.MaxPrice, candle.MinPrice)
+	}
+
+	for _, price := range []big.Decimal{candle.OpenPrice, candle.ClosePrice} {
+		if price.GT(candle.MaxPrice) || price.LT(candle.MinPrice) {
+			return nil, fmt.Errorf("line %v: price %v is outside of the high/low range", line, price)
+		}
+	}
+
+	return candle, nil
+}
+
+func csvField(row []string, index int, name string, line int) (string, error) {
+	if index >= len(row) {
+		return "", fmt.Errorf("line %v: missing %v column %v", line, name, index)
+	}
+
+	return strings.TrimSpace(row[index]), nil
+}
+
+func csvDecimal(row []string, index int, name string, line int) (big.Decimal, error) {
+	field, err := csvField(row, index, name, line)
+	if err != nil {
+		return big.NaN, err
+	}
+
+	if _, err := strconv.ParseFloat(field, 64); err != nil {
+		return big.NaN, fmt.Errorf("line %v: invalid %v %q", line, name, field)
+	}
+
+	return big.NewFromString(field), nil
+}
+
+func isCsvHeader(row []string) bool {
+	for _, field := range row {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseTimestamp(value string, format TimestampFormat, location *time.Location) (time.Time, error) {
+	switch format {
+	case AUTO_TIMESTAMP:
+		if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+			abs := epoch
+			if abs < 0 {
+				abs = -abs
+			}
+
+			switch {
+			case abs < 1e11:
+				return time.Unix(epoch, 0).In(location), nil
+			case abs < 1e14:
+				return time.Unix(0, epoch*int64(time.Millisecond)).In(location), nil
+			case abs < 1e17:
+				return time.Unix(0, epoch*int64(time.Microsecond)).In(location), nil
+			default:
+				return time.Unix(0, epoch).In(location), nil
+			}
+		}
+
+		var err error
+		for _, layout := range []string{
+			time.RFC3339Nano,
+			SimpleDateFormatV2 + "T" + SimpleTimeFormat,
+			SimpleDateFormatV2 + " " + SimpleTimeFormat,
+			SimpleDateFormatV2,
+			SimpleDateTimeFormat,
+			SimpleDateFormat,
+		} {
+			var t time.Time
+			if t, err = time.ParseInLocation(layout, value, location); err == nil {
+				return t.In(location), nil
+			}
+		}
+
+		return time.Time{}, err
+	case EPOCH_SECONDS, EPOCH_MILLISECONDS, EPOCH_MICROSECONDS, EPOCH_NANOSECONDS:
+		epoch, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if format == EPOCH_SECONDS {
+			return time.Unix(epoch, 0).In(location), nil
+		}
+
+		unit := map[TimestampFormat]time.Duration{
+			EPOCH_MILLISECONDS: time.Millisecond,
+			EPOCH_MICROSECONDS: time.Microsecond,
+			EPOCH_NANOSECONDS:  time.Nanosecond,
+		}[format]
+
+		return time.Unix(0, epoch*int64(unit)).In(location), nil
+	default:
+		t, err := time.ParseInLocation(string(format), value, location)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return t.In(location), nil
+	}
+}
+
+// inferCandleDuration returns the smallest positive gap between consecutive timestamps, which is
+// the candle duration for data with gaps such as weekends and holidays.
+func inferCandleDuration(starts []time.Time) time.Duration {
+	var duration time.Duration
+
+	for i := 1; i < len(starts); i++ {
+		gap := starts[i].Sub(starts[i-1])
+		if gap > 0 && (duration == 0 || gap < duration) {
+			duration = gap
+		}
+	}
+
+	return duration
+}
+
+// lineCounter passes on at most one line for every read, so that the csv reader never reads past
+// the end of the record it is parsing, and counts the lines it has passed on.
+type lineCounter struct {
+	r       *bufio.Reader
+	pending []byte
+	err     error
+	lines   int
+}
+
+func (lc *lineCounter) Read(p []byte) (int, error) {
+	if len(lc.pending) == 0 {
+		if lc.err != nil {
+			return 0, lc.err
+		}
+
+		line, err := lc.r.ReadSlice('\n')
+		if err != bufio.ErrBufferFull {
+			lc.err = err
+			if len(line) > 0 {
+				lc.lines++
+			}
+		}
+
+		if len(line) == 0 {
+			return 0, lc.err
+		}
+		lc.pending = line
+	}
+
+	n := copy(p, lc.pending)
+	lc.pending = lc.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,216 @@
+package techan
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTimeSeriesCsv(t *testing.T) {
+	data := `Date,Open,High,Low,Close,Adj Close,Volume
+2020-01-02,10.0,12.0,9.5,11.0,10.9,1000
+2020-01-03,11.0,11.5,10.0,10.5,10.4,1200
+2020-01-06,10.5,13.0,10.5,12.5,12.4,900
+`
+
+	series, err := ReadTimeSeriesCsv(strings.NewReader(data), CsvOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(series.Candles))
+
+	candle := series.Candles[0]
+	assert.Equal(t, time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC), candle.Period.Start)
+	assert.Equal(t, time.Hour*24, candle.Period.Length())
+	decimalEquals(t, 10.0, candle.OpenPrice)
+	decimalEquals(t, 12.0, candle.MaxPrice)
+	decimalEquals(t, 9.5, candle.MinPrice)
+	decimalEquals(t, 11.0, candle.ClosePrice)
+	decimalEquals(t, 1000.0, candle.Volume)
+
+	// the weekend gap does not change the inferred duration
+	assert.Equal(t, time.Hour*24, series.Candles[2].Period.Length())
+}
+
+func TestReadTimeSeriesCsv_Options(t *testing.T) {
+	data := "1577836800000\t100\t3\t7\n1577836860000\t101\t4\t5\n"
+
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	series, err := ReadTimeSeriesCsv(strings.NewReader(data), CsvOptions{
+		Comma:           '\t',
+		Time:            ColumnIndex(0),
+		Open:            NoColumn,
+		High:            NoColumn,
+		Low:             NoColumn,
+		Close:           ColumnIndex(1),
+		Volume:          ColumnIndex(3),
+		TradeCount:      ColumnIndex(2),
+		TimestampFormat: EPOCH_MILLISECONDS,
+		Location:        newYork,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series.Candles))
+
+	candle := series.Candles[1]
+	assert.Equal(t, time.Date(2020, time.January, 1, 0, 1, 0, 0, time.UTC).Unix(), candle.Period.Start.Unix())
+	assert.Equal(t, newYork, candle.Period.Start.Location())
+	assert.Equal(t, time.Minute, candle.Period.Length())
+	decimalEquals(t, 101.0, candle.OpenPrice)
+	decimalEquals(t, 101.0, candle.MaxPrice)
+	decimalEquals(t, 101.0, candle.ClosePrice)
+	decimalEquals(t, 5.0, candle.Volume)
+	assert.EqualValues(t, 4, candle.TradeCount)
+}
+
+func TestReadTimeSeriesCsv_ColumnNames(t *testing.T) {
+	data := `px_last;px_open;px_high;px_low;stamp
+11;10;12;9;2020-01-02 09:30:00
+12;11;13;10;2020-01-02 09:35:00
+`
+
+	series, err := ReadTimeSeriesCsv(strings.NewReader(data), CsvOptions{
+		Comma:           ';',
+		Time:            ColumnName("STAMP"),
+		Open:            ColumnName("px_open"),
+		High:            ColumnName("px_high"),
+		Low:             ColumnName("px_low"),
+		Close:           ColumnName("px_last"),
+		TimestampFormat: TimestampFormat(SimpleDateFormatV2 + " " + SimpleTimeFormat),
+		Duration:        time.Minute * 5,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series.Candles))
+	decimalEquals(t, 11.0, series.Candles[1].OpenPrice)
+	decimalEquals(t, 0.0, series.Candles[1].Volume)
+	assert.Equal(t, time.Date(2020, time.January, 2, 9, 35, 0, 0, time.UTC), series.Candles[1].Period.Start)
+}
+
+func TestReadTimeSeriesCsv_WithoutVolume(t *testing.T) {
+	series, err := ReadTimeSeriesCsv(strings.NewReader("2020-01-01,1,2,1,1\n2020-01-02,1,2,1,2\n"), CsvOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series.Candles))
+	decimalEquals(t, 0.0, series.Candles[1].Volume)
+}
+
+func TestReadTimeSeriesCsv_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		options CsvOptions
+		err     string
+	}{
+		{
+			name: "invalid price",
+			data: "time,open,high,low,close\n2020-01-01,1,2,1,1\n2020-01-02,1,x,1,1\n",
+			err:  "line 3: invalid high \"x\"",
+		},
+		{
+			name: "high below low",
+			data: "2020-01-01,1,2,1,1,10\n2020-01-02,1,1,2,1,10\n",
+			err:  "line 2: high 1 is below low 2",
+		},
+		{
+			name: "close outside of range",
+			data: "2020-01-01,1,2,1,3,10\n2020-01-02,1,2,1,1,10\n",
+			err:  "line 1: price 3 is outside of the high/low range",
+		},
+		{
+			name: "invalid time",
+			data: "2020-01-01,1,2,1,1\n01-02-2020,1,2,1,1\n",
+			err:  "line 2: invalid time \"01-02-2020\"",
+		},
+		{
+			name: "out of order",
+			data: "2020-01-02,1,2,1,1\n2020-01-01,1,2,1,1\n2020-01-03,1,2,1,1\n",
+			err:  "line 2: time 2020-01-01 00:00:00 +0000 UTC is not after the previous candle",
+		},
+		{
+			name: "missing column",
+			data: "2020-01-01,1,2,1,1\n2020-01-02,1,2,1\n",
+			err:  "line 2: missing close column 4",
+		},
+		{
+			name: "missing volume",
+			data: "2020-01-01,1,2,1,1,10\n2020-01-02,1,2,1,1\n",
+			err:  "line 2: missing volume column 5",
+		},
+		{
+			name: "missing mapped volume",
+			data: "date,close,volume\n2020-01-01,1,10\n2020-01-02,1\n",
+			err:  "line 3: missing volume column 2",
+		},
+		{
+			name: "line breaks in quoted fields and blank lines",
+			data: "time,close,note\r\n2020-01-01,1,\"a\r\nb\"\r\n\r\n2020-01-02,x,c\r\n",
+			err:  "line 5: invalid close \"x\"",
+		},
+		{
+			name: "record with line breaks",
+			data: "time,close,note\n2020-01-01,1,a\n2020-01-02,x,\"b\n\nc\"\n",
+			err:  "line 3: invalid close \"x\"",
+		},
+		{
+			name:    "unknown column name",
+			data:    "date,close\n2020-01-01,1\n",
+			options: CsvOptions{Close: ColumnName("last_price")},
+			err:     "close column \"last_price\" not found in header",
+		},
+		{
+			name: "single row",
+			data: "2020-01-01,1,2,1,1\n",
+			err:  "cannot infer the candle duration from less than two distinct timestamps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadTimeSeriesCsv(strings.NewReader(tt.data), tt.options)
+			if assert.NotNil(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), tt.err), err.Error())
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{
+		"1577836800",
+		"1577836800000",
+		"1577836800000000",
+		"1577836800000000000",
+		"2020-01-01T00:00:00Z",
+		"2020-01-01T00:00:00",
+		"2020-01-01",
+	} {
+		parsed, err := parseTimestamp(value, AUTO_TIMESTAMP, time.UTC)
+		assert.Nil(t, err)
+		assert.True(t, expected.Equal(parsed), value)
+	}
+
+	parsed, err := parseTimestamp("1577836800000000000", EPOCH_NANOSECONDS, time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, expected.Equal(parsed))
+
+	parsed, err = parseTimestamp("2020-01-01T00:00:00+01:00", RFC3339_TIMESTAMP, time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, expected.Add(-time.Hour).Equal(parsed))
+}
+
+func TestLoadTimeSeriesCsv(t *testing.T) {
+	filepath := "series.tsv"
+	err := ioutil.WriteFile(filepath, []byte("time\tclose\n2020-01-01\t1\n2020-01-02\t2\n"), 0644)
+	assert.Nil(t, err)
+	defer os.Remove(filepath)
+
+	series, err := LoadTimeSeriesCsv(filepath, CsvOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(series.Candles))
+	decimalEquals(t, 2.0, series.Candles[1].ClosePrice)
+	decimalEquals(t, 2.0, series.Candles[1].OpenPrice)
+}
@@ -0,0 +1,266 @@
+package techan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// ParquetOptions configures how a Parquet file is read into a TimeSeries. Columns are selected as
+// in CsvOptions, by their common names when not set. The zero value reads timestamps without a
+// zone as UTC and infers the candle duration from the smallest gap between timestamps.
+type ParquetOptions struct {
+	Time       CsvColumn
+	Open       CsvColumn
+	High       CsvColumn
+	Low        CsvColumn
+	Close      CsvColumn
+	Volume     CsvColumn
+	TradeCount CsvColumn
+	Location   *time.Location
+	Duration   time.Duration
+}
+
+// Reads candles from a Parquet file into a new TimeSeries. The time column may be a timestamp, a
+// date, an epoch number or text, and prices may be floating point, integer, decimal or text columns.
+// Null prices other than the close are set to the close price, and null volumes and trade counts to
+// zero. Rows must be in chronological order, and any row which cannot be read, or whose prices are
+// inconsistent, results in an error reporting its line, the row number counted from one.
+func ReadTimeSeriesParquet(r io.Reader, options ParquetOptions) (*TimeSeries, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parquetColumns, err := readParquet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	location := options.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	header := make([]string, len(parquetColumns))
+	for i, column := range parquetColumns {
+		header[i] = column.name
+	}
+
+	columns := map[string]int{}
+	for i, column := range []CsvColumn{
+		options.Time,
+		options.Open,
+		options.High,
+		options.Low,
+		options.Close,
+		options.Volume,
+		options.TradeCount,
+	} {
+		name := csvColumnNames[i]
+
+		index, err := column.resolve(name, header)
+		if err != nil {
+			return nil, err
+		}
+
+		if index >= len(parquetColumns) {
+			return nil, fmt.Errorf("%v column %v not found", name, index)
+		}
+
+		columns[name] = index
+	}
+
+	if columns["time"] < 0 || columns["close"] < 0 {
+		return nil, fmt.Errorf("time and close columns are required")
+	}
+
+	count := len(parquetColumns[columns["time"]].values)
+	rows := make([][]string, count)
+	lines := make([]int, count)
+	starts := make([]time.Time, count)
+
+	for i := range rows {
+		lines[i] = i + 1
+
+		starts[i], err = parquetColumns[columns["time"]].time(i, location)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid time: %v", lines[i], err)
+		}
+
+		rows[i] = make([]string, len(parquetColumns))
+		for _, index := range columns {
+			if index >= 0 && index != columns["time"] {
+				rows[i][index] = parquetColumns[index].text(i)
+			}
+		}
+
+		// null values are treated like missing columns
+		for name, missing := range map[string]string{
+			"open":        rows[i][columns["close"]],
+			"high":        rows[i][columns["close"]],
+			"low":         rows[i][columns["close"]],
+			"volume":      "0",
+			"trade_count": "0",
+		} {
+			if index := columns[name]; index >= 0 && rows[i][index] == "" {
+				rows[i][index] = missing
+			}
+		}
+	}
+
+	return candleSeries(rows, lines, columns, starts, options.Duration)
+}
+
+// Loads candles from a Parquet file into a new TimeSeries.
+func LoadTimeSeriesParquet(filepath string, options ParquetOptions) (*TimeSeries, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadTimeSeriesParquet(file, options)
+}
+
+// Writes the candles of a TimeSeries as a Parquet file with time, end, open, high, low, close,
+// volume and trade_count columns. Times are UTC timestamps in microseconds and prices and volumes
+// are written as doubles.
+func WriteTimeSeriesParquet(w io.Writer, series *TimeSeries) error {
+	size := len(series.Candles)
+	starts, ends := make([]time.Time, size), make([]time.Time, size)
+	opens, highs, lows, closes, volumes := make([]float64, size), make([]float64, size), make([]float64, size), make([]float64, size), make([]float64, size)
+	counts := make([]int64, size)
+
+	for i, candle := range series.Candles {
+		starts[i], ends[i] = candle.Period.Start, candle.Period.End
+		opens[i], highs[i], lows[i], closes[i] = candle.OpenPrice.Float(), candle.MaxPrice.Float(), candle.MinPrice.Float(), candle.ClosePrice.Float()
+		volumes[i] = candle.Volume.Float()
+		counts[i] = int64(candle.TradeCount)
+	}
+
+	return writeParquet(w, []parquetField{
+		{"time", starts},
+		{"end", ends},
+		{"open", opens},
+		{"high", highs},
+		{"low", lows},
+		{"close", closes},
+		{"volume", volumes},
+		{"trade_count", counts},
+	})
+}
+
+// Exports the candles of a TimeSeries to a Parquet file which can be loaded with
+// LoadTimeSeriesParquet.
+func ExportTimeSeriesParquet(filepath string, series *TimeSeries) error {
+	return exportParquet(filepath, func(w io.Writer) error {
+		return WriteTimeSeriesParquet(w, series)
+	})
+}
+
+// Writes the values of indicators calculated on a series as a Parquet file, with a time column of
+// the candle start times followed by a double column for every indicator, ordered by name.
+func WriteIndicatorsParquet(w io.Writer, series *TimeSeries, indicators map[string]Indicator) error {
+	names := make([]string, 0, len(indicators))
+	for name := range indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	starts := make([]time.Time, len(series.Candles))
+	for i, candle := range series.Candles {
+		starts[i] = candle.Period.Start
+	}
+
+	fields := []parquetField{{"time", starts}}
+	for _, name := range names {
+		values := make([]float64, len(series.Candles))
+		for i := range series.Candles {
+			values[i] = indicators[name].Calculate(series.FirstIndex() + i).Float()
+		}
+
+		fields = append(fields, parquetField{name, values})
+	}
+
+	return writeParquet(w, fields)
+}
+
+// Exports the values of indicators calculated on a series to a Parquet file. See
+// WriteIndicatorsParquet.
+func ExportIndicatorsParquet(filepath string, series *TimeSeries, indicators map[string]Indicator) error {
+	return exportParquet(filepath, func(w io.Writer) error {
+		return WriteIndicatorsParquet(w, series, indicators)
+	})
+}
+
+// Writes the account snapshots as a Parquet file with start, end, equity and cash columns. Times are
+// UTC timestamps in microseconds and amounts are written as doubles.
+func (ah *AccountHistory) WriteParquet(w io.Writer) error {
+	size := len(ah.Snapshots)
+	starts, ends := make([]time.Time, size), make([]time.Time, size)
+	equities, cash := make([]float64, size), make([]float64, size)
+
+	for i, snapshot := range ah.Snapshots {
+		starts[i], ends[i] = snapshot.Period.Start, snapshot.Period.End
+		equities[i], cash[i] = snapshot.Equity.Float(), snapshot.Cash.Float()
+	}
+
+	return writeParquet(w, []parquetField{
+		{"start", starts},
+		{"end", ends},
+		{"equity", equities},
+		{"cash", cash},
+	})
+}
+
+// Exports the account snapshots to a Parquet file. See WriteParquet.
+func (ah *AccountHistory) ExportParquet(filepath string) error {
+	return exportParquet(filepath, ah.WriteParquet)
+}
+
+// Writes the positions of every account snapshot as a Parquet file with a row per position and
+// start, end, security, side, amount, price and unrealized_gain columns.
+func (ah *AccountHistory) WritePositionsParquet(w io.Writer) error {
+	starts, ends := []time.Time{}, []time.Time{}
+	securities, sides := []string{}, []string{}
+	amounts, prices, gains := []float64{}, []float64{}, []float64{}
+
+	for _, snapshot := range ah.Snapshots {
+		for _, position := range snapshot.Positions {
+			starts, ends = append(starts, snapshot.Period.Start), append(ends, snapshot.Period.End)
+			securities, sides = append(securities, position.Security), append(sides, string(position.Side))
+			amounts = append(amounts, position.Amount.Float())
+			prices = append(prices, position.Price.Float())
+			gains = append(gains, position.UnrealizedGain.Float())
+		}
+	}
+
+	return writeParquet(w, []parquetField{
+		{"start", starts},
+		{"end", ends},
+		{"security", securities},
+		{"side", sides},
+		{"amount", amounts},
+		{"price", prices},
+		{"unrealized_gain", gains},
+	})
+}
+
+// Exports the positions of every account snapshot to a Parquet file. See WritePositionsParquet.
+func (ah *AccountHistory) ExportPositionsParquet(filepath string) error {
+	return exportParquet(filepath, ah.WritePositionsParquet)
+}
+
+func exportParquet(filepath string, write func(io.Writer) error) error {
+	var buffer bytes.Buffer
+	if err := write(&buffer); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath, buffer.Bytes(), 0644)
+}
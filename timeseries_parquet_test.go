@@ -0,0 +1,229 @@
+package techan
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+// daily candles written by parquet-go with snappy compression and dictionary encoded close and
+// symbol columns, in version 1 data pages
+const snappyDictionaryParquet = "UEFSMRUGFVAVVBWYp/bvBEwVChUAFQoVABUAFQARAAAonACEscp2AQAAAODXz3YBAAAAPP7UdgEAAACYJNp2AQAAAPRK33YB" +
+	"AAAVBhVQFSgV8e3p0wZMFQoVABUKFQAVABUAEQAAKAAABQEEJEAJCAAmDQgAKA0IGRgVBhVQFSgV8ri/swNMFQoVABUKFQAV" +
+	"ABUAEQAAKAAABQEEKkAJCAAsDQgALg0IGRgVBhVQFRYVmbGUuA1MFQoVABUKFQAVABUAEQAAKAAABQEEIkB+CAAVBBVAFUQV" +
+	"xtrDJzwVCBUAAAAgfAAAAAAAACdAAAAAAACAJ0AAAAAAAAAoQAAAAAAAgChAFQYVFhUWFcTL6eMPTBUKFQAVChUQFQAVABIA" +
+	"AAICAAIBAgICAwIAFQYVUBU4FYyWvQpMFQoVABUKFQAVABUAEQAAKAjoAwAFAQDpDQgA6g0IAOsNCBzsAwAAAAAAABUEFQ4V" +
+	"EhXtybzdBDwVAhUAAAAHGAMAAABTUFkVBhUEFQQVvZCY7wtMFQoVABUKFRAVABUAEgAAAAoZEgAZGAgAhLHKdgEAABkYCAD0" +
+	"St92AQAAFQAZFgAAGRIAGRgIAAAAAAAAJEAZGAgAAAAAAAAoQBUAGRYAABkSABkYCAAAAAAAACpAGRgIAAAAAAAALkAVABkW" +
+	"AAAZEgAZGAgAAAAAAAAiQBkYCAAAAAAAACJAFQAZFgAAGRIAGRgIAAAAAAAAJ0AZGAgAAAAAAIAoQBUAGRYAABkSABkYCOgD" +
+	"AAAAAAAAGRgI7AMAAAAAAAAVABkWAAAZEgAZGANTUFkZGANTUFkVABkWAAAZHBYIFYwBFgAAABkcFpQBFWAWAAAAGRwW9AEV" +
+	"YBYAAAAZHBbUAhVOFgAAABkcFooEFU4WAAAAGRwW2AQVbhYAAAAZHBb+BRU8FgAAABUEGYxIA1JvdxUOABUEFYABFQAYBGRh" +
+	"dGUlEkyMERwcAAAAAAAVChWAARUAGARvcGVuABUKFYABFQAYBGhpZ2gAFQoVgAEVABgDbG93ABUKFYABFQAYBWNsb3NlABUE" +
+	"FYABFQAYBnZvbHVtZSUkTKwTQBEAAAAVDCUAGAZzeW1ib2wlAEwcAAAAFgoZHBl8JgAcFQQZFQAZGARkYXRlFQIWChaIARaM" +
+	"ASYIPFgIAPRK33YBAAAYCACEscp2AQAAABkcFQYVABUCAAAW2AkVFha6BhU+ACYAHBUKGRUAGRgEb3BlbhUCFgoWiAEWYCaU" +
+	"ATxYCAAAAAAAAChAGAgAAAAAAAAkQAAZHBUGFQAVAgAAFu4JFRYW+AYVPgAmABwVChkVABkYBGhpZ2gVAhYKFogBFmAm9AE8" +
+	"WAgAAAAAAAAuQBgIAAAAAAAAKkAAGRwVBhUAFQIAABaEChUWFrYHFT4AJgAcFQoZFQAZGANsb3cVAhYKFogBFk4m1AI8WAgA" +
+	"AAAAAAAiQBgIAAAAAAAAIkAAGRwVBhUAFQIAABaaChUWFvQHFT4AJgAcFQoZJQAQGRgFY2xvc2UVAhYKFrIBFrYBJooEJqID" +
+	"HFgIAAAAAACAKEAYCAAAAAAAACdAABksFQQVABUCABUGFRAVAgAAFrAKFRYWsggVPgAmABwVBBkVABkYBnZvbHVtZRUCFgoW" +
+	"hgEWbibYBDxYCOwDAAAAAAAAGAjoAwAAAAAAAAAZHBUGFQAVAgAAFsYKFRYW8AgVPgAmABwVDBklABAZGAZzeW1ib2wVAhYK" +
+	"FnAWdCb+BSbGBRxYA1NQWRgDU1BZABksFQQVABUCABUGFRAVAgAAFtwKFRYWrgkVKgAWyAcWChkMFggWsgYAGQwYN2dpdGh1" +
+	"Yi5jb20vcGFycXVldC1nby9wYXJxdWV0LWdvIHZlcnNpb24gMC4yNS4xKGJ1aWxkICkZfBwAABwAABwAABwAABwAABwAABwA" +
+	"AADpAgAAUEFSMQ=="
+
+// minute closes with epoch millisecond timestamps and an optional volume column with nulls, written
+// by parquet-go with gzip compression in version 2 data pages
+const gzipOptionalParquet = "UEFSMRUGFUAVchXJjIfdBkwVCBUAFQgVABUAFQARAAAfiwgAAAluiAD/ACAA3/8AcD67dgEAAGBaP7t2AQAAwERAu3YBAAAg" +
+	"L0G7dgEAAAMABwJWtyAAAAAVBhVEFXYV+8iC+wFMFQgVABUIFQAVBBUAEQAACAEfiwgAAAluiAD/ACAA3/8AAAAAAAAUQAAA" +
+	"AAAAABZAAAAAAAAAGEAAAAAAAAAaQAMAy/9icyAAAAAVBhUsFV4VvMO2oQFMFQgVBBUIFQAVDBUAEQAAAgAEAQIAH4sIAAAJ" +
+	"bogA/wAQAO//AAAAAAAA8D8AAAAAAAAAQAMAX4mjexAAAAAZEgAZGAgAcD67dgEAABkYCCAvQbt2AQAAFQAZFgAAGRIAGRgI" +
+	"AAAAAAAAFEAZGAgAAAAAAAAaQBUAGRYAABkSABkYCAAAAAAAAPA/GRgIAAAAAAAAAEAVABkWBAAZHBYIFaoBFgAAABkcFrIB" +
+	"Fa4BFgAAABkcFuACFZYBFgAAABUEGUxIBk9wdFJvdxUGABUEFYABFQAYCXRpbWVzdGFtcCUkTKwTQBEAAAAVChWAARUCGAVj" +
+	"bG9zZQAVChWAARUCGAN2b2wAFggZHBk8JgAcFQQZFQAZGAl0aW1lc3RhbXAVBBYIFngWqgEmCDxYCCAvQbt2AQAAGAgAcD67" +
+	"dgEAAAAZHBUGFQAVAgAAFrAFFRYW9gMVPgAmABwVChklAAYZGAVjbG9zZRUEFggWfBauASayATxYCAAAAAAAABpAGAgAAAAA" +
+	"AAAUQAAZHBUGFQAVAgAAFsYFFRgWtAQVPgAmABwVChklAAYZGAN2b2wVBBYIFmQWlgEm4AI8NgQoCAAAAAAAAABAGAgAAAAA" +
+	"AADwPwAZHBUGFQAVAgAAFt4FFRgW8gQVPgAW2AIWCBkMFggW7gMAGQwYN2dpdGh1Yi5jb20vcGFycXVldC1nby9wYXJxdWV0" +
+	"LWdvIHZlcnNpb24gMC4yNS4xKGJ1aWxkICkZPBwAABwAABwAAAB7AQAAUEFSMQ=="
+
+func decodeFixture(t *testing.T, fixture string) []byte {
+	data, err := base64.StdEncoding.DecodeString(fixture)
+	assert.Nil(t, err)
+	return data
+}
+
+func TestReadTimeSeriesParquet(t *testing.T) {
+	t.Run("snappy and dictionary pages", func(t *testing.T) {
+		series, err := ReadTimeSeriesParquet(bytes.NewReader(decodeFixture(t, snappyDictionaryParquet)), ParquetOptions{})
+		assert.Nil(t, err)
+
+		assert.EqualValues(t, 5, len(series.Candles))
+		for i, candle := range series.Candles {
+			assert.EqualValues(t, time.Date(2021, 1, 4+i, 0, 0, 0, 0, time.UTC), candle.Period.Start)
+			assert.EqualValues(t, 24*time.Hour, candle.Period.Length())
+			decimalEquals(t, 10+float64(i%3), candle.OpenPrice)
+			decimalEquals(t, 13+float64(i%3), candle.MaxPrice)
+			decimalEquals(t, 9, candle.MinPrice)
+			decimalEquals(t, 11.5+float64(i%4)*0.25, candle.ClosePrice)
+			decimalEquals(t, float64(1000+i), candle.Volume)
+		}
+	})
+
+	t.Run("gzip version 2 pages with nulls", func(t *testing.T) {
+		series, err := ReadTimeSeriesParquet(bytes.NewReader(decodeFixture(t, gzipOptionalParquet)), ParquetOptions{
+			Volume: ColumnName("vol"),
+		})
+		assert.Nil(t, err)
+
+		assert.EqualValues(t, 4, len(series.Candles))
+		for i, candle := range series.Candles {
+			assert.EqualValues(t, time.Date(2021, 1, 1, 0, i, 0, 0, time.UTC), candle.Period.Start)
+			assert.EqualValues(t, time.Minute, candle.Period.Length())
+			decimalEquals(t, 5+float64(i)*0.5, candle.OpenPrice)
+			decimalEquals(t, 5+float64(i)*0.5, candle.ClosePrice)
+		}
+
+		indicatorEquals(t, []float64{0, 1, 2, 0}, NewVolumeIndicator(series))
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		_, err := ReadTimeSeriesParquet(bytes.NewReader(decodeFixture(t, gzipOptionalParquet)), ParquetOptions{
+			Close: ColumnName("price"),
+		})
+		assert.EqualError(t, err, "close column \"price\" not found in header")
+	})
+
+	t.Run("not a parquet file", func(t *testing.T) {
+		_, err := ReadTimeSeriesParquet(bytes.NewReader([]byte("time,close\n")), ParquetOptions{})
+		assert.EqualError(t, err, "not a parquet file")
+	})
+}
+
+func TestTimeSeriesParquet(t *testing.T) {
+	series := mockMinuteSeries(20)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteTimeSeriesParquet(&buffer, series))
+
+	decoded, err := ReadTimeSeriesParquet(&buffer, ParquetOptions{})
+	assert.Nil(t, err)
+	assertCandlesEqual(t, series.Candles, decoded.Candles)
+
+	filepath := "series.parquet"
+	assert.Nil(t, ExportTimeSeriesParquet(filepath, series))
+	defer os.Remove(filepath)
+
+	loaded, err := LoadTimeSeriesParquet(filepath, ParquetOptions{})
+	assert.Nil(t, err)
+	assertCandlesEqual(t, series.Candles, loaded.Candles)
+}
+
+func TestWriteIndicatorsParquet(t *testing.T) {
+	series := mockTimeSeriesFl(1, 2, 3, 4, 5)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteIndicatorsParquet(&buffer, series, map[string]Indicator{
+		"sma":   NewSimpleMovingAverage(NewClosePriceIndicator(series), 2),
+		"close": NewClosePriceIndicator(series),
+	}))
+
+	columns, err := readParquet(buffer.Bytes())
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, 3, len(columns))
+	assert.EqualValues(t, "time", columns[0].name)
+	assert.EqualValues(t, "close", columns[1].name)
+	assert.EqualValues(t, "sma", columns[2].name)
+	assert.EqualValues(t, []interface{}{0.0, 1.5, 2.5, 3.5, 4.5}, columns[2].values)
+
+	start, err := columns[0].time(2, time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, series.Candles[2].Period.Start.Equal(start))
+}
+
+func TestAccountHistoryParquet(t *testing.T) {
+	history := mockAccountHistory(100, 110, 105)
+	history.Snapshots[1].Positions = []*PositionSnapshot{
+		{Security: "SPY", Side: BUY, Amount: big.NewFromInt(10), Price: big.NewFromString("10.5"), UnrealizedGain: big.NewFromInt(5)},
+		{Security: "QQQ", Side: BUY, Amount: big.NewFromInt(2), Price: big.NewFromInt(3), UnrealizedGain: big.ZERO},
+	}
+
+	var snapshots bytes.Buffer
+	assert.Nil(t, history.WriteParquet(&snapshots))
+
+	columns, err := readParquet(snapshots.Bytes())
+	assert.Nil(t, err)
+	assert.EqualValues(t, "equity", columns[2].name)
+	assert.EqualValues(t, []interface{}{100.0, 110.0, 105.0}, columns[2].values)
+
+	var positions bytes.Buffer
+	assert.Nil(t, history.WritePositionsParquet(&positions))
+
+	columns, err = readParquet(positions.Bytes())
+	assert.Nil(t, err)
+	assert.EqualValues(t, "security", columns[2].name)
+	assert.EqualValues(t, []string{"SPY", "QQQ"}, []string{columns[2].text(0), columns[2].text(1)})
+	assert.EqualValues(t, []interface{}{10.5, 3.0}, columns[5].values)
+
+	start, err := columns[0].time(0, time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, history.Snapshots[1].Period.Start.Equal(start))
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	// the bit-packed example of the parquet specification followed by a run of five sevens
+	values, err := decodeHybrid([]byte{0x03, 0x88, 0xC6, 0xFA, 0x0A, 0x07}, 3, 13)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 7, 7, 7, 7, 7}, values)
+
+	_, err = decodeHybrid([]byte{0x03, 0x88}, 3, 8)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// a literal "ab" followed by a copy of six bytes at an offset of two
+	decoded, err := decodeSnappy([]byte{0x08, 0x04, 'a', 'b', 0x09, 0x02})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "abababab", string(decoded))
+
+	_, err = decodeSnappy([]byte{0x08, 0x04, 'a', 'b', 0x09, 0x03})
+	assert.NotNil(t, err)
+
+	// a declared size far beyond what the data can expand to
+	_, err = decodeSnappy([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x04, 'a', 'b'})
+	assert.NotNil(t, err)
+}
+
+func TestReadTimeSeriesParquet_Corrupted(t *testing.T) {
+	var written bytes.Buffer
+	assert.Nil(t, WriteTimeSeriesParquet(&written, mockTimeSeriesFl(1, 2, 3, 4, 5)))
+
+	for name, data := range map[string][]byte{
+		"snappy":  decodeFixture(t, snappyDictionaryParquet),
+		"gzip":    decodeFixture(t, gzipOptionalParquet),
+		"written": written.Bytes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// damaged files must be rejected with an error, or read if the damage is harmless, but never panic
+			read := func(corrupted []byte, description string) {
+				assert.NotPanics(t, func() {
+					ReadTimeSeriesParquet(bytes.NewReader(corrupted), ParquetOptions{})
+				}, description)
+			}
+
+			for i := range data {
+				for _, value := range []byte{0x00, 0x01, 0x7f, 0x80, 0xff, data[i] ^ 0x01, data[i] ^ 0x40} {
+					corrupted := append([]byte(nil), data...)
+					corrupted[i] = value
+					read(corrupted, fmt.Sprintf("byte %v set to %#x", i, value))
+				}
+
+				read(append(append([]byte(nil), data[:i]...), data[len(data)-8:]...), fmt.Sprintf("truncated at %v", i))
+			}
+		})
+	}
+}
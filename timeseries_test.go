@@ -84,3 +84,92 @@ func TestTimeSeries_LastIndex(t *testing.T) {
 
 	assert.EqualValues(t, 1, ts.LastIndex())
 }
+
+func TestTimeSeries_Bounded(t *testing.T) {
+	assert.Panics(t, func() {
+		NewBoundedTimeSeries(0)
+	})
+
+	ts := NewBoundedTimeSeries(3)
+	for i := 0; i < 5; i++ {
+		candle := NewCandle(NewTimePeriod(time.Unix(int64(i), 0), time.Second))
+		candle.ClosePrice = big.NewFromInt(i)
+		ts.AddCandle(candle)
+	}
+
+	assert.Equal(t, 3, ts.Capacity())
+	assert.Len(t, ts.Candles, 3)
+	assert.Equal(t, 2, ts.FirstIndex())
+	assert.Equal(t, 4, ts.LastIndex())
+	assert.False(t, ts.HasIndex(1))
+	assert.True(t, ts.HasIndex(2))
+	assert.False(t, ts.HasIndex(5))
+	assert.EqualValues(t, 3, ts.Candle(3).ClosePrice.Float())
+
+	closePrice := NewClosePriceIndicator(ts)
+	assert.EqualValues(t, 4, closePrice.Calculate(4).Float())
+	assert.PanicsWithValue(t, EvictedIndexError{Index: 1, FirstIndex: 2}, func() {
+		closePrice.Calculate(1)
+	})
+	assert.EqualError(t, EvictedIndexError{Index: 1, FirstIndex: 2},
+		"index 1 has been evicted from the timeseries, the first retained index is 2")
+}
+
+func TestTimeSeries_BoundedComposedIndicators(t *testing.T) {
+	full := randomTimeSeries(100)
+	bounded := NewBoundedTimeSeries(20)
+	for _, candle := range full.Candles {
+		bounded.AddCandle(candle)
+	}
+
+	constructors := map[string]func(*TimeSeries) Indicator{
+		"bollinger": func(s *TimeSeries) Indicator {
+			return NewBollingerUpperBandIndicator(NewClosePriceIndicator(s), 5, 2)
+		},
+		"stochastic": func(s *TimeSeries) Indicator {
+			return NewSlowStochasticIndicator(NewFastStochasticIndicator(s, 5), 3)
+		},
+		"sma of atr": func(s *TimeSeries) Indicator {
+			return NewSimpleMovingAverage(NewAverageTrueRangeIndicator(s, 5), 3)
+		},
+	}
+
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			expected := constructor(full)
+			indicator := constructor(bounded)
+
+			// values whose windows only reach retained candles match the unbounded series
+			for i := bounded.FirstIndex() + Lookback(indicator); i <= bounded.LastIndex(); i++ {
+				assert.EqualValues(t, expected.Calculate(i).String(), indicator.Calculate(i).String(), "index %v", i)
+			}
+
+			assert.Panics(t, func() {
+				indicator.Calculate(bounded.FirstIndex() - 1)
+			})
+		})
+	}
+}
+
+func TestTimeSeries_UpdateLastCandle(t *testing.T) {
+	ts := NewTimeSeries()
+	assert.False(t, ts.UpdateLastCandle(NewCandle(NewTimePeriod(time.Unix(0, 0), time.Second))))
+	assert.Panics(t, func() {
+		ts.UpdateLastCandle(nil)
+	})
+
+	ts.AddCandle(NewCandle(NewTimePeriod(time.Unix(0, 0), time.Second)))
+	ts.AddCandle(NewCandle(NewTimePeriod(time.Unix(1, 0), time.Second)))
+	assert.Equal(t, 2, ts.Revision())
+
+	updated := NewCandle(NewTimePeriod(time.Unix(1, 0), time.Second))
+	updated.ClosePrice = big.NewFromInt(5)
+
+	assert.True(t, ts.UpdateLastCandle(updated))
+	assert.Equal(t, 3, ts.Revision())
+	assert.Len(t, ts.Candles, 2)
+	assert.EqualValues(t, 5, ts.LastCandle().ClosePrice.Float())
+
+	assert.False(t, ts.UpdateLastCandle(NewCandle(NewTimePeriod(time.Unix(2, 0), time.Second))))
+	assert.Equal(t, 3, ts.Revision())
+}
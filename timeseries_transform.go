@@ -0,0 +1,175 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/schmidthole/big"
+)
+
+// HeikinAshiSeries returns the Heikin-Ashi candles of a series. The close is the average of the
+// open, high, low and close, the open is the midpoint of the previous Heikin-Ashi candle, and the
+// high and low extend to include both. Periods, volumes and trade counts are kept, so indicators
+// built on the result line up with the original series.
+// https://www.investopedia.com/trading/heikin-ashi-better-candlestick/
+func HeikinAshiSeries(series *TimeSeries) *TimeSeries {
+	transformed := NewTimeSeries()
+	var previous *Candle
+
+	for _, candle := range series.Candles {
+		close := candle.OpenPrice.Add(candle.MaxPrice).Add(candle.MinPrice).Add(candle.ClosePrice).Div(big.NewFromInt(4))
+
+		var open big.Decimal
+		if previous == nil {
+			open = candle.OpenPrice.Add(candle.ClosePrice).Div(big.NewFromInt(2))
+		} else {
+			open = previous.OpenPrice.Add(previous.ClosePrice).Div(big.NewFromInt(2))
+		}
+
+		previous = &Candle{
+			Period:     candle.Period,
+			OpenPrice:  open,
+			ClosePrice: close,
+			MaxPrice:   big.MaxSlice(candle.MaxPrice, open, close),
+			MinPrice:   big.MinSlice(candle.MinPrice, open, close),
+			Volume:     candle.Volume,
+			TradeCount: candle.TradeCount,
+		}
+
+		transformed.AddCandle(previous)
+	}
+
+	return transformed
+}
+
+// LogPriceSeries returns a series of the natural logarithm of every price of a series, keeping
+// periods, volumes and trade counts. An error is returned if a price is not positive.
+func LogPriceSeries(series *TimeSeries) (*TimeSeries, error) {
+	return mapPrices(series, func(_ int, price big.Decimal) (big.Decimal, error) {
+		if !price.GT(big.ZERO) {
+			return big.ZERO, fmt.Errorf("cannot take the logarithm of price %v", price)
+		}
+
+		return big.NewDecimal(math.Log(price.Float())), nil
+	})
+}
+
+// PercentReturnSeries returns a series of the percent change of every price of a series from the
+// previous close. The first candle has no previous close and is measured against its own open, so
+// the result has the same indexes as the original series. An error is returned if a reference
+// price is zero.
+func PercentReturnSeries(series *TimeSeries) (*TimeSeries, error) {
+	return mapPrices(series, func(i int, price big.Decimal) (big.Decimal, error) {
+		reference := series.Candles[0].OpenPrice
+		if i > 0 {
+			reference = series.Candles[i-1].ClosePrice
+		}
+
+		if reference.IsZero() {
+			return big.ZERO, fmt.Errorf("cannot calculate a return from a price of zero")
+		}
+
+		return price.Div(reference).Sub(big.ONE).Mul(big.NewFromInt(100)), nil
+	})
+}
+
+// SpreadSeries returns the difference of the prices of two securities for every period both series
+// have a candle starting at. The open and close are exact, while the high and low are bounds on the
+// spread, the first high less the second low and the first low less the second high, since the
+// candles do not tell when each extreme was reached. Volumes and trade counts are zero.
+func SpreadSeries(first, second *TimeSeries) (*TimeSeries, error) {
+	return combineSeries(first, second, func(a, b *Candle) (*Candle, error) {
+		return &Candle{
+			Period:     a.Period,
+			OpenPrice:  a.OpenPrice.Sub(b.OpenPrice),
+			ClosePrice: a.ClosePrice.Sub(b.ClosePrice),
+			MaxPrice:   a.MaxPrice.Sub(b.MinPrice),
+			MinPrice:   a.MinPrice.Sub(b.MaxPrice),
+			Volume:     big.ZERO,
+		}, nil
+	})
+}
+
+// RatioSeries returns the ratio of the prices of two securities for every period both series have a
+// candle starting at. The open and close are exact, while the high and low are bounds on the ratio,
+// the first high over the second low and the first low over the second high. Volumes and trade
+// counts are zero. An error is returned if a price of the second series is not positive.
+func RatioSeries(first, second *TimeSeries) (*TimeSeries, error) {
+	return combineSeries(first, second, func(a, b *Candle) (*Candle, error) {
+		if !b.MinPrice.GT(big.ZERO) {
+			return nil, fmt.Errorf("cannot divide by price %v at %v", b.MinPrice, b.Period)
+		}
+
+		return &Candle{
+			Period:     a.Period,
+			OpenPrice:  a.OpenPrice.Div(b.OpenPrice),
+			ClosePrice: a.ClosePrice.Div(b.ClosePrice),
+			MaxPrice:   a.MaxPrice.Div(b.MinPrice),
+			MinPrice:   a.MinPrice.Div(b.MaxPrice),
+			Volume:     big.ZERO,
+		}, nil
+	})
+}
+
+func mapPrices(series *TimeSeries, transform func(i int, price big.Decimal) (big.Decimal, error)) (*TimeSeries, error) {
+	transformed := NewTimeSeries()
+
+	for i, candle := range series.Candles {
+		mapped := &Candle{
+			Period:     candle.Period,
+			Volume:     candle.Volume,
+			TradeCount: candle.TradeCount,
+		}
+
+		for _, price := range []struct {
+			from big.Decimal
+			to   *big.Decimal
+		}{
+			{candle.OpenPrice, &mapped.OpenPrice},
+			{candle.MaxPrice, &mapped.MaxPrice},
+			{candle.MinPrice, &mapped.MinPrice},
+			{candle.ClosePrice, &mapped.ClosePrice},
+		} {
+			value, err := transform(i, price.from)
+			if err != nil {
+				return nil, fmt.Errorf("candle %v: %v", candle.Period, err)
+			}
+
+			*price.to = value
+		}
+
+		transformed.AddCandle(mapped)
+	}
+
+	return transformed, nil
+}
+
+func combineSeries(first, second *TimeSeries, combine func(a, b *Candle) (*Candle, error)) (*TimeSeries, error) {
+	transformed := NewTimeSeries()
+	j := 0
+
+	for _, a := range first.Candles {
+		for j < len(second.Candles) && second.Candles[j].Period.Start.Before(a.Period.Start) {
+			j++
+		}
+
+		if j == len(second.Candles) {
+			break
+		}
+
+		if b := second.Candles[j]; b.Period.Start.Equal(a.Period.Start) {
+			combined, err := combine(a, b)
+			if err != nil {
+				return nil, err
+			}
+
+			transformed.AddCandle(combined)
+		}
+	}
+
+	if len(transformed.Candles) == 0 {
+		return nil, fmt.Errorf("the series have no periods in common")
+	}
+
+	return transformed, nil
+}
@@ -0,0 +1,110 @@
+package techan
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeikinAshiSeries(t *testing.T) {
+	series := mockTimeSeriesOCHL(
+		[]float64{10, 12, 13, 9},
+		[]float64{12, 11, 14, 10},
+	)
+
+	ha := HeikinAshiSeries(series)
+	assert.Equal(t, 2, len(ha.Candles))
+
+	first := ha.Candles[0]
+	assert.Equal(t, series.Candles[0].Period, first.Period)
+	decimalEquals(t, 11, first.OpenPrice)
+	decimalEquals(t, 11, first.ClosePrice)
+	decimalEquals(t, 13, first.MaxPrice)
+	decimalEquals(t, 9, first.MinPrice)
+
+	second := ha.Candles[1]
+	decimalEquals(t, 11, second.OpenPrice)
+	decimalEquals(t, 11.75, second.ClosePrice)
+	decimalEquals(t, 14, second.MaxPrice)
+	decimalEquals(t, 10, second.MinPrice)
+	decimalEquals(t, 1, second.Volume)
+
+	// indicators run on the transformed series as on any other
+	decimalEquals(t, 11.75, NewClosePriceIndicator(ha).Calculate(1))
+}
+
+func TestLogPriceSeries(t *testing.T) {
+	series := mockTimeSeriesOCHL([]float64{1, math.E, math.E * math.E, 1})
+
+	logs, err := LogPriceSeries(series)
+	assert.Nil(t, err)
+	decimalEquals(t, 0, logs.Candles[0].OpenPrice)
+	decimalEquals(t, 1, logs.Candles[0].ClosePrice)
+	decimalEquals(t, 2, logs.Candles[0].MaxPrice)
+	decimalEquals(t, 0, logs.Candles[0].MinPrice)
+
+	_, err = LogPriceSeries(mockTimeSeriesOCHL([]float64{1, 1, 1, 0}))
+	assert.EqualError(t, err, "candle 1970-01-01T00:00:00 -> 1970-01-01T00:00:01: cannot take the logarithm of price 0")
+}
+
+func TestPercentReturnSeries(t *testing.T) {
+	series := mockTimeSeriesOCHL(
+		[]float64{10, 11, 12, 9},
+		[]float64{11, 12.1, 13.2, 9.9},
+	)
+
+	returns, err := PercentReturnSeries(series)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(returns.Candles))
+	decimalEquals(t, 0, returns.Candles[0].OpenPrice)
+	decimalEquals(t, 10, returns.Candles[0].ClosePrice)
+	decimalEquals(t, 20, returns.Candles[0].MaxPrice)
+	decimalEquals(t, 0, returns.Candles[1].OpenPrice)
+	decimalEquals(t, 10, returns.Candles[1].ClosePrice)
+	decimalEquals(t, -10, returns.Candles[1].MinPrice)
+}
+
+func TestSpreadAndRatioSeries(t *testing.T) {
+	first := mockTimeSeriesOCHL(
+		[]float64{10, 11, 12, 9},
+		[]float64{11, 12, 13, 10},
+		[]float64{12, 14, 15, 11},
+	)
+
+	// the second series is missing the middle period
+	second := mockTimeSeriesOCHL(
+		[]float64{5, 5.5, 6, 4},
+		[]float64{6, 7, 8, 5},
+	)
+	second.Candles[1].Period = first.Candles[2].Period
+
+	spread, err := SpreadSeries(first, second)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(spread.Candles))
+	assert.Equal(t, first.Candles[2].Period, spread.Candles[1].Period)
+	decimalEquals(t, 5, spread.Candles[0].OpenPrice)
+	decimalEquals(t, 5.5, spread.Candles[0].ClosePrice)
+	decimalEquals(t, 8, spread.Candles[0].MaxPrice)
+	decimalEquals(t, 3, spread.Candles[0].MinPrice)
+	decimalEquals(t, 7, spread.Candles[1].ClosePrice)
+
+	ratio, err := RatioSeries(first, second)
+	assert.Nil(t, err)
+	decimalEquals(t, 2, ratio.Candles[0].OpenPrice)
+	decimalEquals(t, 3, ratio.Candles[0].MaxPrice)
+	decimalEquals(t, 1.5, ratio.Candles[0].MinPrice)
+	decimalEquals(t, 2, ratio.Candles[1].ClosePrice)
+
+	second.Candles[0].MinPrice = second.Candles[0].MinPrice.Sub(second.Candles[0].MinPrice)
+	_, err = RatioSeries(first, second)
+	assert.NotNil(t, err)
+
+	shifted := mockTimeSeriesOCHL([]float64{1, 1, 1, 1})
+	shifted.Candles[0].Period = shifted.Candles[0].Period.Advance(10)
+	_, err = SpreadSeries(first, shifted)
+	assert.EqualError(t, err, "the series have no periods in common")
+
+	assert.Equal(t, time.Second, spread.Candles[0].Period.Length())
+}
@@ -0,0 +1,356 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/schmidthole/big"
+)
+
+// DataIssue is the kind of a problem found in a TimeSeries by ValidateTimeSeries
+type DataIssue string
+
+// DataIssue enumerations
+const (
+	HIGH_BELOW_LOW      DataIssue = "high_below_low"
+	PRICE_OUTSIDE_RANGE DataIssue = "price_outside_range"
+	NON_POSITIVE_PRICE  DataIssue = "non_positive_price"
+	DUPLICATE_PERIOD    DataIssue = "duplicate_period"
+	OUT_OF_ORDER        DataIssue = "out_of_order"
+	GAP                 DataIssue = "gap"
+	OUTLIER_RETURN      DataIssue = "outlier_return"
+	ZERO_VOLUME_RUN     DataIssue = "zero_volume_run"
+)
+
+// A ValidationIssue is a problem found at an absolute index of a TimeSeries
+type ValidationIssue struct {
+	Index   int
+	Kind    DataIssue
+	Message string
+}
+
+func (vi ValidationIssue) String() string {
+	return fmt.Sprintf("index %v: %v", vi.Index, vi.Message)
+}
+
+// ValidationOptions configures the checks of ValidateTimeSeries which depend on the data. Gaps are
+// reported when more than MaxGap candles, measured by the length of the previous candle, are missing
+// between two candles; the default of zero reports every gap, so daily data without weekends needs a
+// MaxGap of 2. Close to close returns further than OutlierSigma standard deviations from the mean
+// are reported as outliers, and runs of at least ZeroVolumeRun candles without volume are reported.
+// Both checks are disabled when zero.
+type ValidationOptions struct {
+	MaxGap        int
+	OutlierSigma  float64
+	ZeroVolumeRun int
+}
+
+// A ValidationReport lists the issues found in a TimeSeries, in index order.
+type ValidationReport struct {
+	Issues []ValidationIssue
+}
+
+// Valid returns true if no issues were found
+func (vr *ValidationReport) Valid() bool {
+	return len(vr.Issues) == 0
+}
+
+// IssuesAt returns the kinds of the issues found at an absolute index
+func (vr *ValidationReport) IssuesAt(index int) []DataIssue {
+	kinds := make([]DataIssue, 0)
+	for _, issue := range vr.Issues {
+		if issue.Index == index {
+			kinds = append(kinds, issue.Kind)
+		}
+	}
+
+	return kinds
+}
+
+func (vr *ValidationReport) String() string {
+	lines := make([]string, len(vr.Issues))
+	for i, issue := range vr.Issues {
+		lines[i] = issue.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ValidateTimeSeries checks every candle of a series for inconsistent or suspicious data. A high
+// below the low, an open or close outside of the high/low range, zero or negative prices, periods
+// starting at or before the previous candle's start, and the optional gap, outlier and zero volume
+// checks are reported with the absolute index of the candle.
+func ValidateTimeSeries(series *TimeSeries, options ValidationOptions) *ValidationReport {
+	report := &ValidationReport{Issues: make([]ValidationIssue, 0)}
+	first := series.FirstIndex()
+
+	add := func(i int, kind DataIssue, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, ValidationIssue{
+			Index:   first + i,
+			Kind:    kind,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	returns, outliers, _, _ := outlierReturns(series, options.OutlierSigma)
+	zeroRun := 0
+
+	for i, candle := range series.Candles {
+		if candle.MaxPrice.LT(candle.MinPrice) {
+			add(i, HIGH_BELOW_LOW, "high %v is below low %v", candle.MaxPrice, candle.MinPrice)
+		} else {
+			for _, price := range []big.Decimal{candle.OpenPrice, candle.ClosePrice} {
+				if price.GT(candle.MaxPrice) || price.LT(candle.MinPrice) {
+					add(i, PRICE_OUTSIDE_RANGE, "price %v is outside of the high/low range", price)
+					break
+				}
+			}
+		}
+
+		for _, price := range []big.Decimal{candle.OpenPrice, candle.MaxPrice, candle.MinPrice, candle.ClosePrice} {
+			if !price.GT(big.ZERO) {
+				add(i, NON_POSITIVE_PRICE, "price %v is not positive", price)
+				break
+			}
+		}
+
+		if i > 0 {
+			previous := series.Candles[i-1].Period
+
+			if candle.Period.Start.Equal(previous.Start) {
+				add(i, DUPLICATE_PERIOD, "period %v duplicates the previous candle", candle.Period)
+			} else if candle.Period.Start.Before(previous.Start) {
+				add(i, OUT_OF_ORDER, "period %v starts before the previous candle", candle.Period)
+			} else if missing := missingCandles(previous, candle.Period); missing > options.MaxGap {
+				add(i, GAP, "%v candles are missing before %v", missing, candle.Period)
+			}
+		}
+
+		if outliers[i] {
+			add(i, OUTLIER_RETURN, "return of %.4f%% is more than %v standard deviations from the mean", returns[i]*100, options.OutlierSigma)
+		}
+
+		if candle.Volume.IsZero() {
+			zeroRun++
+		} else {
+			zeroRun = 0
+		}
+
+		if options.ZeroVolumeRun > 0 && zeroRun >= options.ZeroVolumeRun {
+			// the whole run is reported once it is long enough
+			start := i
+			if zeroRun == options.ZeroVolumeRun {
+				start = i - zeroRun + 1
+			}
+
+			for j := start; j <= i; j++ {
+				add(j, ZERO_VOLUME_RUN, "no volume for at least %v candles", options.ZeroVolumeRun)
+			}
+		}
+	}
+
+	sortIssues(report.Issues)
+
+	return report
+}
+
+// CleaningPolicy defines how CleanTimeSeries repairs the candles reported by ValidateTimeSeries
+type CleaningPolicy int
+
+// CleaningPolicy enumerations. CLEAN_DROP removes every candle with an issue other than a gap. CLEAN_CLAMP widens the
+// high/low range to include the open and close and clamps outliers to the largest accepted return,
+// dropping candles with non-positive prices. CLEAN_INTERPOLATE and CLEAN_FORWARD_FILL replace bad
+// candles with flat candles, at a price interpolated between the neighbouring good closes or at the
+// previous good close respectively, and fill gaps of more than MaxGap candles the same way. Duplicate and out of order candles
+// are always dropped, and zero volume runs are only removed by CLEAN_DROP.
+const (
+	CLEAN_DROP CleaningPolicy = iota
+	CLEAN_CLAMP
+	CLEAN_INTERPOLATE
+	CLEAN_FORWARD_FILL
+)
+
+// CleanTimeSeries validates a series and returns a cleaned copy along with the report of the issues
+// found in the original. The original series is not modified.
+func CleanTimeSeries(series *TimeSeries, options ValidationOptions, policy CleaningPolicy) (*TimeSeries, *ValidationReport) {
+	report := ValidateTimeSeries(series, options)
+	first := series.FirstIndex()
+
+	type cleanedCandle struct {
+		candle *Candle
+		fill   bool
+	}
+
+	cleaned := make([]cleanedCandle, 0, len(series.Candles))
+	_, _, mean, deviation := outlierReturns(series, options.OutlierSigma)
+
+	for i, original := range series.Candles {
+		issues := report.IssuesAt(first + i)
+		if hasIssue(issues, DUPLICATE_PERIOD, OUT_OF_ORDER) {
+			continue
+		}
+
+		// a gap is an issue between candles rather than with the candle after it
+		if policy == CLEAN_DROP && hasIssue(issues, HIGH_BELOW_LOW, PRICE_OUTSIDE_RANGE, NON_POSITIVE_PRICE, OUTLIER_RETURN, ZERO_VOLUME_RUN) {
+			continue
+		}
+
+		candle := *original
+
+		if (policy == CLEAN_INTERPOLATE || policy == CLEAN_FORWARD_FILL) && len(cleaned) > 0 {
+			previous := cleaned[len(cleaned)-1].candle.Period
+			if missing := missingCandles(previous, candle.Period); missing > options.MaxGap {
+				for k := 1; k <= missing; k++ {
+					cleaned = append(cleaned, cleanedCandle{candle: NewCandle(previous.Advance(k)), fill: true})
+				}
+			}
+		}
+
+		if !hasIssue(issues, HIGH_BELOW_LOW, PRICE_OUTSIDE_RANGE, NON_POSITIVE_PRICE, OUTLIER_RETURN) {
+			cleaned = append(cleaned, cleanedCandle{candle: &candle})
+			continue
+		}
+
+		if policy != CLEAN_CLAMP {
+			cleaned = append(cleaned, cleanedCandle{candle: &candle, fill: true})
+			continue
+		}
+
+		if !candle.ClosePrice.GT(big.ZERO) {
+			continue
+		}
+
+		prices := []*big.Decimal{&candle.OpenPrice, &candle.MaxPrice, &candle.MinPrice, &candle.ClosePrice}
+		for _, price := range prices {
+			if !price.GT(big.ZERO) {
+				*price = candle.ClosePrice
+			}
+		}
+
+		if hasIssue(issues, OUTLIER_RETURN) && len(cleaned) > 0 {
+			previousClose := cleaned[len(cleaned)-1].candle.ClosePrice.Float()
+			low := big.NewDecimal(previousClose * (1 + mean - options.OutlierSigma*deviation))
+			high := big.NewDecimal(previousClose * (1 + mean + options.OutlierSigma*deviation))
+
+			for _, price := range prices {
+				*price = big.MinSlice(big.MaxSlice(*price, low), high)
+			}
+		}
+
+		candle.MaxPrice = big.MaxSlice(candle.OpenPrice, candle.MaxPrice, candle.MinPrice, candle.ClosePrice)
+		candle.MinPrice = big.MinSlice(candle.OpenPrice, candle.MaxPrice, candle.MinPrice, candle.ClosePrice)
+		cleaned = append(cleaned, cleanedCandle{candle: &candle})
+	}
+
+	result := NewTimeSeries()
+
+	for i, entry := range cleaned {
+		if !entry.fill {
+			result.AddCandle(entry.candle)
+			continue
+		}
+
+		previous, next := -1, -1
+		for j := i - 1; j >= 0 && previous < 0; j-- {
+			if !cleaned[j].fill {
+				previous = j
+			}
+		}
+
+		for j := i + 1; j < len(cleaned) && next < 0; j++ {
+			if !cleaned[j].fill {
+				next = j
+			}
+		}
+
+		var price big.Decimal
+		switch {
+		case previous < 0 && next < 0:
+			continue
+		case previous < 0:
+			price = cleaned[next].candle.ClosePrice
+		case next < 0 || policy == CLEAN_FORWARD_FILL:
+			price = cleaned[previous].candle.ClosePrice
+		default:
+			from := cleaned[previous].candle.ClosePrice
+			to := cleaned[next].candle.ClosePrice
+			fraction := big.NewFromInt(i - previous).Div(big.NewFromInt(next - previous))
+			price = from.Add(to.Sub(from).Mul(fraction))
+		}
+
+		filled := flatCandle(entry.candle.Period, price)
+		filled.Volume = entry.candle.Volume
+		filled.TradeCount = entry.candle.TradeCount
+
+		result.AddCandle(filled)
+	}
+
+	return result, report
+}
+
+// outlierReturns returns the close to close return of every candle, zero for the first candle or
+// after a non-positive close, whether it is an outlier, and the mean and standard deviation of the
+// returns. A spike which reverts on the next candle is only reported at the spike.
+func outlierReturns(series *TimeSeries, sigma float64) (returns []float64, outliers []bool, average, deviation float64) {
+	returns = make([]float64, len(series.Candles))
+	outliers = make([]bool, len(series.Candles))
+	valid := make([]float64, 0, len(series.Candles))
+
+	for i := 1; i < len(series.Candles); i++ {
+		previous := series.Candles[i-1].ClosePrice
+		if previous.GT(big.ZERO) {
+			returns[i] = series.Candles[i].ClosePrice.Div(previous).Float() - 1
+			valid = append(valid, returns[i])
+		}
+	}
+
+	average, deviation = mean(valid), stdev(valid)
+	if sigma <= 0 || len(valid) < 2 {
+		return returns, outliers, average, deviation
+	}
+
+	for i := 1; i < len(returns); i++ {
+		if math.Abs(returns[i]-average) <= sigma*deviation {
+			continue
+		}
+
+		if outliers[i-1] && (returns[i] > average) != (returns[i-1] > average) {
+			continue
+		}
+
+		outliers[i] = true
+	}
+
+	return returns, outliers, average, deviation
+}
+
+// missingCandles returns the number of candles of the previous period's length which fit between
+// the end of the previous period and the start of the next
+func missingCandles(previous, next TimePeriod) int {
+	length := previous.Length()
+	if length <= 0 || !next.Start.After(previous.End) {
+		return 0
+	}
+
+	return int(next.Start.Sub(previous.End) / length)
+}
+
+func hasIssue(issues []DataIssue, kinds ...DataIssue) bool {
+	for _, issue := range issues {
+		for _, kind := range kinds {
+			if issue == kind {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sortIssues(issues []ValidationIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Index < issues[j].Index
+	})
+}
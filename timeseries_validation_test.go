@@ -0,0 +1,109 @@
+package techan
+
+import (
+	"testing"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockDirtySeries() *TimeSeries {
+	series := mockGappedSeries([]int{0, 1, 2, 3, 4, 6, 7, 8, 9}, 10, 10.1, 10.2, 10.1, 10.2, 10.3, 10.2, 10.1, 10.2)
+
+	// high below low
+	series.Candles[1].MaxPrice = big.NewDecimal(9)
+
+	// a bad tick which reverts on the next candle
+	series.Candles[3].ClosePrice = big.NewDecimal(20)
+	series.Candles[3].MaxPrice = big.NewDecimal(21)
+
+	// no volume
+	series.Candles[6].Volume = big.ZERO
+	series.Candles[7].Volume = big.ZERO
+
+	// a duplicate of the previous candle
+	series.Candles[8].Period = series.Candles[7].Period
+
+	return series
+}
+
+func TestValidateTimeSeries(t *testing.T) {
+	report := ValidateTimeSeries(mockDirtySeries(), ValidationOptions{OutlierSigma: 2, ZeroVolumeRun: 2})
+	assert.False(t, report.Valid())
+
+	assert.Equal(t, []DataIssue{HIGH_BELOW_LOW}, report.IssuesAt(1))
+	assert.Equal(t, []DataIssue{OUTLIER_RETURN}, report.IssuesAt(3))
+	assert.Equal(t, []DataIssue{}, report.IssuesAt(4))
+	assert.Equal(t, []DataIssue{GAP}, report.IssuesAt(5))
+	assert.Equal(t, []DataIssue{ZERO_VOLUME_RUN}, report.IssuesAt(6))
+	assert.Equal(t, []DataIssue{DUPLICATE_PERIOD}, report.IssuesAt(8))
+
+	assert.Equal(t, "index 1: high 9 is below low 9.1", report.Issues[0].String())
+	assert.Equal(t, "index 5: 1 candles are missing before 1970-01-01T00:00:06 -> 1970-01-01T00:00:07", report.Issues[2].String())
+
+	report = ValidateTimeSeries(mockDirtySeries(), ValidationOptions{MaxGap: 1})
+	assert.Equal(t, []DataIssue{}, report.IssuesAt(5))
+	assert.Equal(t, []DataIssue{}, report.IssuesAt(3))
+}
+
+func TestCleanTimeSeries(t *testing.T) {
+	options := ValidationOptions{OutlierSigma: 2, ZeroVolumeRun: 2}
+
+	t.Run("drop", func(t *testing.T) {
+		cleaned, report := CleanTimeSeries(mockDirtySeries(), options, CLEAN_DROP)
+		assert.Equal(t, 6, len(report.Issues))
+		assert.Equal(t, 4, len(cleaned.Candles))
+		decimalEquals(t, 10.2, cleaned.Candles[1].ClosePrice)
+		decimalEquals(t, 10.3, cleaned.Candles[3].ClosePrice)
+	})
+
+	t.Run("clamp", func(t *testing.T) {
+		cleaned, _ := CleanTimeSeries(mockDirtySeries(), options, CLEAN_CLAMP)
+		assert.Equal(t, 8, len(cleaned.Candles))
+		decimalEquals(t, 10.1, cleaned.Candles[1].MaxPrice)
+		decimalEquals(t, 9.1, cleaned.Candles[1].MinPrice)
+		assert.True(t, cleaned.Candles[3].ClosePrice.LT(big.NewDecimal(20)))
+		assert.True(t, cleaned.Candles[3].MaxPrice.GTE(cleaned.Candles[3].ClosePrice))
+	})
+
+	t.Run("interpolate", func(t *testing.T) {
+		cleaned, _ := CleanTimeSeries(mockDirtySeries(), options, CLEAN_INTERPOLATE)
+		assert.Equal(t, 9, len(cleaned.Candles))
+		decimalEquals(t, 10.1, cleaned.Candles[1].ClosePrice)
+		decimalEquals(t, 10.1, cleaned.Candles[1].MaxPrice)
+		decimalEquals(t, 10.1, cleaned.Candles[1].Volume)
+		decimalEquals(t, 10.2, cleaned.Candles[3].ClosePrice)
+		decimalEquals(t, 10.25, cleaned.Candles[5].ClosePrice)
+		decimalEquals(t, 0, cleaned.Candles[5].Volume)
+		assert.Equal(t, cleaned.Candles[4].Period.Advance(1), cleaned.Candles[5].Period)
+	})
+
+	t.Run("forward fill", func(t *testing.T) {
+		cleaned, _ := CleanTimeSeries(mockDirtySeries(), options, CLEAN_FORWARD_FILL)
+		assert.Equal(t, 9, len(cleaned.Candles))
+		decimalEquals(t, 10, cleaned.Candles[1].ClosePrice)
+		decimalEquals(t, 10.2, cleaned.Candles[3].ClosePrice)
+		decimalEquals(t, 10.2, cleaned.Candles[5].ClosePrice)
+	})
+
+	t.Run("max gap", func(t *testing.T) {
+		// weekdays, with the weekends and a three day gap missing
+		series := mockGappedSeries([]int{0, 1, 2, 3, 4, 7, 8, 12, 15}, 10, 10, 10, 10, 10, 11, 11, 15, 15)
+
+		for _, policy := range []CleaningPolicy{CLEAN_INTERPOLATE, CLEAN_FORWARD_FILL} {
+			cleaned, report := CleanTimeSeries(series, ValidationOptions{MaxGap: 2}, policy)
+			assert.Equal(t, []DataIssue{GAP}, report.IssuesAt(7))
+			assert.Equal(t, 12, len(cleaned.Candles))
+			assert.Equal(t, series.Candles[6].Period.Advance(1), cleaned.Candles[7].Period)
+			assert.Equal(t, series.Candles[7].Period, cleaned.Candles[10].Period)
+			assert.Equal(t, series.Candles[8].Period, cleaned.Candles[11].Period)
+		}
+	})
+
+	t.Run("clean series", func(t *testing.T) {
+		series := mockTimeSeriesFl(2, 3, 4)
+		cleaned, report := CleanTimeSeries(series, ValidationOptions{}, CLEAN_DROP)
+		assert.True(t, report.Valid())
+		assert.Equal(t, series.Candles, cleaned.Candles)
+	})
+}
@@ -0,0 +1,331 @@
+package techan
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/schmidthole/big"
+	"gopkg.in/yaml.v3"
+)
+
+// A RoundTrip is a complete trade in a single security, from the fill that opens a position to the
+// fill that brings it back to zero. Scale-ins and partial exits are folded into the same round trip
+// so that the profit of the whole trade can be measured. Round trips which are still open at the
+// end of the trade record only report their realized values.
+type RoundTrip struct {
+	Security      string        `yaml:"security"`
+	Entries       []*Order      `yaml:"-"`
+	Exits         []*Order      `yaml:"-"`
+	EntryTime     time.Time     `yaml:"entry_time"`
+	ExitTime      time.Time     `yaml:"exit_time"`
+	Closed        bool          `yaml:"closed"`
+	Quantity      big.Decimal   `yaml:"quantity"`
+	AvgEntryPrice big.Decimal   `yaml:"avg_entry_price"`
+	AvgExitPrice  big.Decimal   `yaml:"avg_exit_price"`
+	CostBasis     big.Decimal   `yaml:"cost_basis"`
+	Proceeds      big.Decimal   `yaml:"proceeds"`
+	Profit        big.Decimal   `yaml:"profit"`
+	Return        big.Decimal   `yaml:"return"`
+	HoldingPeriod time.Duration `yaml:"holding_period"`
+	MAE           big.Decimal   `yaml:"mae"`
+	MFE           big.Decimal   `yaml:"mfe"`
+
+	openAmount   big.Decimal
+	exitQuantity big.Decimal
+}
+
+// TradeAnalysis contains the round trips derived from a trade record along with aggregate
+// statistics of the closed trades. Percentages are expressed from 0 to 100.
+type TradeAnalysis struct {
+	Trades               []*RoundTrip `yaml:"trades"`
+	TotalTrades          int          `yaml:"total_trades"`
+	WinningTrades        int          `yaml:"winning_trades"`
+	LosingTrades         int          `yaml:"losing_trades"`
+	WinRate              big.Decimal  `yaml:"win_rate"`
+	ProfitFactor         big.Decimal  `yaml:"profit_factor"`
+	Expectancy           big.Decimal  `yaml:"expectancy"`
+	AverageWin           big.Decimal  `yaml:"average_win"`
+	AverageLoss          big.Decimal  `yaml:"average_loss"`
+	LargestWin           big.Decimal  `yaml:"largest_win"`
+	LargestLoss          big.Decimal  `yaml:"largest_loss"`
+	LongestWinningStreak int          `yaml:"longest_winning_streak"`
+	LongestLosingStreak  int          `yaml:"longest_losing_streak"`
+}
+
+// Pairs the fills of a trade record into round trips per security. Orders are expected in the
+// order they were executed, as they appear in Account.TradeRecord. Only long positions are
+// supported, so a sell which does not reduce an open round trip is an error.
+func NewRoundTrips(orders []*Order) ([]*RoundTrip, error) {
+	trips := []*RoundTrip{}
+	open := map[string]*RoundTrip{}
+
+	for _, order := range orders {
+		trip, exists := open[order.Security]
+
+		if order.Side == BUY {
+			if !exists {
+				trip = newRoundTrip(order)
+				open[order.Security] = trip
+				trips = append(trips, trip)
+				continue
+			}
+
+			trip.addEntry(order)
+			continue
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("cannot sell %v of %v without an open round trip", order.Amount, order.Security)
+		}
+
+		err := trip.addExit(order)
+		if err != nil {
+			return nil, err
+		}
+
+		if trip.Closed {
+			delete(open, order.Security)
+		}
+	}
+
+	return trips, nil
+}
+
+// Analyzes the trade record of the account. If the timeseries for a security is provided, the
+// maximum adverse and favourable excursions of its round trips are computed from the candles.
+func (a *Account) AnalyzeTrades(series map[string]*TimeSeries) (*TradeAnalysis, error) {
+	return AnalyzeTrades(a.TradeRecord, series)
+}
+
+// Analyzes a list of executed orders by pairing them into round trips and computing aggregate
+// statistics of the closed trades. The series map is optional and is keyed by security.
+func AnalyzeTrades(orders []*Order, series map[string]*TimeSeries) (*TradeAnalysis, error) {
+	trips, err := NewRoundTrips(orders)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trip := range trips {
+		if ts, exists := series[trip.Security]; exists {
+			trip.ComputeExcursions(ts)
+		}
+	}
+
+	analysis := &TradeAnalysis{
+		Trades:       trips,
+		WinRate:      big.ZERO,
+		ProfitFactor: big.ZERO,
+		Expectancy:   big.ZERO,
+		AverageWin:   big.ZERO,
+		AverageLoss:  big.ZERO,
+		LargestWin:   big.ZERO,
+		LargestLoss:  big.ZERO,
+	}
+
+	closed := []*RoundTrip{}
+	for _, trip := range trips {
+		if trip.Closed {
+			closed = append(closed, trip)
+		}
+	}
+
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].ExitTime.Before(closed[j].ExitTime)
+	})
+
+	analysis.TotalTrades = len(closed)
+	if len(closed) == 0 {
+		return analysis, nil
+	}
+
+	grossProfit := big.ZERO
+	grossLoss := big.ZERO
+	netProfit := big.ZERO
+	winStreak := 0
+	lossStreak := 0
+
+	for _, trip := range closed {
+		netProfit = netProfit.Add(trip.Profit)
+
+		if trip.Profit.GT(big.ZERO) {
+			analysis.WinningTrades++
+			grossProfit = grossProfit.Add(trip.Profit)
+
+			if trip.Profit.GT(analysis.LargestWin) {
+				analysis.LargestWin = trip.Profit
+			}
+
+			winStreak++
+			lossStreak = 0
+		} else if trip.Profit.LT(big.ZERO) {
+			analysis.LosingTrades++
+			grossLoss = grossLoss.Add(trip.Profit)
+
+			if trip.Profit.LT(analysis.LargestLoss) {
+				analysis.LargestLoss = trip.Profit
+			}
+
+			lossStreak++
+			winStreak = 0
+		} else {
+			winStreak = 0
+			lossStreak = 0
+		}
+
+		analysis.LongestWinningStreak = Max(analysis.LongestWinningStreak, winStreak)
+		analysis.LongestLosingStreak = Max(analysis.LongestLosingStreak, lossStreak)
+	}
+
+	total := big.NewFromInt(len(closed))
+	analysis.WinRate = big.NewFromInt(analysis.WinningTrades).Div(total).Mul(big.NewDecimal(100.00))
+	analysis.Expectancy = netProfit.Div(total)
+
+	if analysis.WinningTrades > 0 {
+		analysis.AverageWin = grossProfit.Div(big.NewFromInt(analysis.WinningTrades))
+	}
+
+	if analysis.LosingTrades > 0 {
+		analysis.AverageLoss = grossLoss.Div(big.NewFromInt(analysis.LosingTrades))
+		analysis.ProfitFactor = grossProfit.Div(grossLoss.Abs())
+	} else if grossProfit.GT(big.ZERO) {
+		analysis.ProfitFactor = big.NewDecimal(math.Inf(1))
+	}
+
+	return analysis, nil
+}
+
+// Computes the maximum adverse excursion (MAE) and maximum favourable excursion (MFE) of the
+// round trip from the candles it was held over. Both are expressed as a percentage move from the
+// average entry price, so MAE is zero or negative and MFE is zero or positive. Open round trips
+// are measured up until the last candle of the series.
+func (rt *RoundTrip) ComputeExcursions(series *TimeSeries) {
+	rt.MAE = big.ZERO
+	rt.MFE = big.ZERO
+
+	if rt.AvgEntryPrice.IsZero() {
+		return
+	}
+
+	for _, candle := range series.Candles {
+		if candle.Period.Start.Before(rt.EntryTime) {
+			continue
+		}
+
+		if rt.Closed && candle.Period.Start.After(rt.ExitTime) {
+			break
+		}
+
+		adverse := candle.MinPrice.Sub(rt.AvgEntryPrice).Div(rt.AvgEntryPrice).Mul(big.NewDecimal(100.00))
+		if adverse.LT(rt.MAE) {
+			rt.MAE = adverse
+		}
+
+		favourable := candle.MaxPrice.Sub(rt.AvgEntryPrice).Div(rt.AvgEntryPrice).Mul(big.NewDecimal(100.00))
+		if favourable.GT(rt.MFE) {
+			rt.MFE = favourable
+		}
+	}
+}
+
+// Exports the trade analysis, including every round trip, to yaml for viewing and analysis.
+func (ta *TradeAnalysis) ExportYaml(filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	defer encoder.Close()
+
+	err = encoder.Encode(ta)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newRoundTrip(order *Order) *RoundTrip {
+	trip := &RoundTrip{
+		Security:      order.Security,
+		Entries:       []*Order{},
+		Exits:         []*Order{},
+		EntryTime:     order.ExecutionTime,
+		Quantity:      big.ZERO,
+		AvgEntryPrice: big.ZERO,
+		AvgExitPrice:  big.ZERO,
+		CostBasis:     big.ZERO,
+		Proceeds:      big.ZERO,
+		Profit:        big.ZERO,
+		Return:        big.ZERO,
+		MAE:           big.ZERO,
+		MFE:           big.ZERO,
+		openAmount:    big.ZERO,
+		exitQuantity:  big.ZERO,
+	}
+
+	trip.addEntry(order)
+
+	return trip
+}
+
+func (rt *RoundTrip) addEntry(order *Order) {
+	rt.Entries = append(rt.Entries, order)
+	rt.Quantity = rt.Quantity.Add(order.Amount)
+	rt.openAmount = rt.openAmount.Add(order.Amount)
+	rt.CostBasis = rt.CostBasis.Add(order.CostBasis())
+
+	if !rt.Quantity.IsZero() {
+		rt.AvgEntryPrice = rt.CostBasis.Div(rt.Quantity)
+	}
+
+	rt.updateProfit()
+}
+
+func (rt *RoundTrip) addExit(order *Order) error {
+	remaining := rt.openAmount.Sub(order.Amount)
+	if remaining.LT(big.ZERO) {
+		return fmt.Errorf(
+			"cannot sell %v of %v when the open round trip has %v",
+			order.Amount,
+			order.Security,
+			rt.openAmount,
+		)
+	}
+
+	rt.Exits = append(rt.Exits, order)
+	rt.openAmount = remaining
+	rt.exitQuantity = rt.exitQuantity.Add(order.Amount)
+	rt.Proceeds = rt.Proceeds.Add(order.CostBasis())
+	rt.ExitTime = order.ExecutionTime
+
+	if !rt.exitQuantity.IsZero() {
+		rt.AvgExitPrice = rt.Proceeds.Div(rt.exitQuantity)
+	}
+
+	if remaining.IsZero() {
+		rt.Closed = true
+		rt.HoldingPeriod = rt.ExitTime.Sub(rt.EntryTime)
+	}
+
+	rt.updateProfit()
+
+	return nil
+}
+
+// updateProfit computes the realized profit of the shares which have been sold so far.
+func (rt *RoundTrip) updateProfit() {
+	soldCost := rt.AvgEntryPrice.Mul(rt.exitQuantity)
+	rt.Profit = rt.Proceeds.Sub(soldCost)
+
+	if soldCost.IsZero() {
+		rt.Return = big.ZERO
+		return
+	}
+
+	rt.Return = rt.Profit.Div(soldCost).Mul(big.NewDecimal(100.00))
+}
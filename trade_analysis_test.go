@@ -0,0 +1,179 @@
+package techan
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/schmidthole/big"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockFill(security string, side OrderSide, amount, price float64, day int) *Order {
+	return &Order{
+		Security:      security,
+		Side:          side,
+		Amount:        big.NewDecimal(amount),
+		Price:         big.NewDecimal(price),
+		ExecutionTime: time.Unix(int64(day), 0),
+	}
+}
+
+func TestNewRoundTrips(t *testing.T) {
+	t.Run("scale in and partial exits", func(t *testing.T) {
+		orders := []*Order{
+			mockFill("ONE", BUY, 10, 10.0, 0),
+			mockFill("TWO", BUY, 5, 20.0, 1),
+			mockFill("ONE", BUY, 10, 12.0, 2),
+			mockFill("ONE", SELL, 5, 13.0, 3),
+			mockFill("ONE", SELL, 15, 14.0, 4),
+		}
+
+		trips, err := NewRoundTrips(orders)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(trips))
+
+		one := trips[0]
+		assert.Equal(t, "ONE", one.Security)
+		assert.True(t, one.Closed)
+		assert.Equal(t, 2, len(one.Entries))
+		assert.Equal(t, 2, len(one.Exits))
+		decimalEquals(t, 20.0, one.Quantity)
+		decimalEquals(t, 11.0, one.AvgEntryPrice)
+		decimalEquals(t, 13.75, one.AvgExitPrice)
+		decimalEquals(t, 220.0, one.CostBasis)
+		decimalEquals(t, 275.0, one.Proceeds)
+		decimalEquals(t, 55.0, one.Profit)
+		decimalEquals(t, 25.0, one.Return)
+		assert.Equal(t, time.Second*4, one.HoldingPeriod)
+
+		two := trips[1]
+		assert.False(t, two.Closed)
+		decimalEquals(t, 0.0, two.Profit)
+	})
+
+	t.Run("reopen after close", func(t *testing.T) {
+		orders := []*Order{
+			mockFill("ONE", BUY, 1, 10.0, 0),
+			mockFill("ONE", SELL, 1, 11.0, 1),
+			mockFill("ONE", BUY, 1, 12.0, 2),
+		}
+
+		trips, err := NewRoundTrips(orders)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(trips))
+		assert.True(t, trips[0].Closed)
+		assert.False(t, trips[1].Closed)
+	})
+
+	t.Run("sell without position", func(t *testing.T) {
+		_, err := NewRoundTrips([]*Order{mockFill("ONE", SELL, 1, 10.0, 0)})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("oversell", func(t *testing.T) {
+		_, err := NewRoundTrips([]*Order{
+			mockFill("ONE", BUY, 1, 10.0, 0),
+			mockFill("ONE", SELL, 2, 10.0, 1),
+		})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAnalyzeTrades(t *testing.T) {
+	orders := []*Order{
+		mockFill("ONE", BUY, 1, 10.0, 0),
+		mockFill("ONE", SELL, 1, 15.0, 1),
+		mockFill("ONE", BUY, 1, 10.0, 2),
+		mockFill("ONE", SELL, 1, 12.0, 3),
+		mockFill("ONE", BUY, 1, 10.0, 4),
+		mockFill("ONE", SELL, 1, 7.0, 5),
+		mockFill("ONE", BUY, 1, 10.0, 6),
+		mockFill("ONE", SELL, 1, 9.0, 7),
+		mockFill("ONE", BUY, 1, 10.0, 8),
+		mockFill("ONE", SELL, 1, 11.0, 9),
+	}
+
+	analysis, err := AnalyzeTrades(orders, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 5, analysis.TotalTrades)
+	assert.Equal(t, 3, analysis.WinningTrades)
+	assert.Equal(t, 2, analysis.LosingTrades)
+	decimalEquals(t, 60.0, analysis.WinRate)
+	decimalEquals(t, 2.0, analysis.ProfitFactor)
+	decimalEquals(t, 0.8, analysis.Expectancy)
+	decimalEquals(t, 8.0/3.0, analysis.AverageWin)
+	decimalEquals(t, -2.0, analysis.AverageLoss)
+	decimalEquals(t, 5.0, analysis.LargestWin)
+	decimalEquals(t, -3.0, analysis.LargestLoss)
+	assert.Equal(t, 2, analysis.LongestWinningStreak)
+	assert.Equal(t, 2, analysis.LongestLosingStreak)
+}
+
+func TestAnalyzeTrades_NoLosses(t *testing.T) {
+	analysis, err := AnalyzeTrades([]*Order{
+		mockFill("ONE", BUY, 1, 10.0, 0),
+		mockFill("ONE", SELL, 1, 15.0, 1),
+	}, nil)
+
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(analysis.ProfitFactor.Float(), 1))
+}
+
+func TestAnalyzeTrades_Empty(t *testing.T) {
+	analysis, err := AnalyzeTrades([]*Order{}, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, analysis.TotalTrades)
+	decimalEquals(t, 0.0, analysis.WinRate)
+}
+
+func TestRoundTrip_ComputeExcursions(t *testing.T) {
+	series := mockTimeSeriesOCHL(
+		[]float64{10, 10, 11, 9},
+		[]float64{10, 12, 13, 8},
+		[]float64{12, 14, 15, 11},
+		[]float64{14, 10, 20, 5},
+	)
+
+	analysis, err := AnalyzeTrades([]*Order{
+		mockFill(MOCK_SECURITY, BUY, 1, 10.0, 0),
+		mockFill(MOCK_SECURITY, SELL, 1, 14.0, 2),
+	}, map[string]*TimeSeries{MOCK_SECURITY: series})
+	assert.Nil(t, err)
+
+	trip := analysis.Trades[0]
+	decimalEquals(t, -20.0, trip.MAE)
+	decimalEquals(t, 50.0, trip.MFE)
+}
+
+func TestAccount_AnalyzeTrades(t *testing.T) {
+	ts := mockTimeSeriesFl(1.0, 2.0, 3.0, 4.0, 5.0)
+	strat := Strategy{
+		Security:   "ONE",
+		Timeseries: *ts,
+		Rule:       truthRule{},
+		Indicators: map[string]Indicator{},
+	}
+	acct := NewAccount()
+	acct.Deposit(big.NewDecimal(100.0))
+
+	bt := NewBacktest([]Strategy{strat}, NewNaiveAllocator(big.NewDecimal(0.5), big.NewDecimal(0.5)), acct)
+	_, err := bt.Run()
+	assert.Nil(t, err)
+
+	analysis, err := acct.AnalyzeTrades(map[string]*TimeSeries{"ONE": ts})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(analysis.Trades))
+	assert.True(t, len(acct.TradeRecord) > 1)
+	assert.NotEqual(t, acct.TradeRecord[0], acct.TradeRecord[1])
+	assert.Equal(t, ts.Candles[0].Period.Start, analysis.Trades[0].EntryTime)
+
+	filepath := "trade_analysis.yaml"
+	err = analysis.ExportYaml(filepath)
+	assert.Nil(t, err)
+
+	os.Remove(filepath)
+}